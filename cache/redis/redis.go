@@ -176,7 +176,7 @@ func (c *Cache) Set(ctx context.Context, key string, value interface{}, expirati
 func (c *Cache) Increment(ctx context.Context, key string, expiration int) (int64, error) {
 	switch expiration {
 	case 0:
-		i, err := c.client.Incr(ctx, key).Result()
+		i, err := c.client.Incr(ctx, c.ns+key).Result()
 		if err != nil {
 			return 0, err
 		}
@@ -184,8 +184,8 @@ func (c *Cache) Increment(ctx context.Context, key string, expiration int) (int6
 	default:
 		pipe := c.client.TxPipeline()
 
-		incr := pipe.Incr(ctx, key)
-		pipe.Expire(ctx, key, time.Second*time.Duration(expiration))
+		incr := pipe.Incr(ctx, c.ns+key)
+		pipe.Expire(ctx, c.ns+key, time.Second*time.Duration(expiration))
 
 		_, err := pipe.Exec(ctx)
 		if err != nil {
@@ -10,8 +10,16 @@ const (
 	LE = "<="
 	//regex
 	RE = "~"
+	//case insensitive regex
+	IR = "~*"
 	NE = "!="
 	SN = "!=="
 	IN = "[]"
+	//not in
+	NI = "![]"
 	EM = "{}"
+	//field exists and not null, value is boolean, nil means true
+	EX = "?"
+	//between inclusive, value is slice of [min, max]
+	BT = "><"
 )
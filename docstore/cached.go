@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/diki-haryadi/govega/cache"
@@ -179,9 +180,7 @@ func (s *CachedStore) update(ctx context.Context, doc interface{}, replace bool)
 		return err
 	}
 
-	if err := s.cache.Delete(ctx, fmt.Sprintf("%v", id)); err != nil {
-		log.WithError(err).Error("error deleting cache ")
-	}
+	s.invalidate(ctx, id)
 
 	return s.storage.Update(ctx, id, doc, replace)
 }
@@ -191,17 +190,13 @@ func (s *CachedStore) Update(ctx context.Context, doc interface{}) error {
 }
 
 func (s *CachedStore) UpdateField(ctx context.Context, id interface{}, key string, value interface{}) error {
-	if err := s.cache.Delete(ctx, fmt.Sprintf("%v", id)); err != nil {
-		log.WithError(err).Error("error deleting cache ")
-	}
+	s.invalidate(ctx, id)
 
 	return s.storage.UpdateField(ctx, id, []Field{{Name: key, Value: value}})
 }
 
 func (s *CachedStore) Increment(ctx context.Context, id interface{}, fieldName string, value int) error {
-	if err := s.cache.Delete(ctx, fmt.Sprintf("%v", id)); err != nil {
-		log.WithError(err).Error("error deleting cache ")
-	}
+	s.invalidate(ctx, id)
 	return s.storage.Increment(ctx, id, fieldName, value)
 }
 
@@ -215,6 +210,12 @@ func (s *CachedStore) Get(ctx context.Context, id, doc interface{}) error {
 		return errors.New("[docstore] docs should be a pointer of struct or map")
 	}
 
+	// cache is bypassed within transaction, it may hold value older than the transaction writes
+	// and uncommitted document should not be cached
+	if inTransaction(ctx) {
+		return s.storage.Get(ctx, id, doc)
+	}
+
 	if s.cache.Exist(ctx, fmt.Sprintf("%v", id)) {
 		if err := s.cache.GetObject(ctx, fmt.Sprintf("%v", id), doc); err == nil {
 			return nil
@@ -229,9 +230,7 @@ func (s *CachedStore) Get(ctx context.Context, id, doc interface{}) error {
 }
 
 func (s *CachedStore) Delete(ctx context.Context, id interface{}) error {
-	if err := s.cache.Delete(ctx, fmt.Sprintf("%v", id)); err != nil {
-		log.WithError(err).Error("error deleting cache ")
-	}
+	s.invalidate(ctx, id)
 
 	return s.storage.Delete(ctx, id)
 }
@@ -245,6 +244,16 @@ func (s *CachedStore) Find(ctx context.Context, query *QueryOpt, docs interface{
 	return s.storage.Find(ctx, query, docs)
 }
 
+// FindPage find a page of documents after the cursor, empty cursor return the first page
+func (s *CachedStore) FindPage(ctx context.Context, query *QueryOpt, cursor string, docs interface{}) (*PageResult, error) {
+
+	if !util.IsPointerOfSlice(docs) {
+		return nil, errors.New("[docstore] docs should be a pointer of slice")
+	}
+
+	return s.storage.FindPage(ctx, query, cursor, docs)
+}
+
 func (s *CachedStore) BulkCreate(ctx context.Context, docs interface{}) error {
 	if !util.IsSlice(docs) {
 		return errors.New("[docstore] documents should be a slice")
@@ -315,6 +324,60 @@ func (s *CachedStore) Migrate(ctx context.Context, config interface{}) error {
 	return s.storage.Migrate(ctx, config)
 }
 
+// WithTransaction run fn within storage transaction, cache invalidation of documents
+// written within the transaction is deferred until it is committed
+func (s *CachedStore) WithTransaction(ctx context.Context, fn TxFunc) error {
+	storage, ok := s.storage.(Transactional)
+	if !ok {
+		return UnsupportedTransaction
+	}
+
+	if inTransaction(ctx) {
+		return storage.WithTransaction(ctx, fn)
+	}
+
+	tx := &cacheTx{}
+	if err := storage.WithTransaction(context.WithValue(ctx, cacheTxKey{}, tx), fn); err != nil {
+		return err
+	}
+
+	for _, key := range tx.keys {
+		if err := s.cache.Delete(ctx, key); err != nil {
+			log.WithError(err).Error("error deleting cache ")
+		}
+	}
+
+	return nil
+}
+
+type cacheTxKey struct{}
+
+// cacheTx keep cache keys to be invalidated on commit
+type cacheTx struct {
+	lock sync.Mutex
+	keys []string
+}
+
+func inTransaction(ctx context.Context) bool {
+	_, ok := ctx.Value(cacheTxKey{}).(*cacheTx)
+	return ok
+}
+
+func (s *CachedStore) invalidate(ctx context.Context, id interface{}) {
+	key := fmt.Sprintf("%v", id)
+
+	if tx, ok := ctx.Value(cacheTxKey{}).(*cacheTx); ok {
+		tx.lock.Lock()
+		tx.keys = append(tx.keys, key)
+		tx.lock.Unlock()
+		return
+	}
+
+	if err := s.cache.Delete(ctx, key); err != nil {
+		log.WithError(err).Error("error deleting cache ")
+	}
+}
+
 func (s *CachedStore) GetCache() cache.Cache {
 	return s.cache
 }
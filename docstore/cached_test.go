@@ -2,6 +2,7 @@ package docstore
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -160,3 +161,59 @@ func TestBulkStore(t *testing.T) {
 		assert.Equal(t, ins[i].CreatedAt.Unix(), out[i].CreatedAt.Unix())
 	}
 }
+
+func TestDocstoreTransaction(t *testing.T) {
+	type User struct {
+		ID        string    `json:"id"`
+		Name      string    `json:"name"`
+		Age       int       `json:"age"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	ms := NewMemoryStore("test", "id")
+	cache := mem.NewMemoryCache()
+	conf := &Config{
+		IDField:        defaultID,
+		TimestampField: defaultTimestamp,
+	}
+
+	cs := NewDocstore(ms, cache, conf)
+	ctx := context.Background()
+
+	usr := &User{ID: "1", Name: "sahal", Age: 35}
+	require.Nil(t, cs.Create(ctx, usr))
+
+	var doc User
+	require.Nil(t, cs.Get(ctx, usr.ID, &doc))
+	require.True(t, cache.Exist(ctx, usr.ID))
+
+	failed := errors.New("failed")
+	err := cs.WithTransaction(ctx, func(ctx context.Context) error {
+		require.Nil(t, cs.UpdateField(ctx, usr.ID, "age", 40))
+		assert.True(t, cache.Exist(ctx, usr.ID), "invalidation deferred until commit")
+
+		var user User
+		require.Nil(t, cs.Get(ctx, usr.ID, &user))
+		assert.Equal(t, 40, user.Age, "cache bypassed within transaction")
+		return failed
+	})
+	require.ErrorIs(t, err, failed)
+	assert.True(t, cache.Exist(ctx, usr.ID))
+
+	var cached User
+	require.Nil(t, cs.Get(ctx, usr.ID, &cached))
+	assert.Equal(t, 35, cached.Age)
+
+	err = cs.WithTransaction(ctx, func(ctx context.Context) error {
+		return cs.UpdateField(ctx, usr.ID, "age", 40)
+	})
+	require.Nil(t, err)
+	assert.False(t, cache.Exist(ctx, usr.ID))
+
+	var committed User
+	require.Nil(t, cs.Get(ctx, usr.ID, &committed))
+	assert.Equal(t, 40, committed.Age)
+
+	unsupported := NewDocstore(struct{ Driver }{ms}, cache, conf)
+	assert.ErrorIs(t, unsupported.WithTransaction(ctx, func(ctx context.Context) error { return nil }), UnsupportedTransaction)
+}
@@ -20,11 +20,19 @@ type Driver interface {
 	Delete(ctx context.Context, id interface{}) error
 	Get(ctx context.Context, id interface{}, doc interface{}) error
 	Find(ctx context.Context, query *QueryOpt, docs interface{}) error
+	FindPage(ctx context.Context, query *QueryOpt, cursor string, docs interface{}) (*PageResult, error)
 	BulkCreate(ctx context.Context, docs []interface{}) error
 	BulkGet(ctx context.Context, ids []interface{}, docs interface{}) error
 	Migrate(ctx context.Context, config interface{}) error
 }
 
+// Conditional driver supporting atomic conditional update
+type Conditional interface {
+	// UpdateFieldIf update fields of the document only when it match all filters at the time of update,
+	// NotFound is returned when the document is missing or doesn't match
+	UpdateFieldIf(ctx context.Context, id interface{}, filters []FilterOpt, fields []Field) error
+}
+
 type DriverFactory func(config *Config) (Driver, error)
 
 var drivers = map[string]DriverFactory{
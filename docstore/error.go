@@ -4,4 +4,9 @@ type DocstoreError string
 
 func (e DocstoreError) Error() string { return string(e) }
 
-const NotFound = DocstoreError("[docstore] document not found")
+const (
+	NotFound               = DocstoreError("[docstore] document not found")
+	UnsupportedQuery       = DocstoreError("[docstore] unsupported query")
+	InvalidCursor          = DocstoreError("[docstore] invalid cursor")
+	UnsupportedTransaction = DocstoreError("[docstore] transaction not supported")
+)
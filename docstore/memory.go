@@ -3,8 +3,12 @@ package docstore
 import (
 	"context"
 	"errors"
+	"fmt"
+	"regexp"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"dario.cat/mergo"
 	"github.com/diki-haryadi/govega/constant"
@@ -58,7 +62,8 @@ func (m *MemoryStore) Create(ctx context.Context, doc interface{}) error {
 		return err
 	}
 
-	if _, ok := m.storage[id]; ok {
+	s := m.documents(ctx)
+	if _, ok := s.docs[id]; ok {
 		return errors.New("[docstore/memory] document ID is already exist")
 	}
 
@@ -67,7 +72,7 @@ func (m *MemoryStore) Create(ctx context.Context, doc interface{}) error {
 		return err
 	}
 
-	m.storage[id] = d
+	s.put(id, d)
 	return nil
 }
 
@@ -75,7 +80,9 @@ func (m *MemoryStore) Update(ctx context.Context, id, doc interface{}, replace b
 	m.mux.Lock()
 	defer m.mux.Unlock()
 
-	if _, ok := m.storage[id]; !ok {
+	s := m.documents(ctx)
+	cd, ok := s.mutable(id)
+	if !ok {
 		return NotFound
 	}
 	d := make(map[string]interface{})
@@ -84,17 +91,15 @@ func (m *MemoryStore) Update(ctx context.Context, id, doc interface{}, replace b
 	}
 
 	if replace {
-		m.storage[id] = d
+		s.put(id, d)
 		return nil
 	}
 
-	cd := m.storage[id]
-
 	if err := mergo.MergeWithOverwrite(&cd, d); err != nil {
 		return err
 	}
 
-	m.storage[id] = cd
+	s.put(id, cd)
 
 	return nil
 }
@@ -102,7 +107,31 @@ func (m *MemoryStore) Update(ctx context.Context, id, doc interface{}, replace b
 func (m *MemoryStore) UpdateField(ctx context.Context, id interface{}, fields []Field) error {
 	m.mux.Lock()
 	defer m.mux.Unlock()
-	d, ok := m.storage[id]
+	return m.updateField(ctx, id, fields)
+}
+
+func (m *MemoryStore) UpdateFieldIf(ctx context.Context, id interface{}, filters []FilterOpt, fields []Field) error {
+	for _, f := range filters {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	d, ok := m.documents(ctx).docs[id]
+	if !ok || !matchAll(d, filters) {
+		return NotFound
+	}
+
+	return m.updateField(ctx, id, fields)
+}
+
+// updateField set fields of the document, caller should hold the lock
+func (m *MemoryStore) updateField(ctx context.Context, id interface{}, fields []Field) error {
+	s := m.documents(ctx)
+	d, ok := s.mutable(id)
 	if !ok {
 		return NotFound
 	}
@@ -114,16 +143,17 @@ func (m *MemoryStore) UpdateField(ctx context.Context, id interface{}, fields []
 		}
 	}
 
-	m.storage[id] = d
+	s.put(id, d)
 	return nil
 }
 
 func (m *MemoryStore) Increment(ctx context.Context, id interface{}, key string, value int) error {
 	m.mux.Lock()
 	defer m.mux.Unlock()
-	d, ok := m.storage[id]
+	s := m.documents(ctx)
+	d, ok := s.mutable(id)
 	if !ok {
-		m.storage[id] = map[string]interface{}{key: value}
+		s.put(id, map[string]interface{}{key: value})
 		return nil
 	}
 
@@ -153,7 +183,7 @@ func (m *MemoryStore) Increment(ctx context.Context, id interface{}, key string,
 		return errors.New("[docstore/memory] destination type is not a number")
 	}
 
-	m.storage[id] = d
+	s.put(id, d)
 	return nil
 }
 
@@ -168,14 +198,14 @@ func (m *MemoryStore) Delete(ctx context.Context, id interface{}) error {
 	m.mux.Lock()
 	defer m.mux.Unlock()
 
-	delete(m.storage, id)
+	m.documents(ctx).remove(id)
 	return nil
 }
 
 func (m *MemoryStore) Get(ctx context.Context, id interface{}, doc interface{}) error {
 	m.mux.Lock()
 	defer m.mux.Unlock()
-	d, ok := m.storage[id]
+	d, ok := m.documents(ctx).docs[id]
 	if !ok {
 		return NotFound
 	}
@@ -188,50 +218,69 @@ func (m *MemoryStore) Get(ctx context.Context, id interface{}, doc interface{})
 }
 
 func (m *MemoryStore) Find(ctx context.Context, query *QueryOpt, docs interface{}) error {
-	// Only support = operation
+	if err := query.Validate(); err != nil {
+		return err
+	}
+
 	m.mux.Lock()
 	defer m.mux.Unlock()
 
-	out := make([]interface{}, 0)
-	for _, d := range m.storage {
-		match := false
-		for _, f := range query.Filter {
-			if !util.Assert(f.Field, d, f.Value, f.Ops) {
-				match = false
-				break
-			}
-			match = true
-		}
-		if match {
+	out := make([]map[string]interface{}, 0)
+	for _, d := range m.documents(ctx).docs {
+		if matchAll(d, query.Filter) {
 			out = append(out, d)
 		}
 	}
 
-	if query.OrderBy != "" {
-		sort.Slice(out, func(i, j int) bool {
-			val, ok := util.Lookup(query.OrderBy, out[j])
-			if ok {
-				return util.Assert(query.OrderBy, out[i], val, constant.GT)
+	if sorts := query.Sorts(); len(sorts) > 0 {
+		sort.SliceStable(out, func(i, j int) bool {
+			for _, s := range sorts {
+				a, _ := util.Lookup(s.Field, out[i])
+				b, _ := util.Lookup(s.Field, out[j])
+
+				c := compareValue(a, b)
+				if c == 0 {
+					continue
+				}
+
+				if s.IsAscend {
+					return c < 0
+				}
+				return c > 0
 			}
 			return false
 		})
-		if query.IsAscend {
-			util.Reverse(out)
-		}
 	}
 
 	if query.Page > 0 && query.Limit > 0 {
 		query.Skip = query.Page * query.Limit
 	}
 
-	if query.Skip > 0 && len(out) > query.Skip {
-		out = out[query.Skip:]
+	if query.Skip > 0 {
+		if len(out) > query.Skip {
+			out = out[query.Skip:]
+		} else {
+			out = out[:0]
+		}
 	}
 
 	if query.Limit > 0 && len(out) > query.Limit {
 		out = out[:query.Limit]
 	}
 
+	if len(query.Fields) > 0 {
+		projected := make([]map[string]interface{}, len(out))
+		for i, d := range out {
+			projected[i] = make(map[string]interface{}, len(query.Fields))
+			for _, f := range query.Fields {
+				if v, ok := d[f]; ok {
+					projected[i][f] = v
+				}
+			}
+		}
+		out = projected
+	}
+
 	if err := util.DecodeJSON(out, docs); err != nil {
 		return err
 	}
@@ -239,17 +288,22 @@ func (m *MemoryStore) Find(ctx context.Context, query *QueryOpt, docs interface{
 
 }
 
+func (m *MemoryStore) FindPage(ctx context.Context, query *QueryOpt, cursor string, docs interface{}) (*PageResult, error) {
+	return Paginate(ctx, m.Find, m.idField, query, cursor, docs)
+}
+
 func (m *MemoryStore) BulkCreate(ctx context.Context, docs []interface{}) error {
 	m.mux.Lock()
 	defer m.mux.Unlock()
 
+	s := m.documents(ctx)
 	for _, doc := range docs {
 		id, err := m.getID(doc)
 		if err != nil {
 			return err
 		}
 
-		if _, ok := m.storage[id]; ok {
+		if _, ok := s.docs[id]; ok {
 			return errors.New("[docstore/memory] document ID is already exist")
 		}
 
@@ -258,7 +312,7 @@ func (m *MemoryStore) BulkCreate(ctx context.Context, docs []interface{}) error
 			return err
 		}
 
-		m.storage[id] = d
+		s.put(id, d)
 	}
 
 	return nil
@@ -268,9 +322,10 @@ func (m *MemoryStore) BulkGet(ctx context.Context, ids []interface{}, docs inter
 	m.mux.Lock()
 	defer m.mux.Unlock()
 
+	s := m.documents(ctx)
 	out := make([]map[string]interface{}, 0)
 	for _, id := range ids {
-		d, ok := m.storage[id]
+		d, ok := s.docs[id]
 		if !ok {
 			return NotFound
 		}
@@ -287,3 +342,214 @@ func (m *MemoryStore) BulkGet(ctx context.Context, ids []interface{}, docs inter
 func (m *MemoryStore) Migrate(ctx context.Context, config interface{}) error {
 	return nil
 }
+
+// WithTransaction run fn on copy-on-write snapshot of the storage, documents written within
+// the transaction are applied on commit and discarded on rollback. Nested call join the current transaction
+func (m *MemoryStore) WithTransaction(ctx context.Context, fn TxFunc) error {
+	if _, ok := ctx.Value(memoryTxKey{m}).(*memoryDocuments); ok {
+		return fn(ctx)
+	}
+
+	m.mux.Lock()
+	tx := &memoryDocuments{
+		docs:    make(map[interface{}]map[string]interface{}, len(m.storage)),
+		written: make(map[interface{}]bool),
+		copied:  make(map[interface{}]bool),
+	}
+	for id, d := range m.storage {
+		tx.docs[id] = d
+	}
+	m.mux.Unlock()
+
+	if err := fn(context.WithValue(ctx, memoryTxKey{m}, tx)); err != nil {
+		return err
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for id := range tx.written {
+		if d, ok := tx.docs[id]; ok {
+			m.storage[id] = d
+		} else {
+			delete(m.storage, id)
+		}
+	}
+
+	return nil
+}
+
+type memoryTxKey struct {
+	store *MemoryStore
+}
+
+// memoryDocuments documents of the store or the transaction snapshot,
+// snapshot documents are copied before modified in place
+type memoryDocuments struct {
+	docs    map[interface{}]map[string]interface{}
+	written map[interface{}]bool
+	copied  map[interface{}]bool
+}
+
+// documents return documents of the transaction in the context if any, caller should hold the lock
+func (m *MemoryStore) documents(ctx context.Context) *memoryDocuments {
+	if tx, ok := ctx.Value(memoryTxKey{m}).(*memoryDocuments); ok {
+		return tx
+	}
+
+	return &memoryDocuments{docs: m.storage}
+}
+
+// mutable return the document to be modified in place
+func (s *memoryDocuments) mutable(id interface{}) (map[string]interface{}, bool) {
+	d, ok := s.docs[id]
+	if !ok || s.copied == nil || s.copied[id] {
+		return d, ok
+	}
+
+	cd := make(map[string]interface{}, len(d))
+	for k, v := range d {
+		cd[k] = v
+	}
+	s.docs[id] = cd
+	s.copied[id] = true
+
+	return cd, true
+}
+
+func (s *memoryDocuments) put(id interface{}, doc map[string]interface{}) {
+	s.docs[id] = doc
+	if s.written != nil {
+		s.written[id] = true
+		s.copied[id] = true
+	}
+}
+
+func (s *memoryDocuments) remove(id interface{}) {
+	delete(s.docs, id)
+	if s.written != nil {
+		s.written[id] = true
+	}
+}
+
+func matchAll(doc map[string]interface{}, filters []FilterOpt) bool {
+	for _, f := range filters {
+		if !match(doc, f) {
+			return false
+		}
+	}
+	return true
+}
+
+func match(doc map[string]interface{}, f FilterOpt) bool {
+	switch f.Logic {
+	case LogicAnd:
+		return matchAll(doc, f.Filters)
+	case LogicOr:
+		for _, sf := range f.Filters {
+			if match(doc, sf) {
+				return true
+			}
+		}
+		return false
+	case LogicNot:
+		return !matchAll(doc, f.Filters)
+	}
+
+	switch f.Ops {
+	case "", constant.EQ:
+		if _, ok := f.Value.(time.Time); ok {
+			// compare time instant, string representation may differ
+			return matchCompare(doc, f)
+		}
+		return util.Assert(f.Field, doc, f.Value, constant.EQ)
+	case constant.GT, constant.GE, constant.LT, constant.LE:
+		return matchCompare(doc, f)
+	case constant.IN:
+		return matchIn(doc, f)
+	case constant.NI:
+		return !matchIn(doc, f)
+	case constant.BT:
+		min, max, _ := f.Range()
+		return util.Assert(f.Field, doc, min, constant.GE) && util.Assert(f.Field, doc, max, constant.LE)
+	case constant.EX:
+		exists, _ := f.Exists()
+		val, ok := util.Lookup(f.Field, doc)
+		return (ok && val != nil) == exists
+	case constant.IR:
+		val, ok := util.Lookup(f.Field, doc)
+		if !ok || val == nil {
+			return false
+		}
+		re, err := regexp.Compile("(?i)" + fmt.Sprintf("%v", f.Value))
+		if err != nil {
+			return false
+		}
+		return re.MatchString(fmt.Sprintf("%v", val))
+	default:
+		return util.Assert(f.Field, doc, f.Value, f.Ops)
+	}
+}
+
+// matchCompare compare the field the same way as sorting, so non numeric value is comparable
+func matchCompare(doc map[string]interface{}, f FilterOpt) bool {
+	val, ok := util.Lookup(f.Field, doc)
+	if !ok || val == nil {
+		return false
+	}
+
+	c := compareValue(val, f.Value)
+	switch f.Ops {
+	case constant.GT:
+		return c > 0
+	case constant.GE:
+		return c >= 0
+	case constant.LT:
+		return c < 0
+	case constant.LE:
+		return c <= 0
+	default:
+		return c == 0
+	}
+}
+
+func matchIn(doc map[string]interface{}, f FilterOpt) bool {
+	values, _ := f.Values()
+	for _, v := range values {
+		if util.Assert(f.Field, doc, v, constant.EQ) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareValue compare time and numbers by its value and other values by its string representation,
+// missing value is less than any other value
+func compareValue(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Compare(tb)
+		}
+	}
+
+	if lt, err := util.CompareValue(a, b, constant.LT); err == nil {
+		if lt {
+			return -1
+		}
+		if gt, _ := util.CompareValue(a, b, constant.GT); gt {
+			return 1
+		}
+		return 0
+	}
+
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
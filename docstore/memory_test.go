@@ -6,4 +6,7 @@ func TestMemoryStore(t *testing.T) {
 	ms := NewMemoryStore("test", "id")
 	DriverCRUDTest(ms, t)
 	DriverBulkTest(ms, t)
+	DriverPageTest(ms, t)
+	DriverTransactionTest(ms, t)
+	DriverConditionalTest(ms, t)
 }
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"strings"
 	"time"
 
+	"github.com/diki-haryadi/govega/constant"
 	"github.com/diki-haryadi/govega/database"
 	"github.com/diki-haryadi/govega/docstore"
 	"github.com/diki-haryadi/govega/util"
@@ -23,6 +25,30 @@ type MongoStore struct {
 
 func init() {
 	docstore.RegisterDriver("mongo", MongoStoreFactory)
+
+	// keep the bson type of the sort keys in the page cursor
+	docstore.RegisterCursorValue("objectid", docstore.CursorValue{
+		Encode: func(v interface{}) (string, bool) {
+			id, ok := v.(primitive.ObjectID)
+			return id.Hex(), ok
+		},
+		Decode: func(s string) (interface{}, error) {
+			return primitive.ObjectIDFromHex(s)
+		},
+	})
+	docstore.RegisterCursorValue("datetime", docstore.CursorValue{
+		Encode: func(v interface{}) (string, bool) {
+			dt, ok := v.(primitive.DateTime)
+			return dt.Time().UTC().Format(time.RFC3339Nano), ok
+		},
+		Decode: func(s string) (interface{}, error) {
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, err
+			}
+			return primitive.NewDateTimeFromTime(t), nil
+		},
+	})
 }
 
 func MongoStoreFactory(config *docstore.Config) (docstore.Driver, error) {
@@ -148,6 +174,43 @@ func (m *MongoStore) UpdateField(ctx context.Context, id interface{}, fields []d
 	return err
 }
 
+func (m *MongoStore) UpdateFieldIf(ctx context.Context, id interface{}, filters []docstore.FilterOpt, fields []docstore.Field) error {
+	q := &docstore.QueryOpt{Filter: []docstore.FilterOpt{{Field: m.idField, Value: id}}}
+	for _, f := range filters {
+		q.Filter = append(q.Filter, matchTimeString(f))
+	}
+
+	f, _, err := toMongoFilter(q)
+	if err != nil {
+		return err
+	}
+
+	fs := bson.D{}
+	for _, v := range fields {
+		fs = append(fs, bson.E{Key: v.Name, Value: v.Value})
+	}
+
+	res, err := m.store.UpdateOne(ctx, f, bson.D{{Key: "$set", Value: fs}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return docstore.NotFound
+	}
+	return nil
+}
+
+// matchTimeString match time equality filter with the date and the json string stored by Create
+func matchTimeString(f docstore.FilterOpt) docstore.FilterOpt {
+	t, ok := f.Value.(time.Time)
+	if !ok || f.IsGroup() || (f.Ops != constant.EQ && f.Ops != "") {
+		return f
+	}
+
+	ts, _ := t.MarshalJSON()
+	return docstore.Or(f, docstore.FilterOpt{Field: f.Field, Ops: constant.EQ, Value: strings.Trim(string(ts), `"`)})
+}
+
 func (m *MongoStore) Increment(ctx context.Context, id interface{}, key string, value int) error {
 	update := bson.D{{Key: "$inc", Value: bson.D{{Key: key, Value: value}}}}
 	upsert := true
@@ -197,7 +260,11 @@ func (m *MongoStore) exist(ctx context.Context, id interface{}) bool {
 
 func (m *MongoStore) Find(ctx context.Context, query *docstore.QueryOpt, docs interface{}) error {
 
-	f, opt := toMongoFilter(query)
+	f, opt, err := toMongoFilter(query)
+	if err != nil {
+		return err
+	}
+
 	res, err := m.store.Find(ctx, f, opt)
 	if err != nil {
 		return err
@@ -212,6 +279,37 @@ func (m *MongoStore) Find(ctx context.Context, query *docstore.QueryOpt, docs in
 	return util.DecodeJSON(out, docs)
 }
 
+// FindPage find a page of documents, cursor is built from the raw documents
+// so bson sort keys such as ObjectID keep their type on the next page
+func (m *MongoStore) FindPage(ctx context.Context, query *docstore.QueryOpt, cursor string, docs interface{}) (*docstore.PageResult, error) {
+	var raw []bson.M
+	page, err := docstore.Paginate(ctx, m.findRaw, m.idField, query, cursor, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := util.DecodeJSON(raw, docs); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// findRaw find documents without converting the bson values
+func (m *MongoStore) findRaw(ctx context.Context, query *docstore.QueryOpt, docs interface{}) error {
+	f, opt, err := toMongoFilter(query)
+	if err != nil {
+		return err
+	}
+
+	res, err := m.store.Find(ctx, f, opt)
+	if err != nil {
+		return err
+	}
+
+	return res.All(ctx, docs)
+}
+
 func (m *MongoStore) BulkCreate(ctx context.Context, docs []interface{}) error {
 	ins := make([]interface{}, 0)
 	for _, doc := range docs {
@@ -257,6 +355,25 @@ func (m *MongoStore) Migrate(ctx context.Context, config interface{}) error {
 	return db.CreateCollection(ctx, m.collection)
 }
 
+// WithTransaction run fn within session transaction, requires replica set or sharded cluster.
+// fn may be retried on transient transaction error. Nested call join the current transaction
+func (m *MongoStore) WithTransaction(ctx context.Context, fn docstore.TxFunc) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	sess, err := m.store.Database().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer sess.EndSession(ctx)
+
+	_, err = sess.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
 func convertTime(obj map[string]interface{}) {
 	for k, v := range obj {
 		if reflect.TypeOf(v) == reflect.TypeOf(time.Time{}) {
@@ -2,13 +2,18 @@ package mongo
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	_ "github.com/diki-haryadi/govega/cache/mem"
+	"github.com/diki-haryadi/govega/constant"
 	"github.com/diki-haryadi/govega/database"
 	"github.com/diki-haryadi/govega/docstore"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func initDriver(t *testing.T) *database.Database {
@@ -41,6 +46,9 @@ func TestMongoStore(t *testing.T) {
 	require.Nil(t, err)
 	docstore.DriverCRUDTest(ms, t)
 	docstore.DriverBulkTest(ms, t)
+	docstore.DriverPageTest(ms, t)
+	docstore.DriverTransactionTest(ms, t)
+	docstore.DriverConditionalTest(ms, t)
 }
 
 func TestDocstore(t *testing.T) {
@@ -68,3 +76,128 @@ func TestDocstore(t *testing.T) {
 
 	docstore.DocstoreTestCRUD(cs, t)
 }
+
+// filterValues collect values of the field from nested filters
+func filterValues(filters []docstore.FilterOpt, field string) []interface{} {
+	values := make([]interface{}, 0)
+	for _, f := range filters {
+		if f.IsGroup() {
+			values = append(values, filterValues(f.Filters, field)...)
+			continue
+		}
+		if f.Field == field {
+			values = append(values, f.Value)
+		}
+	}
+	return values
+}
+
+func TestPageCursorBsonValue(t *testing.T) {
+	ctx := context.Background()
+	ts := time.Now().Truncate(time.Millisecond)
+
+	docs := make([]bson.M, 5)
+	for i := range docs {
+		docs[i] = bson.M{
+			"_id":        primitive.NewObjectID(),
+			"created_at": primitive.NewDateTimeFromTime(ts.Add(time.Duration(i) * time.Second)),
+		}
+	}
+
+	find := func(ctx context.Context, q *docstore.QueryOpt, out interface{}) error {
+		start := 0
+		for _, v := range filterValues(q.Filter, "_id") {
+			id, ok := v.(primitive.ObjectID)
+			require.True(t, ok, "cursor id should be ObjectID, got %T", v)
+			for i := range docs {
+				if docs[i]["_id"] == id {
+					start = i + 1
+				}
+			}
+		}
+		for _, v := range filterValues(q.Filter, "created_at") {
+			assert.IsType(t, primitive.DateTime(0), v)
+		}
+
+		end := start + q.Limit
+		if end > len(docs) {
+			end = len(docs)
+		}
+		*out.(*[]bson.M) = append([]bson.M{}, docs[start:end]...)
+		return nil
+	}
+
+	q := &docstore.QueryOpt{OrderBy: "created_at", IsAscend: true, Limit: 2}
+
+	var ids []interface{}
+	cursor := ""
+	for {
+		var out []bson.M
+		page, err := docstore.Paginate(ctx, find, "_id", q, cursor, &out)
+		require.NoError(t, err)
+		for _, d := range out {
+			ids = append(ids, d["_id"])
+		}
+		if !page.HasMore {
+			break
+		}
+		cursor = page.Cursor
+	}
+
+	require.Len(t, ids, len(docs))
+	for i := range docs {
+		assert.Equal(t, docs[i]["_id"], ids[i])
+	}
+}
+
+func TestMongoFindPageObjectID(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	db := initDriver(t)
+	col := db.Database.Collection("docstore_oid")
+	col.DeleteMany(ctx, bson.D{})
+
+	// documents inserted without id get ObjectID _id
+	for i := 0; i < 5; i++ {
+		_, err := col.InsertOne(ctx, bson.M{"name": fmt.Sprintf("oid-%d", i), "group": "oid"})
+		require.NoError(t, err)
+	}
+
+	ms, err := NewMongostore(db, "docstore_oid", "_id")
+	require.NoError(t, err)
+
+	q := &docstore.QueryOpt{
+		Filter: []docstore.FilterOpt{{Field: "group", Ops: constant.EQ, Value: "oid"}},
+		Limit:  2,
+	}
+
+	type doc struct {
+		ID   string `json:"_id"`
+		Name string `json:"name"`
+	}
+
+	var names []string
+	sizes := make([]int, 0)
+	cursor := ""
+	for {
+		var out []doc
+		page, err := ms.FindPage(ctx, q, cursor, &out)
+		require.NoError(t, err)
+
+		sizes = append(sizes, len(out))
+		for _, d := range out {
+			names = append(names, d.Name)
+		}
+
+		if !page.HasMore {
+			break
+		}
+		cursor = page.Cursor
+	}
+
+	assert.Equal(t, []int{2, 2, 1}, sizes)
+	assert.Equal(t, []string{"oid-0", "oid-1", "oid-2", "oid-3", "oid-4"}, names)
+}
@@ -10,10 +10,14 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func toMongoFilter(q *docstore.QueryOpt) (bson.M, *options.FindOptions) {
+func toMongoFilter(q *docstore.QueryOpt) (bson.M, *options.FindOptions, error) {
+	if err := q.Validate(); err != nil {
+		return nil, nil, err
+	}
+
 	d := bson.M{}
-	for _, s := range q.Filter {
-		d[s.Field] = toMongoM(s)
+	if len(q.Filter) > 0 {
+		d = toMongoGroup("$and", q.Filter)
 	}
 
 	if q.Page > 0 && q.Limit > 0 {
@@ -23,20 +27,56 @@ func toMongoFilter(q *docstore.QueryOpt) (bson.M, *options.FindOptions) {
 	opt := options.Find()
 	opt.SetLimit(int64(q.Limit))
 	opt.SetSkip(int64(q.Skip))
-	if q.OrderBy != "" {
-		dir := -1
-		if q.IsAscend {
-			dir = 1
+
+	if sorts := q.Sorts(); len(sorts) > 0 {
+		sort := bson.D{}
+		for _, s := range sorts {
+			dir := -1
+			if s.IsAscend {
+				dir = 1
+			}
+			sort = append(sort, bson.E{Key: s.Field, Value: dir})
+		}
+		opt.SetSort(sort)
+	}
+
+	if len(q.Fields) > 0 {
+		projection := bson.D{}
+		for _, f := range q.Fields {
+			projection = append(projection, bson.E{Key: f, Value: 1})
 		}
-		opt.SetSort(bson.D{{Key: q.OrderBy, Value: dir}})
+		opt.SetProjection(projection)
 	}
 
-	return d, opt
+	return d, opt, nil
+}
+
+func toMongoGroup(op string, filters []docstore.FilterOpt) bson.M {
+	exps := make(bson.A, len(filters))
+	for i, f := range filters {
+		exps[i] = toMongoExpression(f)
+	}
+
+	return bson.M{op: exps}
+}
+
+// toMongoExpression convert validated filter into mongo query expression
+func toMongoExpression(f docstore.FilterOpt) bson.M {
+	switch f.Logic {
+	case docstore.LogicAnd:
+		return toMongoGroup("$and", f.Filters)
+	case docstore.LogicOr:
+		return toMongoGroup("$or", f.Filters)
+	case docstore.LogicNot:
+		return bson.M{"$nor": bson.A{toMongoGroup("$and", f.Filters)}}
+	}
+
+	return bson.M{f.Field: toMongoM(f)}
 }
 
 func toMongoM(f docstore.FilterOpt) bson.M {
 	switch f.Ops {
-	case constant.EQ:
+	case constant.EQ, constant.SE, "":
 		return bson.M{
 			"$eq": f.Value,
 		}
@@ -56,7 +96,7 @@ func toMongoM(f docstore.FilterOpt) bson.M {
 		return bson.M{
 			"$gte": f.Value,
 		}
-	case constant.NE:
+	case constant.NE, constant.SN:
 		return bson.M{
 			"$ne": f.Value,
 		}
@@ -64,11 +104,31 @@ func toMongoM(f docstore.FilterOpt) bson.M {
 		return bson.M{
 			"$in": f.Value,
 		}
+	case constant.NI:
+		return bson.M{
+			"$nin": f.Value,
+		}
+	case constant.BT:
+		min, max, _ := f.Range()
+		return bson.M{
+			"$gte": min,
+			"$lte": max,
+		}
+	case constant.EX:
+		// match null and missing field the same way as sql null
+		if exists, _ := f.Exists(); exists {
+			return bson.M{
+				"$ne": nil,
+			}
+		}
+		return bson.M{
+			"$eq": nil,
+		}
 	case constant.EM:
 		return bson.M{
 			"$elemMatch": f.Value,
 		}
-	case constant.RE:
+	case constant.RE, constant.IR:
 		return bson.M{
 			"$regex": primitive.Regex{
 				Pattern: fmt.Sprintf("%v", f.Value),
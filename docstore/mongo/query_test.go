@@ -0,0 +1,47 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/diki-haryadi/govega/constant"
+	"github.com/diki-haryadi/govega/docstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestToMongoFilter(t *testing.T) {
+	q := &docstore.QueryOpt{
+		Filter: []docstore.FilterOpt{
+			{Field: "age", Ops: constant.GE, Value: 30},
+			{Field: "age", Ops: constant.LT, Value: 40},
+			docstore.Or(
+				docstore.FilterOpt{Field: "name", Ops: constant.NI, Value: []string{"sahal"}},
+				docstore.Not(docstore.FilterOpt{Field: "username", Ops: constant.EX}),
+			),
+		},
+		OrderBy:  "name",
+		IsAscend: true,
+		Sort:     []docstore.SortOpt{{Field: "age"}},
+		Fields:   []string{"id", "name"},
+	}
+
+	f, opt, err := toMongoFilter(q)
+	require.NoError(t, err)
+
+	assert.Equal(t, bson.M{"$and": bson.A{
+		bson.M{"age": bson.M{"$gte": 30}},
+		bson.M{"age": bson.M{"$lt": 40}},
+		bson.M{"$or": bson.A{
+			bson.M{"name": bson.M{"$nin": []string{"sahal"}}},
+			bson.M{"$nor": bson.A{bson.M{"$and": bson.A{bson.M{"username": bson.M{"$ne": nil}}}}}},
+		}},
+	}}, f)
+	assert.Equal(t, bson.D{{Key: "name", Value: 1}, {Key: "age", Value: -1}}, opt.Sort)
+	assert.Equal(t, bson.D{{Key: "id", Value: 1}, {Key: "name", Value: 1}}, opt.Projection)
+
+	_, _, err = toMongoFilter(&docstore.QueryOpt{
+		Filter: []docstore.FilterOpt{docstore.Or()},
+	})
+	assert.ErrorIs(t, err, docstore.UnsupportedQuery)
+}
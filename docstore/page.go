@@ -0,0 +1,303 @@
+package docstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/diki-haryadi/govega/constant"
+	"github.com/diki-haryadi/govega/util"
+)
+
+const cursorTime = "time"
+
+// cursorValues registered cursor value types by name
+var cursorValues = map[string]CursorValue{}
+
+// CursorValue keep the type of sort key which is not json native in the cursor, e.g. mongo ObjectID
+type CursorValue struct {
+	// Encode return string of the value and true when the value is of this type
+	Encode func(v interface{}) (string, bool)
+	// Decode return the typed value of the string
+	Decode func(s string) (interface{}, error)
+}
+
+// PageResult result of cursor based find
+type PageResult struct {
+	// Cursor opaque token to fetch the next page, empty when there is no more page
+	Cursor  string
+	HasMore bool
+}
+
+// RegisterCursorValue register cursor value type with the name, drivers register their key types on init
+func RegisterCursorValue(name string, cv CursorValue) {
+	cursorValues[name] = cv
+}
+
+// FindFunc find documents matching the query
+type FindFunc func(ctx context.Context, query *QueryOpt, docs interface{}) error
+
+// cursor keep sort key of the last document of the page,
+// fields are prefixed with - on descending order
+type cursor struct {
+	Fields []string      `json:"f"`
+	Values []cursorValue `json:"v"`
+}
+
+type cursorValue struct {
+	Type  string      `json:"t,omitempty"`
+	Value interface{} `json:"v"`
+}
+
+// Paginate find a page of documents using keyset pagination, documents are fetched after
+// the sort key of the cursor instead of skipping the previous pages.
+// The query should have a limit, Skip and Page are ignored. ID field is appended to the sort
+// so documents with the same sort key are never skipped or returned twice.
+// Sort fields should not be null and should be part of the projection if any.
+func Paginate(ctx context.Context, find FindFunc, idField string, query *QueryOpt, token string, docs interface{}) (*PageResult, error) {
+	if !util.IsPointerOfSlice(docs) {
+		return nil, errors.New("[docstore] docs should be a pointer of slice")
+	}
+
+	if query.Limit <= 0 {
+		return nil, fmt.Errorf("%w: page limit should be greater than zero", UnsupportedQuery)
+	}
+
+	pq, err := pageQuery(query, idField, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := find(ctx, pq, docs); err != nil {
+		return nil, err
+	}
+
+	rv := reflect.ValueOf(docs).Elem()
+	if rv.Len() <= query.Limit {
+		return &PageResult{}, nil
+	}
+
+	rv.Set(rv.Slice(0, query.Limit))
+
+	next, err := encodeCursor(pq.Sort, rv.Index(query.Limit-1).Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	return &PageResult{Cursor: next, HasMore: true}, nil
+}
+
+// pageQuery copy the query with keyset filter of the cursor, fetching one extra document
+// to find out if there is more page
+func pageQuery(query *QueryOpt, idField, token string) (*QueryOpt, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	sorts := query.Sorts()
+	hasID := false
+	for _, s := range sorts {
+		if s.Field == idField {
+			hasID = true
+			break
+		}
+	}
+
+	if !hasID {
+		asc := true
+		if len(sorts) > 0 {
+			asc = sorts[len(sorts)-1].IsAscend
+		}
+		sorts = append(sorts, SortOpt{Field: idField, IsAscend: asc})
+	}
+
+	pq := &QueryOpt{
+		Limit:  query.Limit + 1,
+		Sort:   sorts,
+		Filter: append([]FilterOpt{}, query.Filter...),
+	}
+
+	if len(query.Fields) > 0 {
+		pq.Fields = append([]string{}, query.Fields...)
+		for _, s := range sorts {
+			if !contains(pq.Fields, s.Field) {
+				pq.Fields = append(pq.Fields, s.Field)
+			}
+		}
+	}
+
+	if token == "" {
+		return pq, nil
+	}
+
+	values, err := decodeCursor(token, sorts)
+	if err != nil {
+		return nil, err
+	}
+
+	pq.Filter = append(pq.Filter, keysetFilter(sorts, values))
+	return pq, nil
+}
+
+// keysetFilter match documents sorted after the values:
+// (a > x) OR (a = x AND b > y) OR (a = x AND b = y AND id > z)
+func keysetFilter(sorts []SortOpt, values []interface{}) FilterOpt {
+	groups := make([]FilterOpt, len(sorts))
+	for i, s := range sorts {
+		filters := make([]FilterOpt, 0, i+1)
+		for j := 0; j < i; j++ {
+			filters = append(filters, FilterOpt{Field: sorts[j].Field, Ops: constant.EQ, Value: values[j]})
+		}
+
+		op := constant.LT
+		if s.IsAscend {
+			op = constant.GT
+		}
+		filters = append(filters, FilterOpt{Field: s.Field, Ops: op, Value: values[i]})
+		groups[i] = And(filters...)
+	}
+
+	return Or(groups...)
+}
+
+func sortKeys(sorts []SortOpt) []string {
+	keys := make([]string, len(sorts))
+	for i, s := range sorts {
+		keys[i] = s.Field
+		if !s.IsAscend {
+			keys[i] = "-" + s.Field
+		}
+	}
+	return keys
+}
+
+func encodeCursor(sorts []SortOpt, doc interface{}) (string, error) {
+	c := cursor{
+		Fields: sortKeys(sorts),
+		Values: make([]cursorValue, len(sorts)),
+	}
+
+	for i, s := range sorts {
+		val, err := lookupField(doc, s.Field)
+		if err != nil {
+			return "", err
+		}
+
+		c.Values[i] = toCursorValue(val)
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func toCursorValue(val interface{}) cursorValue {
+	if t, ok := val.(time.Time); ok {
+		return cursorValue{Type: cursorTime, Value: t.Format(time.RFC3339Nano)}
+	}
+
+	for name, cv := range cursorValues {
+		if s, ok := cv.Encode(val); ok {
+			return cursorValue{Type: name, Value: s}
+		}
+	}
+
+	return cursorValue{Value: val}
+}
+
+func decodeCursor(token string, sorts []SortOpt) ([]interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", InvalidCursor, err)
+	}
+
+	var c cursor
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("%w: %v", InvalidCursor, err)
+	}
+
+	keys := sortKeys(sorts)
+	if strings.Join(c.Fields, ",") != strings.Join(keys, ",") || len(c.Values) != len(keys) {
+		return nil, fmt.Errorf("%w: sort doesn't match the query", InvalidCursor)
+	}
+
+	values := make([]interface{}, len(c.Values))
+	for i, v := range c.Values {
+		switch val := v.Value.(type) {
+		case json.Number:
+			if n, err := val.Int64(); err == nil {
+				values[i] = n
+			} else if f, err := val.Float64(); err == nil {
+				values[i] = f
+			} else {
+				return nil, fmt.Errorf("%w: %v", InvalidCursor, err)
+			}
+		case string:
+			value, err := fromCursorValue(v.Type, val)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", InvalidCursor, err)
+			}
+			values[i] = value
+		default:
+			values[i] = val
+		}
+	}
+
+	return values, nil
+}
+
+func fromCursorValue(typ, val string) (interface{}, error) {
+	if typ == "" {
+		return val, nil
+	}
+
+	if typ == cursorTime {
+		return time.Parse(time.RFC3339Nano, val)
+	}
+
+	cv, ok := cursorValues[typ]
+	if !ok {
+		return nil, fmt.Errorf("unknown value type %s", typ)
+	}
+
+	return cv.Decode(val)
+}
+
+// lookupField get value of the json field from struct or map document
+func lookupField(doc interface{}, field string) (interface{}, error) {
+	name := field
+	if util.IsStructOrPointerOf(doc) {
+		fn, err := util.FindFieldByTag(doc, "json", field)
+		if err != nil {
+			return nil, fmt.Errorf("[docstore] missing sort field %s: %w", field, err)
+		}
+		name = fn
+	}
+
+	val, ok := util.Lookup(name, doc)
+	if !ok {
+		return nil, fmt.Errorf("[docstore] missing sort field %s", field)
+	}
+
+	return val, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
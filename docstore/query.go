@@ -1,20 +1,48 @@
 package docstore
 
-// FilterOpt filter option
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/diki-haryadi/govega/constant"
+)
+
+// logical operator of filter group
+const (
+	LogicAnd = "and"
+	LogicOr  = "or"
+	// LogicNot match document which doesn't match all of the filters
+	LogicNot = "not"
+)
+
+// FilterOpt filter option, filter with Logic is a group of nested Filters
+// combined with the logical operator instead of a field filter
 type FilterOpt struct {
-	Field string
-	Value interface{}
-	Ops   string
+	Field   string
+	Value   interface{}
+	Ops     string
+	Logic   string
+	Filters []FilterOpt
+}
+
+// SortOpt sort option
+type SortOpt struct {
+	Field    string
+	IsAscend bool
 }
 
-// QueryOpt query option
+// QueryOpt query option, all filters are combined with AND
 type QueryOpt struct {
 	Limit    int
 	Skip     int
 	Page     int
 	OrderBy  string
 	IsAscend bool
-	Filter   []FilterOpt
+	// Sort additional sort fields, applied after OrderBy
+	Sort []SortOpt
+	// Fields projection, only return the given fields, empty means all fields
+	Fields []string
+	Filter []FilterOpt
 }
 
 func (q *QueryOpt) AddFilter(filter FilterOpt) *QueryOpt {
@@ -27,3 +55,143 @@ func (q *QueryOpt) AddFilter(filter FilterOpt) *QueryOpt {
 	q.Filter = append(q.Filter, filter)
 	return q
 }
+
+// AddSort add sort field
+func (q *QueryOpt) AddSort(field string, isAscend bool) *QueryOpt {
+	q.Sort = append(q.Sort, SortOpt{Field: field, IsAscend: isAscend})
+	return q
+}
+
+// Sorts return all sort fields, starting with OrderBy
+func (q *QueryOpt) Sorts() []SortOpt {
+	sorts := make([]SortOpt, 0, len(q.Sort)+1)
+	if q.OrderBy != "" {
+		sorts = append(sorts, SortOpt{Field: q.OrderBy, IsAscend: q.IsAscend})
+	}
+
+	return append(sorts, q.Sort...)
+}
+
+// Validate check all filters are supported
+func (q *QueryOpt) Validate() error {
+	for _, f := range q.Filter {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range q.Sort {
+		if s.Field == "" {
+			return fmt.Errorf("%w: missing sort field", UnsupportedQuery)
+		}
+	}
+
+	return nil
+}
+
+// And create filter group matching all of the filters
+func And(filters ...FilterOpt) FilterOpt {
+	return FilterOpt{Logic: LogicAnd, Filters: filters}
+}
+
+// Or create filter group matching any of the filters
+func Or(filters ...FilterOpt) FilterOpt {
+	return FilterOpt{Logic: LogicOr, Filters: filters}
+}
+
+// Not create filter group matching document which doesn't match all of the filters
+func Not(filters ...FilterOpt) FilterOpt {
+	return FilterOpt{Logic: LogicNot, Filters: filters}
+}
+
+// IsGroup return true when filter is a group of nested filters
+func (f FilterOpt) IsGroup() bool {
+	return f.Logic != ""
+}
+
+// Validate check filter operator and value are supported
+func (f FilterOpt) Validate() error {
+	if f.IsGroup() {
+		switch f.Logic {
+		case LogicAnd, LogicOr, LogicNot:
+		default:
+			return fmt.Errorf("%w: logical operator %s", UnsupportedQuery, f.Logic)
+		}
+
+		if len(f.Filters) == 0 {
+			return fmt.Errorf("%w: empty %s filter group", UnsupportedQuery, f.Logic)
+		}
+
+		for _, sf := range f.Filters {
+			if err := sf.Validate(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if f.Field == "" {
+		return fmt.Errorf("%w: missing filter field", UnsupportedQuery)
+	}
+
+	switch f.Ops {
+	case "", constant.EQ, constant.SE, constant.GT, constant.GE, constant.LT, constant.LE,
+		constant.NE, constant.SN, constant.RE, constant.IR, constant.EM:
+		return nil
+	case constant.IN, constant.NI:
+		if _, err := f.Values(); err != nil {
+			return err
+		}
+		return nil
+	case constant.BT:
+		if _, _, err := f.Range(); err != nil {
+			return err
+		}
+		return nil
+	case constant.EX:
+		if _, err := f.Exists(); err != nil {
+			return err
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: operator %s", UnsupportedQuery, f.Ops)
+	}
+}
+
+// Values return value of IN and NOT IN filter as slice
+func (f FilterOpt) Values() ([]interface{}, error) {
+	rv := reflect.ValueOf(f.Value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("%w: %s value of %s should be a slice", UnsupportedQuery, f.Ops, f.Field)
+	}
+
+	out := make([]interface{}, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+
+	return out, nil
+}
+
+// Range return min and max value of BETWEEN filter
+func (f FilterOpt) Range() (interface{}, interface{}, error) {
+	values, err := f.Values()
+	if err != nil || len(values) != 2 {
+		return nil, nil, fmt.Errorf("%w: %s value of %s should be [min, max]", UnsupportedQuery, f.Ops, f.Field)
+	}
+
+	return values[0], values[1], nil
+}
+
+// Exists return value of EXISTS filter, nil value means the field should exist
+func (f FilterOpt) Exists() (bool, error) {
+	switch v := f.Value.(type) {
+	case nil:
+		return true, nil
+	case bool:
+		return v, nil
+	default:
+		return false, fmt.Errorf("%w: %s value of %s should be a boolean", UnsupportedQuery, f.Ops, f.Field)
+	}
+}
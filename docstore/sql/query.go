@@ -5,6 +5,7 @@ import (
 
 	"github.com/diki-haryadi/govega/constant"
 	"github.com/diki-haryadi/govega/docstore"
+	"github.com/doug-martin/goqu/v9/exp"
 )
 
 func (s *SQLStore) buildInsertQuery(obj map[string]interface{}) string {
@@ -30,6 +31,21 @@ func (s *SQLStore) buildUpdateQuery(obj map[string]interface{}, id interface{})
 	return stmt
 }
 
+func (s *SQLStore) buildConditionalUpdateQuery(obj map[string]interface{}, id interface{}, filters []docstore.FilterOpt) (string, error) {
+	delete(obj, s.idField)
+	where := []exp.Expression{goqu.Ex{s.idField: id}}
+	for _, f := range filters {
+		if err := f.Validate(); err != nil {
+			return "", err
+		}
+		where = append(where, toSQLExpression(f))
+	}
+
+	ds := goqu.Dialect(s.driver).Update(s.table).Set(goqu.Record(obj)).Where(where...)
+	stmt, _, err := ds.ToSQL()
+	return stmt, err
+}
+
 func (s *SQLStore) buildDeleteQuery(id interface{}) string {
 	ds := goqu.Dialect(s.driver).Delete(s.table).Where(goqu.Ex{s.idField: id})
 	stmt, _, _ := ds.ToSQL()
@@ -66,42 +82,29 @@ func (s *SQLStore) buildGetIncrQuery(id interface{}, key string, value int) stri
 	return stmt
 }
 
-func (s *SQLStore) buildFindQuery(opt *docstore.QueryOpt) string {
+func (s *SQLStore) buildFindQuery(opt *docstore.QueryOpt) (string, error) {
+	if err := opt.Validate(); err != nil {
+		return "", err
+	}
+
 	ds := goqu.Dialect(s.driver).From(s.table)
 
-	filter := make(map[string]interface{})
-	for _, f := range opt.Filter {
-		switch f.Ops {
-		case constant.EQ, constant.SE:
-			filter[f.Field] = f.Value
-			continue
-		case constant.GT:
-			filter[f.Field] = goqu.Op{"gt": f.Value}
-			continue
-		case constant.GE:
-			filter[f.Field] = goqu.Op{"gte": f.Value}
-			continue
-		case constant.LT:
-			filter[f.Field] = goqu.Op{"lt": f.Value}
-			continue
-		case constant.LE:
-			filter[f.Field] = goqu.Op{"lte": f.Value}
-			continue
-		case constant.NE, constant.SN:
-			filter[f.Field] = goqu.Op{"neq": f.Value}
-			continue
-		case constant.IN, constant.EM:
-			filter[f.Field] = goqu.Op{"in": f.Value}
-			continue
-		case constant.RE:
-			filter[f.Field] = goqu.Op{"like": f.Value}
-			continue
-		default:
-			filter[f.Field] = f.Value
+	if len(opt.Fields) > 0 {
+		cols := make([]interface{}, len(opt.Fields))
+		for i, f := range opt.Fields {
+			cols[i] = goqu.C(f)
+		}
+		ds = ds.Select(cols...)
+	}
+
+	if len(opt.Filter) > 0 {
+		where := make([]exp.Expression, len(opt.Filter))
+		for i, f := range opt.Filter {
+			where[i] = toSQLExpression(f)
 		}
+		ds = ds.Where(where...)
 	}
 
-	ds = ds.Where(goqu.Ex(filter))
 	if opt.Limit > 0 {
 		ds = ds.Limit(uint(opt.Limit))
 		if opt.Page > 0 {
@@ -113,15 +116,68 @@ func (s *SQLStore) buildFindQuery(opt *docstore.QueryOpt) string {
 		ds = ds.Offset(uint(opt.Skip))
 	}
 
-	if opt.OrderBy != "" {
-		col := goqu.C(opt.OrderBy)
-		if opt.IsAscend {
-			ds = ds.Order(col.Asc())
+	for _, sort := range opt.Sorts() {
+		col := goqu.C(sort.Field)
+		if sort.IsAscend {
+			ds = ds.OrderAppend(col.Asc())
 		} else {
-			ds = ds.Order(col.Desc())
+			ds = ds.OrderAppend(col.Desc())
 		}
 	}
 
-	stmt, _, _ := ds.ToSQL()
-	return stmt
+	stmt, _, err := ds.ToSQL()
+	return stmt, err
+}
+
+// toSQLExpression convert validated filter into sql expression
+func toSQLExpression(f docstore.FilterOpt) exp.Expression {
+	switch f.Logic {
+	case docstore.LogicAnd, docstore.LogicOr, docstore.LogicNot:
+		exps := make([]exp.Expression, len(f.Filters))
+		for i, sf := range f.Filters {
+			exps[i] = toSQLExpression(sf)
+		}
+
+		switch f.Logic {
+		case docstore.LogicOr:
+			return goqu.Or(exps...)
+		case docstore.LogicNot:
+			return goqu.L("NOT ?", goqu.And(exps...))
+		default:
+			return goqu.And(exps...)
+		}
+	}
+
+	col := goqu.C(f.Field)
+
+	switch f.Ops {
+	case constant.GT:
+		return col.Gt(f.Value)
+	case constant.GE:
+		return col.Gte(f.Value)
+	case constant.LT:
+		return col.Lt(f.Value)
+	case constant.LE:
+		return col.Lte(f.Value)
+	case constant.NE, constant.SN:
+		return col.Neq(f.Value)
+	case constant.IN, constant.EM:
+		return col.In(f.Value)
+	case constant.NI:
+		return col.NotIn(f.Value)
+	case constant.RE:
+		return col.Like(f.Value)
+	case constant.IR:
+		return col.RegexpILike(f.Value)
+	case constant.BT:
+		min, max, _ := f.Range()
+		return col.Between(goqu.Range(min, max))
+	case constant.EX:
+		if exists, _ := f.Exists(); exists {
+			return col.IsNotNull()
+		}
+		return col.IsNull()
+	default:
+		return col.Eq(f.Value)
+	}
 }
@@ -3,7 +3,10 @@ package sql
 import (
 	"testing"
 
+	"github.com/diki-haryadi/govega/constant"
+	"github.com/diki-haryadi/govega/docstore"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInsertQuery(t *testing.T) {
@@ -33,6 +36,14 @@ func TestUpdateQuery(t *testing.T) {
 
 }
 
+func TestConditionalUpdateQuery(t *testing.T) {
+	s := &SQLStore{table: "user", idField: "id"}
+	st, err := s.buildConditionalUpdateQuery(map[string]interface{}{"name": "sahal"}, "1234",
+		[]docstore.FilterOpt{{Field: "age", Ops: constant.EQ, Value: 30}})
+	require.NoError(t, err)
+	assert.Equal(t, `UPDATE "user" SET "name"='sahal' WHERE (("id" = '1234') AND ("age" = 30))`, st)
+}
+
 func TestDeleteQuery(t *testing.T) {
 	s := &SQLStore{table: "user", idField: "id"}
 	st := s.buildDeleteQuery("1234")
@@ -82,3 +93,69 @@ func TestBulkGet(t *testing.T) {
 	st := s.buildBulkGetQuery([]interface{}{"1234", "1235"})
 	assert.Equal(t, `SELECT * FROM "user" WHERE ("id" IN ('1234', '1235'))`, st)
 }
+
+func TestFindQuery(t *testing.T) {
+	s := &SQLStore{table: "user", idField: "id"}
+
+	tests := []struct {
+		name     string
+		query    *docstore.QueryOpt
+		expected string
+	}{
+		{
+			name: "same field",
+			query: &docstore.QueryOpt{
+				Filter: []docstore.FilterOpt{
+					{Field: "age", Ops: constant.GE, Value: 30},
+					{Field: "age", Ops: constant.LT, Value: 40},
+				},
+			},
+			expected: `SELECT * FROM "user" WHERE (("age" >= 30) AND ("age" < 40))`,
+		},
+		{
+			name: "nested group",
+			query: &docstore.QueryOpt{
+				Filter: []docstore.FilterOpt{
+					docstore.Or(
+						docstore.FilterOpt{Field: "name", Ops: constant.EQ, Value: "sahal"},
+						docstore.Not(docstore.FilterOpt{Field: "age", Ops: constant.BT, Value: []int{30, 40}}),
+					),
+				},
+			},
+			expected: `SELECT * FROM "user" WHERE (("name" = 'sahal') OR NOT ("age" BETWEEN 30 AND 40))`,
+		},
+		{
+			name: "operators",
+			query: &docstore.QueryOpt{
+				Filter: []docstore.FilterOpt{
+					{Field: "age", Ops: constant.NI, Value: []int{30, 31}},
+					{Field: "name", Ops: constant.IR, Value: "^sa"},
+					{Field: "username", Ops: constant.EX, Value: false},
+				},
+			},
+			expected: `SELECT * FROM "user" WHERE (("age" NOT IN (30, 31)) AND ("name" ~* '^sa') AND ("username" IS NULL))`,
+		},
+		{
+			name: "sort and projection",
+			query: &docstore.QueryOpt{
+				OrderBy:  "name",
+				IsAscend: true,
+				Sort:     []docstore.SortOpt{{Field: "age"}},
+				Fields:   []string{"id", "name"},
+				Limit:    10,
+			},
+			expected: `SELECT "id", "name" FROM "user" ORDER BY "name" ASC, "age" DESC LIMIT 10`,
+		},
+	}
+
+	for _, tt := range tests {
+		st, err := s.buildFindQuery(tt.query)
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.expected, st, tt.name)
+	}
+
+	_, err := s.buildFindQuery(&docstore.QueryOpt{
+		Filter: []docstore.FilterOpt{{Field: "age", Ops: "unknown"}},
+	})
+	assert.ErrorIs(t, err, docstore.UnsupportedQuery)
+}
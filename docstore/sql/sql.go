@@ -97,8 +97,13 @@ func (s *SQLStore) Create(ctx context.Context, doc interface{}) error {
 		return err
 	}
 
+	ex, err := getExecutor(ctx, s.db)
+	if err != nil {
+		return err
+	}
+
 	gs := s.buildGetQuery(id)
-	ok, err := s.recordExists(gs)
+	ok, err := s.recordExists(ctx, ex, gs)
 	if err != nil {
 		return err
 	}
@@ -121,10 +126,6 @@ func (s *SQLStore) Create(ctx context.Context, doc interface{}) error {
 		}
 	}
 
-	ex, err := getExecutor(ctx, s.db)
-	if err != nil {
-		return err
-	}
 	stmt := s.buildInsertQuery(d)
 
 	if _, err = ex.ExecContext(ctx, stmt); err != nil {
@@ -192,6 +193,35 @@ func (s *SQLStore) UpdateField(ctx context.Context, id interface{}, fields []doc
 	return err
 }
 
+func (s *SQLStore) UpdateFieldIf(ctx context.Context, id interface{}, filters []docstore.FilterOpt, fields []docstore.Field) error {
+	tr := otel.Tracer("docstore/sql")
+	ctx, span := tr.Start(ctx, "docstore.update_field_if")
+	defer span.End()
+	ex, err := getExecutor(ctx, s.db)
+	if err != nil {
+		return err
+	}
+
+	d := make(map[string]interface{})
+	for _, f := range fields {
+		d[f.Name] = f.Value
+	}
+
+	us, err := s.buildConditionalUpdateQuery(d, id, filters)
+	if err != nil {
+		return err
+	}
+
+	res, err := ex.ExecContext(ctx, us)
+	if err != nil {
+		return err
+	}
+	if c, _ := res.RowsAffected(); c == 0 {
+		return docstore.NotFound
+	}
+	return nil
+}
+
 func (s *SQLStore) Increment(ctx context.Context, id interface{}, key string, value int) error {
 	tr := otel.Tracer("docstore/sql")
 	ctx, span := tr.Start(ctx, "docstore.increment")
@@ -269,8 +299,23 @@ func (s *SQLStore) Find(ctx context.Context, query *docstore.QueryOpt, docs inte
 	tr := otel.Tracer("docstore/sql")
 	ctx, span := tr.Start(ctx, "docstore.find")
 	defer span.End()
-	fs := s.buildFindQuery(query)
-	return s.db.SelectContext(ctx, docs, fs)
+	ex, err := getExecutor(ctx, s.db)
+	if err != nil {
+		return err
+	}
+
+	fs, err := s.buildFindQuery(query)
+	if err != nil {
+		return err
+	}
+	return ex.SelectContext(ctx, docs, fs)
+}
+
+func (s *SQLStore) FindPage(ctx context.Context, query *docstore.QueryOpt, cursor string, docs interface{}) (*docstore.PageResult, error) {
+	tr := otel.Tracer("docstore/sql")
+	ctx, span := tr.Start(ctx, "docstore.find_page")
+	defer span.End()
+	return docstore.Paginate(ctx, s.Find, s.idField, query, cursor, docs)
 }
 
 func (s *SQLStore) BulkCreate(ctx context.Context, docs []interface{}) error {
@@ -322,10 +367,10 @@ func (s *SQLStore) BulkGet(ctx context.Context, ids []interface{}, docs interfac
 	return ex.SelectContext(ctx, docs, gs)
 }
 
-func (s *SQLStore) recordExists(query string) (bool, error) {
+func (s *SQLStore) recordExists(ctx context.Context, ex QueryExecutor, query string) (bool, error) {
 	var exists bool
 	query = fmt.Sprintf("SELECT exists (%s)", query)
-	err := s.db.QueryRow(query).Scan(&exists)
+	err := ex.QueryRowxContext(ctx, query).Scan(&exists)
 	if err != nil && err != sql.ErrNoRows {
 		return false, err
 	}
@@ -343,6 +388,34 @@ func (s *SQLStore) Migrate(ctx context.Context, config interface{}) error {
 	return nil
 }
 
+// WithTransaction run fn within database transaction, nested call join the current transaction
+func (s *SQLStore) WithTransaction(ctx context.Context, fn docstore.TxFunc) error {
+	if _, ok := ctx.Value(constant.TxKey).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, constant.TxKey, tx)); err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			return fmt.Errorf("[docstore/sql] failed to rollback: %v: %w", rerr, err)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func getExecutor(ctx context.Context, db *sqlx.DB) (QueryExecutor, error) {
 	tx, ok := ctx.Value(constant.TxKey).(*sqlx.Tx)
 	if ok {
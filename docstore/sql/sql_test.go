@@ -54,6 +54,9 @@ func TestSQLStore(t *testing.T) {
 	store := initStore()
 	docstore.DriverCRUDTest(store, t)
 	docstore.DriverBulkTest(store, t)
+	docstore.DriverPageTest(store, t)
+	docstore.DriverTransactionTest(store, t)
+	docstore.DriverConditionalTest(store, t)
 }
 
 func TestIncrement(t *testing.T) {
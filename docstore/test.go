@@ -2,6 +2,7 @@ package docstore
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -116,6 +117,131 @@ func DriverCRUDTest(d Driver, t *testing.T) {
 	assert.Equal(t, 3, len(out))
 	assert.Equal(t, 37, out[0].Age)
 
+	queries := []struct {
+		name     string
+		filter   []FilterOpt
+		expected int
+	}{
+		{
+			name: "same field",
+			filter: []FilterOpt{
+				{Field: "age", Ops: constant.GE, Value: 32},
+				{Field: "age", Ops: constant.LT, Value: 35},
+			},
+			expected: 3,
+		},
+		{
+			name: "or",
+			filter: []FilterOpt{
+				Or(
+					FilterOpt{Field: "name", Ops: constant.EQ, Value: "name1"},
+					FilterOpt{Field: "age", Ops: constant.GE, Value: 38},
+				),
+			},
+			expected: 3,
+		},
+		{
+			name: "not",
+			filter: []FilterOpt{
+				Not(FilterOpt{Field: "age", Ops: constant.LT, Value: 35}),
+			},
+			expected: 5,
+		},
+		{
+			name: "nested",
+			filter: []FilterOpt{
+				{Field: "age", Ops: constant.GE, Value: 30},
+				Or(
+					FilterOpt{Field: "name", Ops: constant.EQ, Value: "name1"},
+					And(
+						FilterOpt{Field: "name", Ops: constant.EQ, Value: "name2"},
+						FilterOpt{Field: "age", Ops: constant.EQ, Value: 32},
+					),
+				),
+				Not(FilterOpt{Field: "age", Ops: constant.EQ, Value: 31}),
+			},
+			expected: 1,
+		},
+		{
+			name: "not in",
+			filter: []FilterOpt{
+				{Field: "age", Ops: constant.NI, Value: []int{30, 31, 32}},
+			},
+			expected: 7,
+		},
+		{
+			name: "between",
+			filter: []FilterOpt{
+				{Field: "age", Ops: constant.BT, Value: []int{33, 35}},
+			},
+			expected: 3,
+		},
+		{
+			name: "case insensitive match",
+			filter: []FilterOpt{
+				{Field: "name", Ops: constant.IR, Value: "^NAME[12]$"},
+			},
+			expected: 2,
+		},
+		{
+			name: "exists",
+			filter: []FilterOpt{
+				{Field: "name", Ops: constant.EX, Value: true},
+			},
+			expected: 10,
+		},
+		{
+			name: "not exists",
+			filter: []FilterOpt{
+				{Field: "name", Ops: constant.EX, Value: false},
+			},
+			expected: 0,
+		},
+	}
+
+	for _, tt := range queries {
+		out = nil
+		require.Nil(t, d.Find(ctx, &QueryOpt{Filter: tt.filter}, &out), tt.name)
+		assert.Equal(t, tt.expected, len(out), tt.name)
+	}
+
+	q = &QueryOpt{
+		Filter: []FilterOpt{
+			{Field: "age", Ops: constant.GE, Value: 30},
+		},
+		Sort: []SortOpt{
+			{Field: "username", IsAscend: true},
+			{Field: "age", IsAscend: false},
+		},
+		Limit: 2,
+	}
+
+	out = nil
+	require.Nil(t, d.Find(ctx, q, &out))
+	require.Equal(t, 2, len(out))
+	assert.Equal(t, 39, out[0].Age)
+	assert.Equal(t, 38, out[1].Age)
+
+	q = &QueryOpt{
+		Filter: []FilterOpt{
+			{Field: "name", Ops: constant.EQ, Value: "name1"},
+		},
+		Fields: []string{"id", "name"},
+	}
+
+	out = nil
+	require.Nil(t, d.Find(ctx, q, &out))
+	require.Equal(t, 1, len(out))
+	assert.Equal(t, "1", out[0].ID)
+	assert.Equal(t, "name1", out[0].Name)
+	assert.Equal(t, 0, out[0].Age)
+
+	q = &QueryOpt{
+		Filter: []FilterOpt{
+			{Field: "age", Ops: constant.BT, Value: 30},
+		},
+	}
+	assert.ErrorIs(t, d.Find(ctx, q, &out), UnsupportedQuery)
 }
 
 func DriverBulkTest(d Driver, t *testing.T) {
@@ -158,6 +284,162 @@ func DriverBulkTest(d Driver, t *testing.T) {
 	}
 }
 
+func DriverPageTest(d Driver, t *testing.T) {
+	type User struct {
+		ID        string    `json:"id"`
+		Name      string    `json:"name"`
+		Username  string    `json:"username"`
+		Age       int       `json:"age"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	ctx := context.Background()
+	ts := time.Now()
+
+	ages := []int{20, 21, 21, 21, 22, 23, 23}
+	for i, age := range ages {
+		u := &User{
+			ID:        fmt.Sprintf("PG-%v", i),
+			Name:      "page" + fmt.Sprintf("%v", i),
+			Username:  "paging",
+			Age:       age,
+			CreatedAt: ts.Add(time.Duration(i) * time.Second),
+		}
+		require.Nil(t, d.Create(ctx, u))
+	}
+
+	q := &QueryOpt{
+		Filter: []FilterOpt{
+			{Field: "username", Ops: constant.EQ, Value: "paging"},
+		},
+		OrderBy:  "age",
+		IsAscend: false,
+		Limit:    2,
+	}
+
+	seen := make(map[string]bool)
+	sizes := make([]int, 0)
+	last := 100
+	cursor := ""
+	for {
+		var out []User
+		page, err := d.FindPage(ctx, q, cursor, &out)
+		require.Nil(t, err)
+		sizes = append(sizes, len(out))
+
+		for _, u := range out {
+			assert.False(t, seen[u.ID], "document returned twice %s", u.ID)
+			assert.LessOrEqual(t, u.Age, last)
+			seen[u.ID] = true
+			last = u.Age
+		}
+
+		if !page.HasMore {
+			assert.Empty(t, page.Cursor)
+			break
+		}
+		require.NotEmpty(t, page.Cursor)
+		cursor = page.Cursor
+	}
+
+	assert.Equal(t, []int{2, 2, 2, 1}, sizes)
+	assert.Equal(t, len(ages), len(seen))
+
+	q = &QueryOpt{
+		Filter: []FilterOpt{
+			{Field: "username", Ops: constant.EQ, Value: "paging"},
+		},
+		OrderBy:  "created_at",
+		IsAscend: true,
+		Fields:   []string{"id", "name"},
+		Limit:    4,
+	}
+
+	var out []User
+	page, err := d.FindPage(ctx, q, "", &out)
+	require.Nil(t, err)
+	require.Equal(t, 4, len(out))
+	assert.True(t, page.HasMore)
+	assert.Equal(t, "PG-3", out[3].ID)
+
+	out = nil
+	next, err := d.FindPage(ctx, q, page.Cursor, &out)
+	require.Nil(t, err)
+	require.Equal(t, 3, len(out))
+	assert.False(t, next.HasMore)
+	assert.Equal(t, "PG-4", out[0].ID)
+	assert.Equal(t, "page4", out[0].Name)
+
+	q.IsAscend = false
+	_, err = d.FindPage(ctx, q, page.Cursor, &out)
+	assert.ErrorIs(t, err, InvalidCursor)
+
+	_, err = d.FindPage(ctx, q, "invalid", &out)
+	assert.ErrorIs(t, err, InvalidCursor)
+
+	q.Limit = 0
+	_, err = d.FindPage(ctx, q, "", &out)
+	assert.ErrorIs(t, err, UnsupportedQuery)
+}
+
+func DriverTransactionTest(d Driver, t *testing.T) {
+	type User struct {
+		ID        string    `json:"id"`
+		Name      string    `json:"name"`
+		Username  string    `json:"username"`
+		Age       int       `json:"age"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	tx, ok := d.(Transactional)
+	require.True(t, ok, "driver should implement Transactional")
+
+	ctx := context.Background()
+	usr := &User{
+		ID:        "TX-1",
+		Name:      "sahal",
+		Age:       35,
+		CreatedAt: time.Now(),
+	}
+	require.Nil(t, d.Create(ctx, usr))
+
+	failed := errors.New("failed")
+	err := tx.WithTransaction(ctx, func(ctx context.Context) error {
+		require.Nil(t, d.UpdateField(ctx, usr.ID, []Field{{Name: "age", Value: 40}}))
+		require.Nil(t, d.Create(ctx, &User{ID: "TX-2", Name: "rollback", CreatedAt: time.Now()}))
+
+		var user User
+		require.Nil(t, d.Get(ctx, usr.ID, &user))
+		assert.Equal(t, 40, user.Age, "read own write")
+		return failed
+	})
+	require.ErrorIs(t, err, failed)
+
+	var user User
+	require.Nil(t, d.Get(ctx, usr.ID, &user))
+	assert.Equal(t, 35, user.Age)
+	assert.ErrorIs(t, d.Get(ctx, "TX-2", &user), NotFound)
+
+	err = tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := d.UpdateField(ctx, usr.ID, []Field{{Name: "age", Value: 40}}); err != nil {
+			return err
+		}
+
+		// nested transaction join the current one
+		return tx.WithTransaction(ctx, func(ctx context.Context) error {
+			if err := d.Create(ctx, &User{ID: "TX-2", Name: "commit", CreatedAt: time.Now()}); err != nil {
+				return err
+			}
+			return d.Delete(ctx, usr.ID)
+		})
+	})
+	require.Nil(t, err)
+
+	assert.ErrorIs(t, d.Get(ctx, usr.ID, &user), NotFound)
+	require.Nil(t, d.Get(ctx, "TX-2", &user))
+	assert.Equal(t, "commit", user.Name)
+}
+
 func DocstoreTestCRUD(cs *CachedStore, t *testing.T) {
 	type User struct {
 		ID        string    `json:"id"`
@@ -248,4 +530,58 @@ func DocstoreTestCRUD(cs *CachedStore, t *testing.T) {
 	out = nil
 	require.Nil(t, cs.Find(ctx, q, &out))
 	assert.Equal(t, 5, len(out))
+
+	q.OrderBy = "age"
+	q.IsAscend = true
+	q.Limit = 3
+
+	out = nil
+	page, err := cs.FindPage(ctx, q, "", &out)
+	require.Nil(t, err)
+	require.Equal(t, 3, len(out))
+	assert.True(t, page.HasMore)
+	assert.Equal(t, 37, out[2].Age)
+
+	out = nil
+	page, err = cs.FindPage(ctx, q, page.Cursor, &out)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(out))
+	assert.False(t, page.HasMore)
+	assert.Equal(t, 38, out[0].Age)
+}
+
+func DriverConditionalTest(d Driver, t *testing.T) {
+	type User struct {
+		ID        string    `json:"id"`
+		Name      string    `json:"name"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	cd, ok := d.(Conditional)
+	require.True(t, ok, "driver should implement Conditional")
+
+	ctx := context.Background()
+	usr := &User{ID: "CAS-1", Name: "a", CreatedAt: time.Now().Truncate(time.Second)}
+	require.Nil(t, d.Create(ctx, usr))
+
+	err := cd.UpdateFieldIf(ctx, usr.ID,
+		[]FilterOpt{{Field: "name", Ops: constant.EQ, Value: "b"}},
+		[]Field{{Name: "name", Value: "c"}})
+	assert.ErrorIs(t, err, NotFound, "condition doesn't match")
+
+	// compare-and-swap on the current time value
+	swap := func(old time.Time, name string) error {
+		return cd.UpdateFieldIf(ctx, usr.ID,
+			[]FilterOpt{{Field: "created_at", Ops: constant.EQ, Value: old}},
+			[]Field{{Name: "name", Value: name}, {Name: "created_at", Value: old.Add(time.Minute)}})
+	}
+	require.Nil(t, swap(usr.CreatedAt, "b"))
+	assert.ErrorIs(t, swap(usr.CreatedAt, "c"), NotFound, "value is already swapped")
+
+	var doc User
+	require.Nil(t, d.Get(ctx, usr.ID, &doc))
+	assert.Equal(t, "b", doc.Name)
+	assert.True(t, usr.CreatedAt.Add(time.Minute).Equal(doc.CreatedAt))
+
+	assert.ErrorIs(t, cd.UpdateFieldIf(ctx, "CAS-MISSING", nil, []Field{{Name: "name", Value: "a"}}), NotFound)
 }
@@ -0,0 +1,13 @@
+package docstore
+
+import "context"
+
+// TxFunc function executed within transaction, operations should use the given context
+type TxFunc func(ctx context.Context) error
+
+// Transactional driver supporting transaction
+type Transactional interface {
+	// WithTransaction run fn within transaction, commit when fn return nil and rollback otherwise.
+	// Nested call join the current transaction
+	WithTransaction(ctx context.Context, fn TxFunc) error
+}
@@ -0,0 +1,89 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/diki-haryadi/govega/response"
+	"github.com/diki-haryadi/govega/router"
+)
+
+type (
+	rateLimitRequest struct {
+		Limit float64 `json:"limit"`
+	}
+
+	scaleRequest struct {
+		Workers int `json:"workers"`
+	}
+)
+
+// AdminHandler register the worker pool control endpoints to the router, e.g.
+// r.Group("/admin/consumer", consumer.AdminHandler)
+//
+//	GET  /pools                          list worker pools status
+//	POST /pools/:topic/:group/pause      pause the subscription
+//	POST /pools/:topic/:group/resume     resume the subscription
+//	PUT  /pools/:topic/:group/rate_limit set rate limit, body: {"limit": 10}
+//	PUT  /pools/:topic/:group/workers    set worker count, body: {"workers": 4}
+func (c *Consumer) AdminHandler(r *router.MyRouter) {
+	r.GET("/pools", func(req *http.Request) *response.JSONResponse {
+		return response.NewJSONResponse().SetData(c.Pools())
+	})
+
+	r.POST("/pools/:topic/:group/pause", c.adminHandle(func(topic, group string, _ *http.Request) error {
+		return c.Pause(topic, group)
+	}))
+
+	r.POST("/pools/:topic/:group/resume", c.adminHandle(func(topic, group string, _ *http.Request) error {
+		return c.Resume(topic, group)
+	}))
+
+	r.PUT("/pools/:topic/:group/rate_limit", c.adminHandle(func(topic, group string, req *http.Request) error {
+		var body rateLimitRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return err
+		}
+		return c.SetRateLimit(topic, group, body.Limit)
+	}))
+
+	r.PUT("/pools/:topic/:group/workers", c.adminHandle(func(topic, group string, req *http.Request) error {
+		var body scaleRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return err
+		}
+		return c.Scale(topic, group, body.Workers)
+	}))
+}
+
+// adminHandle run fn with the topic group of the path and respond with the status of the pools
+func (c *Consumer) adminHandle(fn func(topic, group string, req *http.Request) error) router.Handle {
+	return func(req *http.Request) *response.JSONResponse {
+		topic := router.GetHttpParam(req.Context(), "topic")
+		group := router.GetHttpParam(req.Context(), "group")
+
+		if err := fn(topic, group, req); err != nil {
+			switch {
+			case errors.Is(err, ErrPoolNotFound):
+				return response.NewJSONResponse().SetError(response.ErrNotFound, err.Error())
+			case errors.Is(err, ErrScaleUnsupported):
+				return response.NewJSONResponse().SetError(response.ErrPreConditionFailed, err.Error())
+			default:
+				return response.NewJSONResponse().SetError(response.ErrBadRequest, err.Error())
+			}
+		}
+
+		topic = c.eventConfig.getTopic(topic)
+		group = c.eventConfig.getGroup(group)
+
+		pools := make([]PoolStatus, 0)
+		for _, p := range c.Pools() {
+			if p.Topic == topic && p.Group == group {
+				pools = append(pools, p)
+			}
+		}
+
+		return response.NewJSONResponse().SetData(pools)
+	}
+}
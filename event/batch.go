@@ -0,0 +1,287 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/diki-haryadi/govega/log"
+	"github.com/diki-haryadi/govega/monitor"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	DefaultBatchSize    = 100
+	DefaultBatchMaxWait = time.Second
+)
+
+type (
+	// BatchCommitter is implemented by iterator which able to commit multiple messages at once
+	BatchCommitter interface {
+		CommitBatch(ctx context.Context, messages []ConsumeMessage) error
+	}
+
+	// ConsumeBatch batch of messages received by batch subscriber
+	ConsumeBatch struct {
+		Messages  []ConsumeMessage
+		committer BatchCommitter
+	}
+
+	BatchEventHandler    func(ctx context.Context, messages []*EventConsumeMessage) error
+	BatchConsumeStrategy func(ctx context.Context, batch *ConsumeBatch, handler BatchEventHandler) error
+)
+
+// NewConsumeBatch create batch of messages, committer is optional
+func NewConsumeBatch(messages []ConsumeMessage, committer BatchCommitter) *ConsumeBatch {
+	return &ConsumeBatch{
+		Messages:  messages,
+		committer: committer,
+	}
+}
+
+// GetEventConsumeMessages return event messages of all messages in the batch
+func (b *ConsumeBatch) GetEventConsumeMessages(ctx context.Context) ([]*EventConsumeMessage, error) {
+	ems := make([]*EventConsumeMessage, len(b.Messages))
+	for i, m := range b.Messages {
+		em, err := m.GetEventConsumeMessage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ems[i] = em
+	}
+
+	return ems, nil
+}
+
+// Commit commit all messages in the batch, using iterator batch commit if available
+func (b *ConsumeBatch) Commit(ctx context.Context) error {
+	if len(b.Messages) == 0 {
+		return nil
+	}
+
+	if b.committer != nil {
+		return b.committer.CommitBatch(ctx, b.Messages)
+	}
+
+	for _, m := range b.Messages {
+		if err := m.Commit(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CommitBatchOnSuccessStrategy will only commit the whole batch if handler doesn't return error
+func CommitBatchOnSuccessStrategy(ctx context.Context, batch *ConsumeBatch, handler BatchEventHandler) error {
+	ems, err := batch.GetEventConsumeMessages(ctx)
+	if err != nil {
+		return fmt.Errorf("[event/commitBatchOnSuccessStrategy] failed to get event message: %w", err)
+	}
+
+	if err := handler(ctx, ems); err != nil {
+		return fmt.Errorf("[event/commitBatchOnSuccessStrategy] handler failed to process batch: %w", err)
+	}
+
+	if err := batch.Commit(ctx); err != nil {
+		return fmt.Errorf("[event/commitBatchOnSuccessStrategy] %w: %w", ErrCommitFailed, err)
+	}
+
+	return nil
+}
+
+// AlwaysCommitBatchStrategy will always commit the whole batch no matter what is the handler result
+func AlwaysCommitBatchStrategy(ctx context.Context, batch *ConsumeBatch, handler BatchEventHandler) error {
+	ems, err := batch.GetEventConsumeMessages(ctx)
+	if err != nil {
+		return fmt.Errorf("[event/alwaysCommitBatchStrategy] failed to get event message: %w", err)
+	}
+
+	if err := batch.Commit(ctx); err != nil {
+		return fmt.Errorf("[event/alwaysCommitBatchStrategy] %w: %w", ErrCommitFailed, err)
+	}
+
+	return handler(ctx, ems)
+}
+
+// SubscribeBatch subscribe to a topic with specific group receiving up to size messages at once,
+// or whatever received within maxWait after the first message of the batch.
+// Batch are processed one at a time by a single worker to keep the commit order
+// and consumer middlewares are not applied to batch handler.
+// this should be call before Start the consumer
+func (c *Consumer) SubscribeBatch(ctx context.Context, topic, group string, size int,
+	maxWait time.Duration, handler BatchEventHandler) error {
+	if c.isRunning() {
+		return ErrConsumerStarted
+	}
+
+	if handler == nil {
+		return errors.New("[event/consumer] missing batch handler")
+	}
+
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+
+	if maxWait <= 0 {
+		maxWait = DefaultBatchMaxWait
+	}
+
+	topic = c.eventConfig.getTopic(topic)
+	group = c.eventConfig.getGroup(group)
+
+	iterator, err := c.listener.Listen(ctx, topic, group)
+	if err != nil {
+		return fmt.
+			Errorf("[event/consumer] failed to get listener for topic: %w", err)
+	}
+
+	c.listenerPools = append(c.listenerPools, ListenerWorkerPool{
+		workers:       1,
+		iterator:      iterator,
+		batchHandler:  c.payload.BatchMiddleware(handler),
+		batchSize:     size,
+		batchMaxWait:  maxWait,
+		batchStrategy: c.batchStrategy,
+		topic:         topic,
+		group:         group,
+		tracer:        otel.Tracer("event/consumer"),
+		state:         newPoolState(topic, group),
+		control:       newPoolControl(1, false),
+	})
+
+	return nil
+}
+
+// WithBatchConsumeStrategy, set batch consume strategy for this consumer
+func (c *Consumer) WithBatchConsumeStrategy(strategy BatchConsumeStrategy) {
+	c.batchStrategy = strategy
+}
+
+func (k *ListenerWorkerPool) runBatch(stop <-chan bool, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	if closer, ok := k.iterator.(Closer); ok {
+		defer func(closer Closer) {
+			if err := closer.Close(); err != nil {
+				log.WithError(err).
+					Errorln("[listener/workerpool] failed to close iterator")
+			}
+		}(closer)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = context.WithValue(ctx, consumerGroupKey, k.group)
+
+	go func() {
+		select {
+		case <-stop:
+			log.Println("[listener/batch] stopping, cancel context and wait batch to complete")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		messages := k.collectBatch(ctx)
+
+		if ctx.Err() != nil {
+			// stopping, leave collected messages uncommitted so they are redelivered
+			log.Println("[listener/batch] stop processing batch")
+			return
+		}
+
+		if len(messages) == 0 {
+			continue
+		}
+
+		if err := k.consumeBatch(ctx, messages); err != nil {
+			log.WithContext(ctx).WithError(err).
+				Errorln("[listener/batch] failed to complete batch")
+		}
+	}
+}
+
+// collectBatch wait for the first message and then collect messages
+// until batch size is reached or max wait is elapsed
+func (k *ListenerWorkerPool) collectBatch(ctx context.Context) []ConsumeMessage {
+	messages := make([]ConsumeMessage, 0, k.batchSize)
+
+	fetchCtx := ctx
+	for len(messages) < k.batchSize {
+		// paused or throttled longer than max wait, process collected messages
+		if err := k.control.wait(fetchCtx); err != nil {
+			break
+		}
+
+		message, err := k.iterator.Next(fetchCtx)
+		if fetchCtx.Err() != nil {
+			break
+		}
+		k.state.fetched(err)
+
+		if err != nil {
+			log.WithContext(ctx).WithError(err).
+				Errorln("[listener/batch] failed to get next item")
+			continue
+		}
+
+		if message == nil {
+			continue
+		}
+
+		messages = append(messages, message)
+
+		if len(messages) == 1 {
+			var cancelWait context.CancelFunc
+			fetchCtx, cancelWait = context.WithTimeout(ctx, k.batchMaxWait)
+			defer cancelWait()
+		}
+	}
+
+	return messages
+}
+
+func (k *ListenerWorkerPool) consumeBatch(ctx context.Context, messages []ConsumeMessage) error {
+	links := make([]trace.Link, 0, len(messages))
+	for _, m := range messages {
+		if carrier, ok := m.(propagation.TextMapCarrier); ok {
+			sc := trace.SpanContextFromContext(otel.GetTextMapPropagator().Extract(ctx, carrier))
+			if sc.IsValid() {
+				links = append(links, trace.Link{SpanContext: sc})
+			}
+		}
+	}
+
+	ctx, span := k.tracer.Start(ctx, "listenerWorkerPool.consumeBatch",
+		trace.WithAttributes(semconv.MessagingOperationProcess),
+		trace.WithLinks(links...))
+	defer span.End()
+
+	committer, _ := k.iterator.(BatchCommitter)
+	batch := NewConsumeBatch(messages, committer)
+
+	start := time.Now()
+	k.state.begin(len(messages))
+
+	err := k.batchStrategy(ctx, batch, k.batchHandler)
+	k.state.done(len(messages), err)
+	monitor.FeedConsumerMetrics(k.topic, k.group, getMetricStatusFromError(err),
+		time.Since(start))
+
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+
+		return fmt.Errorf("failed to consume batch topic [%s] group [%s]: %w",
+			k.topic, k.group, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testBatchCommitter struct {
+	committed []ConsumeMessage
+}
+
+func (t *testBatchCommitter) CommitBatch(ctx context.Context, messages []ConsumeMessage) error {
+	t.committed = append(t.committed, messages...)
+	return nil
+}
+
+func TestCommitBatchOnSuccessStrategy(t *testing.T) {
+	tests := []struct {
+		name              string
+		handler           BatchEventHandler
+		expectedCommitted bool
+	}{
+		{
+			name: "success handle batch",
+			handler: func(ctx context.Context, messages []*EventConsumeMessage) error {
+				return nil
+			},
+			expectedCommitted: true,
+		},
+		{
+			name: "failed handle batch",
+			handler: func(ctx context.Context, messages []*EventConsumeMessage) error {
+				return errors.New("failed")
+			},
+			expectedCommitted: false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt // NOTE: https://github.com/golang/go/wiki/CommonMistakes#using-goroutines-on-loop-iterator-variables
+		t.Run(tt.name, func(t *testing.T) {
+			msgs := []*testConsumeMessage{
+				newTestConsumeMessage(&EventConsumeMessage{}),
+				newTestConsumeMessage(&EventConsumeMessage{}),
+			}
+			batch := NewConsumeBatch([]ConsumeMessage{msgs[0], msgs[1]}, nil)
+			CommitBatchOnSuccessStrategy(context.Background(), batch, tt.handler)
+
+			for _, cm := range msgs {
+				assert.Equal(t, tt.expectedCommitted, cm.committed)
+			}
+		})
+	}
+}
+
+func TestConsumeBatchCommitter(t *testing.T) {
+	committer := &testBatchCommitter{}
+	cm := newTestConsumeMessage(&EventConsumeMessage{})
+
+	batch := NewConsumeBatch([]ConsumeMessage{cm}, committer)
+	require.NoError(t, batch.Commit(context.Background()))
+
+	assert.Len(t, committer.committed, 1)
+	assert.False(t, cm.committed, "message should be committed through batch committer")
+}
+
+func TestConsumerSubscribeBatch(t *testing.T) {
+	conf := &ConsumerConfig{
+		Listener: &DriverConfig{Type: "TestConsumerSubscribeBatch"},
+	}
+
+	testListener := newTestListener()
+	RegisterListener("TestConsumerSubscribeBatch", testListener.factory)
+
+	ctx := context.Background()
+
+	consumer, err := NewConsumer(ctx, conf)
+	require.NoError(t, err)
+
+	var lock sync.Mutex
+	batches := [][]*EventConsumeMessage{}
+
+	err = consumer.SubscribeBatch(ctx, "test", "test", 3, 100*time.Millisecond,
+		func(_ context.Context, messages []*EventConsumeMessage) error {
+			lock.Lock()
+			defer lock.Unlock()
+			batches = append(batches, messages)
+			return nil
+		})
+	require.NoError(t, err)
+	require.NoError(t, consumer.Start())
+
+	msgs := make([]*testConsumeMessage, 5)
+	for i := range msgs {
+		msgs[i] = newTestConsumeMessage(&EventConsumeMessage{Key: "key"})
+		testListener.ch <- msgs[i]
+	}
+
+	// wait for max wait of second batch to elapse
+	time.Sleep(300 * time.Millisecond)
+	require.NoError(t, consumer.Stop())
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	require.Len(t, batches, 2)
+	assert.Len(t, batches[0], 3)
+	assert.Len(t, batches[1], 2)
+
+	for _, cm := range msgs {
+		assert.True(t, cm.committed)
+	}
+}
+
+func TestConsumerSubscribeBatchLogger(t *testing.T) {
+	conf := &ConsumerConfig{
+		Listener: &DriverConfig{Type: "logger"},
+	}
+
+	ctx := context.Background()
+
+	consumer, err := NewConsumer(ctx, conf)
+	require.NoError(t, err)
+
+	called := false
+	err = consumer.SubscribeBatch(ctx, "test", "test", 0, 0,
+		func(_ context.Context, _ []*EventConsumeMessage) error {
+			called = true
+			return nil
+		})
+	require.NoError(t, err)
+	require.NoError(t, consumer.Start())
+
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, consumer.Stop())
+	assert.False(t, called)
+}
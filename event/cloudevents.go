@@ -0,0 +1,326 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	CloudEventsSpecVersion = "1.0"
+	ContentTypeCloudEvents = "application/cloudevents+json"
+
+	// CloudEventsStructured encode the message as cloudevents json, attributes and data are in the body
+	CloudEventsStructured = "structured"
+	// CloudEventsBinary put cloudevents attributes in the headers and data in the body
+	CloudEventsBinary = "binary"
+
+	HeaderContentType = "content-type"
+
+	ceSpecVersion     = "specversion"
+	ceID              = "id"
+	ceSource          = "source"
+	ceType            = "type"
+	ceTime            = "time"
+	ceDataContentType = "datacontenttype"
+	ceData            = "data"
+	ceDataBase64      = "data_base64"
+	cePartitionKey    = "partitionkey"
+	ceVersion         = "version"
+	// ceMetadata extension holding metadata which is not a valid extension name as json
+	ceMetadata = "metadata"
+)
+
+var (
+	ceExtensionName = regexp.MustCompile(`^[a-z0-9]{1,20}$`)
+
+	ceReserved = map[string]bool{
+		ceSpecVersion: true, ceID: true, ceSource: true, ceType: true, ceTime: true, ceDataContentType: true,
+		ceData: true, ceDataBase64: true, "dataschema": true, "subject": true, cePartitionKey: true,
+		ceVersion: true, ceMetadata: true,
+	}
+
+	// metadata mapped into cloudevents attributes
+	ceMapped = map[string]bool{
+		MetaEvent: true, MetaVersion: true, MetaTime: true, MetaHash: true, MetaContentType: true,
+	}
+)
+
+// ValidateCloudEventsMode return error when the mode is not empty, structured or binary
+func ValidateCloudEventsMode(mode string) error {
+	switch mode {
+	case "", CloudEventsStructured, CloudEventsBinary:
+		return nil
+	default:
+		return fmt.Errorf("[event/cloudevents] unsupported mode: %s", mode)
+	}
+}
+
+// EncodeCloudEvents encode the message into cloudevents v1.0 of the mode, topic is mapped into source,
+// key into partitionkey, event into type, version into version extension, timestamp into time and hash into id.
+// Other metadata is put into extensions. On binary mode attributes are set into headers with the prefix,
+// e.g. ce_ for kafka, and the returned body is the data
+func EncodeCloudEvents(message *EventMessage, mode, prefix string, headers propagation.TextMapCarrier) ([]byte, error) {
+	attrs, data, err := cloudEventsAttributes(message)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType, _ := attrs[ceDataContentType].(string)
+
+	if mode == CloudEventsBinary {
+		delete(attrs, ceDataContentType)
+		for k, v := range attrs {
+			headers.Set(prefix+k, formatCloudEventsValue(v))
+		}
+		headers.Set(HeaderContentType, contentType)
+
+		if isJSONContentType(contentType) {
+			return data, nil
+		}
+
+		var b []byte
+		if err := json.Unmarshal(data, &b); err != nil {
+			// not encoded by codec, send as is
+			return data, nil
+		}
+		return b, nil
+	}
+
+	var b []byte
+	if !isJSONContentType(contentType) && json.Unmarshal(data, &b) == nil {
+		// data of non json codec is json base64 string
+		attrs[ceDataBase64] = json.RawMessage(data)
+	} else if len(data) > 0 {
+		attrs[ceData] = json.RawMessage(data)
+	}
+
+	headers.Set(HeaderContentType, ContentTypeCloudEvents)
+	return json.Marshal(attrs)
+}
+
+// NewEventConsumeMessageFromHeaders return event consume message from binary mode cloudevents when
+// the headers has cloudevents attributes with the prefix, otherwise see NewEventConsumeMessage
+func NewEventConsumeMessageFromHeaders(v []byte, prefix string, headers propagation.TextMapCarrier) (*EventConsumeMessage, error) {
+	if headers == nil || headers.Get(prefix+ceSpecVersion) == "" {
+		return NewEventConsumeMessage(v)
+	}
+
+	attrs := make(map[string]interface{})
+	for _, k := range headers.Keys() {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		attrs[strings.TrimPrefix(k, prefix)] = headers.Get(k)
+	}
+
+	if _, ok := attrs[ceDataContentType]; !ok {
+		if ct := headers.Get(HeaderContentType); ct != "" {
+			attrs[ceDataContentType] = ct
+		}
+	}
+
+	if version, ok := attrs[ceVersion].(string); ok {
+		if n, err := strconv.Atoi(version); err == nil {
+			attrs[ceVersion] = n
+		}
+	}
+
+	em, err := fromCloudEventsAttributes(attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType, _ := attrs[ceDataContentType].(string)
+	if isJSONContentType(contentType) || len(v) == 0 {
+		em.Data = v
+		return em, nil
+	}
+
+	// keep data of non json codec as json base64 string like the default encoding
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	em.Data = data
+	return em, nil
+}
+
+// parseCloudEventsStructured return event consume message from structured mode cloudevents,
+// ok is false when the value is not cloudevents
+func parseCloudEventsStructured(v []byte) (*EventConsumeMessage, bool, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(v, &raw); err != nil {
+		return nil, false, nil
+	}
+
+	if _, ok := raw[ceSpecVersion]; !ok {
+		return nil, false, nil
+	}
+
+	attrs := make(map[string]interface{}, len(raw))
+	for k, r := range raw {
+		if k == ceData || k == ceDataBase64 {
+			continue
+		}
+
+		var value interface{}
+		if err := json.Unmarshal(r, &value); err != nil {
+			return nil, true, fmt.Errorf("failed to unmarshal cloudevents attribute %s: %w", k, err)
+		}
+		attrs[k] = value
+	}
+
+	em, err := fromCloudEventsAttributes(attrs)
+	if err != nil {
+		return nil, true, err
+	}
+
+	if data, ok := raw[ceDataBase64]; ok {
+		em.Data = data
+	} else {
+		em.Data = raw[ceData]
+	}
+
+	return em, true, nil
+}
+
+func cloudEventsAttributes(message *EventMessage) (map[string]interface{}, []byte, error) {
+	var data []byte
+	switch {
+	case message.Data != nil:
+		b, err := json.Marshal(message.Data)
+		if err != nil {
+			return nil, nil, err
+		}
+		data = b
+	case len(message.RawData) > 0:
+		data = message.RawData
+	}
+
+	attrs := map[string]interface{}{
+		ceSpecVersion:     CloudEventsSpecVersion,
+		ceSource:          message.Topic,
+		ceType:            GetMetadataEvent(message.Metadata),
+		ceDataContentType: ContentTypeJSON,
+	}
+
+	if message.Key != "" {
+		attrs[cePartitionKey] = message.Key
+	}
+
+	if id, ok := message.Metadata[MetaHash]; ok {
+		attrs[ceID] = fmt.Sprint(id)
+	} else {
+		h, err := hash(message.Data)
+		if err != nil {
+			return nil, nil, err
+		}
+		attrs[ceID] = h
+	}
+
+	if _, ok := message.Metadata[MetaVersion]; ok {
+		attrs[ceVersion] = GetMetadataVersion(message.Metadata)
+	}
+
+	switch t := message.Metadata[MetaTime].(type) {
+	case time.Time:
+		attrs[ceTime] = t.Format(time.RFC3339Nano)
+	case string:
+		attrs[ceTime] = t
+	}
+
+	if ct, ok := message.Metadata[MetaContentType].(string); ok && ct != "" {
+		attrs[ceDataContentType] = ct
+	}
+
+	others := make(map[string]interface{})
+	for k, v := range message.Metadata {
+		if ceMapped[k] {
+			continue
+		}
+
+		if ceExtensionName.MatchString(k) && !ceReserved[k] && isCloudEventsPrimitive(v) {
+			attrs[k] = v
+			continue
+		}
+		others[k] = v
+	}
+
+	if len(others) > 0 {
+		b, err := json.Marshal(others)
+		if err != nil {
+			return nil, nil, err
+		}
+		attrs[ceMetadata] = string(b)
+	}
+
+	return attrs, data, nil
+}
+
+func fromCloudEventsAttributes(attrs map[string]interface{}) (*EventConsumeMessage, error) {
+	metadata := make(map[string]interface{})
+
+	for k, v := range attrs {
+		switch k {
+		case ceSpecVersion, ceSource, cePartitionKey:
+		case ceID:
+			metadata[MetaHash] = v
+		case ceType:
+			metadata[MetaEvent] = v
+		case ceVersion:
+			metadata[MetaVersion] = v
+		case ceTime:
+			metadata[MetaTime] = v
+		case ceDataContentType:
+			metadata[MetaContentType] = v
+		case ceMetadata:
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid cloudevents metadata extension: %v", v)
+			}
+			if err := json.Unmarshal([]byte(s), &metadata); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal cloudevents metadata extension: %w", err)
+			}
+		default:
+			metadata[k] = v
+		}
+	}
+
+	em := &EventConsumeMessage{Metadata: metadata}
+	if key, ok := attrs[cePartitionKey].(string); ok {
+		em.Key = key
+	}
+
+	return em, nil
+}
+
+func formatCloudEventsValue(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case time.Time:
+		return t.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// isCloudEventsPrimitive return true when the value can be cloudevents extension value
+func isCloudEventsPrimitive(v interface{}) bool {
+	switch v.(type) {
+	case string, bool, int, int32, int64, float64, time.Time:
+		return true
+	default:
+		return false
+	}
+}
+
+func isJSONContentType(contentType string) bool {
+	return contentType == "" || contentType == ContentTypeJSON || strings.HasSuffix(contentType, "+json")
+}
@@ -0,0 +1,132 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// textCodec encode string payload as plain text
+type textCodec struct{}
+
+func (textCodec) ContentType() string {
+	return "text/plain"
+}
+
+func (textCodec) Marshal(v interface{}) ([]byte, error) {
+	return []byte(fmt.Sprint(v)), nil
+}
+
+func (textCodec) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*string)) = string(data)
+	return nil
+}
+
+func TestCloudEventsStructured(t *testing.T) {
+	message := &EventMessage{
+		Topic: "order",
+		Key:   "o123",
+		Data:  testOrder{ID: "o123", Amount: 10},
+		Metadata: map[string]interface{}{
+			MetaEvent:   "order_created",
+			MetaVersion: 2,
+			MetaHash:    "hash123",
+			MetaTime:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			"tenant":    "t1",
+			"dlq_topic": "order-dlq",
+		},
+	}
+
+	headers := propagation.MapCarrier{}
+	b, err := EncodeCloudEvents(message, CloudEventsStructured, "", headers)
+	require.NoError(t, err)
+	assert.Equal(t, ContentTypeCloudEvents, headers.Get(HeaderContentType))
+	assert.JSONEq(t, `{
+		"specversion": "1.0",
+		"id": "hash123",
+		"source": "order",
+		"type": "order_created",
+		"time": "2024-01-02T03:04:05Z",
+		"datacontenttype": "application/json",
+		"partitionkey": "o123",
+		"version": 2,
+		"tenant": "t1",
+		"metadata": "{\"dlq_topic\":\"order-dlq\"}",
+		"data": {"id": "o123", "amount": 10}
+	}`, string(b))
+
+	em, err := NewEventConsumeMessage(b)
+	require.NoError(t, err)
+
+	assert.Equal(t, "o123", em.Key)
+	assert.JSONEq(t, `{"id":"o123","amount":10}`, string(em.Data))
+	assert.Equal(t, "order_created", GetMetadataEvent(em.Metadata))
+	assert.Equal(t, 2, GetMetadataVersion(em.Metadata))
+	assert.Equal(t, "hash123", em.Metadata[MetaHash])
+	assert.Equal(t, "2024-01-02T03:04:05Z", em.Metadata[MetaTime])
+	assert.Equal(t, "t1", em.Metadata["tenant"])
+	assert.Equal(t, "order-dlq", em.Metadata["dlq_topic"])
+}
+
+func TestCloudEventsCodec(t *testing.T) {
+	RegisterCodec(textCodec{})
+
+	data, err := encodePayload(textCodec{}, "hello")
+	require.NoError(t, err)
+
+	message := &EventMessage{
+		Topic:    "greeting",
+		Data:     data,
+		Metadata: map[string]interface{}{MetaEvent: "greeted", MetaContentType: "text/plain"},
+	}
+
+	var received string
+	handler := Typed(nil, func(ctx context.Context, message *TypedMessage[string]) error {
+		received = message.Payload
+		return nil
+	})
+
+	headers := propagation.MapCarrier{}
+	b, err := EncodeCloudEvents(message, CloudEventsStructured, "", headers)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"data_base64":"aGVsbG8="`)
+
+	em, err := NewEventConsumeMessage(b)
+	require.NoError(t, err)
+	require.NoError(t, handler(context.Background(), em))
+	assert.Equal(t, "hello", received)
+
+	headers = propagation.MapCarrier{}
+	b, err = EncodeCloudEvents(message, CloudEventsBinary, "ce_", headers)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(b), "binary mode body is the encoded payload")
+	assert.Equal(t, "text/plain", headers.Get(HeaderContentType))
+	assert.Equal(t, "greeted", headers.Get("ce_type"))
+
+	received = ""
+	em, err = NewEventConsumeMessageFromHeaders(b, "ce_", headers)
+	require.NoError(t, err)
+	require.NoError(t, handler(context.Background(), em))
+	assert.Equal(t, "hello", received)
+}
+
+func TestEventConsumeMessageFromHeaders(t *testing.T) {
+	message := &EventMessage{Topic: "test", Key: "k1", Data: "testdata", Metadata: map[string]interface{}{MetaEvent: "test"}}
+
+	b, err := message.ToBytes()
+	require.NoError(t, err)
+
+	em, err := NewEventConsumeMessageFromHeaders(b, "ce_", propagation.MapCarrier{"traceparent": "parent"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `"testdata"`, string(em.Data), "default encoding is parsed without cloudevents headers")
+	assert.Equal(t, "test", GetMetadataEvent(em.Metadata))
+
+	assert.NoError(t, ValidateCloudEventsMode(""))
+	assert.NoError(t, ValidateCloudEventsMode(CloudEventsBinary))
+	assert.Error(t, ValidateCloudEventsMode("unknown"))
+}
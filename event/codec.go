@@ -0,0 +1,134 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	// MetaContentType content type of the payload encoded by typed publisher
+	MetaContentType = "content_type"
+
+	ContentTypeJSON = "application/json"
+)
+
+var (
+	codecs = map[string]Codec{
+		ContentTypeJSON: JSONCodec{},
+	}
+)
+
+type (
+	// Codec encode and decode event payload
+	Codec interface {
+		// ContentType of the encoded payload, binary payload is embedded in the event as base64 string
+		ContentType() string
+		Marshal(v interface{}) ([]byte, error)
+		Unmarshal(data []byte, v interface{}) error
+	}
+
+	// JSONCodec encode payload as json, the payload is embedded as is in the event
+	JSONCodec struct{}
+
+	// DecodeError failed to decode message payload, it is not retried by the consume strategies
+	DecodeError struct {
+		ContentType string
+		Err         error
+	}
+
+	nonRetryableError struct {
+		err error
+	}
+)
+
+// RegisterCodec register codec by its content type, used to decode payload with the content type metadata
+func RegisterCodec(codec Codec) {
+	codecs[codec.ContentType()] = codec
+}
+
+// GetCodec return registered codec of the content type
+func GetCodec(contentType string) (Codec, bool) {
+	c, ok := codecs[contentType]
+	return c, ok
+}
+
+func (JSONCodec) ContentType() string {
+	return ContentTypeJSON
+}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// encodePayload return event data of the payload encoded with the codec
+func encodePayload(codec Codec, payload interface{}) (interface{}, error) {
+	b, err := codec.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if codec.ContentType() == ContentTypeJSON {
+		return json.RawMessage(b), nil
+	}
+
+	return b, nil
+}
+
+// decodePayload decode event data encoded with the codec
+func decodePayload(codec Codec, data []byte, v interface{}) error {
+	if codec.ContentType() == ContentTypeJSON {
+		return codec.Unmarshal(data, v)
+	}
+
+	var b []byte
+	if err := json.Unmarshal(data, &b); err != nil {
+		return err
+	}
+
+	return codec.Unmarshal(b, v)
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode %s payload: %s", e.ContentType, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// NonRetryable mark decode error as non retryable
+func (e *DecodeError) NonRetryable() bool {
+	return true
+}
+
+// NonRetryable wrap error so it is not retried by the consume strategies
+func NonRetryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &nonRetryableError{err: err}
+}
+
+func (e *nonRetryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *nonRetryableError) Unwrap() error {
+	return e.err
+}
+
+func (e *nonRetryableError) NonRetryable() bool {
+	return true
+}
+
+// IsNonRetryable return true if the error or any of the wrapped error is marked as non retryable,
+// e.g. DecodeError or error wrapped with NonRetryable
+func IsNonRetryable(err error) bool {
+	var nr interface{ NonRetryable() bool }
+	return errors.As(err, &nr) && nr.NonRetryable()
+}
@@ -0,0 +1,77 @@
+package codec
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/diki-haryadi/govega/event"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeMsgpack  = "application/msgpack"
+)
+
+type (
+	// Protobuf encode payload implementing proto.Message
+	Protobuf struct{}
+
+	// Msgpack encode payload as msgpack
+	Msgpack struct{}
+)
+
+func init() {
+	event.RegisterCodec(Protobuf{})
+	event.RegisterCodec(Msgpack{})
+}
+
+func (Protobuf) ContentType() string {
+	return ContentTypeProtobuf
+}
+
+func (Protobuf) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("[event/codec] %T is not a proto message", v)
+	}
+
+	return proto.Marshal(m)
+}
+
+// Unmarshal decode into proto.Message or pointer to nil proto.Message pointer, e.g. typed handler payload
+func (Protobuf) Unmarshal(data []byte, v interface{}) error {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, m)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Ptr {
+		return fmt.Errorf("[event/codec] %T is not a proto message", v)
+	}
+
+	elem := rv.Elem()
+	if elem.IsNil() {
+		elem.Set(reflect.New(elem.Type().Elem()))
+	}
+
+	m, ok := elem.Interface().(proto.Message)
+	if !ok {
+		return fmt.Errorf("[event/codec] %T is not a proto message", v)
+	}
+
+	return proto.Unmarshal(data, m)
+}
+
+func (Msgpack) ContentType() string {
+	return ContentTypeMsgpack
+}
+
+func (Msgpack) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (Msgpack) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
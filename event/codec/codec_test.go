@@ -0,0 +1,97 @@
+package codec
+
+import (
+	"context"
+	"testing"
+
+	"github.com/diki-haryadi/govega/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type order struct {
+	ID     string `msgpack:"id"`
+	Amount int    `msgpack:"amount"`
+}
+
+type captureSender struct {
+	sent []*event.EventMessage
+}
+
+func (c *captureSender) Send(_ context.Context, message *event.EventMessage) error {
+	c.sent = append(c.sent, message)
+	return nil
+}
+
+// consume convert the published message into consumed message
+func consume(t *testing.T, message *event.EventMessage) *event.EventConsumeMessage {
+	b, err := message.ToBytes()
+	require.NoError(t, err)
+
+	em, err := event.NewEventConsumeMessage(b)
+	require.NoError(t, err)
+	return em
+}
+
+func newTestEmitter(t *testing.T) (*event.Emitter, *captureSender) {
+	sender := &captureSender{}
+	event.RegisterSender("codec-test", func(ctx context.Context, config interface{}) (event.Sender, error) {
+		return sender, nil
+	})
+
+	em, err := event.New(context.Background(), &event.EmitterConfig{
+		Sender: &event.DriverConfig{Type: "codec-test"},
+	})
+	require.NoError(t, err)
+	return em, sender
+}
+
+func TestProtobufCodec(t *testing.T) {
+	em, sender := newTestEmitter(t)
+	ctx := context.Background()
+
+	require.NoError(t, event.PublishTyped(ctx, em, Protobuf{}, "order", "k1", wrapperspb.String("o123"), nil))
+	require.Len(t, sender.sent, 1)
+	assert.Equal(t, ContentTypeProtobuf, sender.sent[0].Metadata[event.MetaContentType])
+
+	var received *wrapperspb.StringValue
+	handler := event.Typed(nil, func(ctx context.Context, message *event.TypedMessage[*wrapperspb.StringValue]) error {
+		received = message.Payload
+		return nil
+	})
+
+	require.NoError(t, handler(ctx, consume(t, sender.sent[0])))
+	require.NotNil(t, received)
+	assert.Equal(t, "o123", received.GetValue())
+
+	err := Protobuf{}.Unmarshal([]byte{}, &order{})
+	assert.Error(t, err)
+	_, err = Protobuf{}.Marshal(order{})
+	assert.Error(t, err)
+}
+
+func TestMsgpackCodec(t *testing.T) {
+	em, sender := newTestEmitter(t)
+	ctx := context.Background()
+
+	require.NoError(t, event.PublishTyped(ctx, em, Msgpack{}, "order", "k1", order{ID: "o123", Amount: 10}, nil))
+	require.Len(t, sender.sent, 1)
+
+	var received order
+	handler := event.Typed(Msgpack{}, func(ctx context.Context, message *event.TypedMessage[order]) error {
+		received = message.Payload
+		assert.Equal(t, "order", message.Event())
+		return nil
+	})
+
+	require.NoError(t, handler(ctx, consume(t, sender.sent[0])))
+	assert.Equal(t, order{ID: "o123", Amount: 10}, received)
+
+	// json payload decoded with msgpack codec
+	err := handler(ctx, &event.EventConsumeMessage{Data: []byte(`{"id":"o123"}`)})
+	var derr *event.DecodeError
+	require.ErrorAs(t, err, &derr)
+	assert.Equal(t, ContentTypeMsgpack, derr.ContentType)
+	assert.True(t, event.IsNonRetryable(err))
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -30,6 +31,8 @@ const (
 
 var (
 	ErrConsumerStarted = errors.New("Consumer already started")
+	// ErrCommitFailed wrapped by consume strategy when message is handled but failed to be committed
+	ErrCommitFailed = errors.New("failed to commit")
 
 	errConfigNotFound = errors.New("config not found")
 
@@ -48,6 +51,12 @@ type (
 		Commit(ctx context.Context) error
 	}
 
+	// Nacker message which can be negatively acknowledged, the consumer nack the message
+	// when the consume strategy fails so it is redelivered without waiting for the ack deadline
+	Nacker interface {
+		Nack(ctx context.Context) error
+	}
+
 	Iterator interface {
 		Next(ctx context.Context) (ConsumeMessage, error)
 	}
@@ -58,6 +67,16 @@ type (
 
 	WorkerPoolConfig map[string]interface{}
 
+	// WorkerPoolOption worker pool option for specific topic group
+	WorkerPoolOption struct {
+		// Workers number of workers
+		Workers int `json:"workers" mapstructure:"workers"`
+		// Keyed dispatch messages with the same key to the same worker to keep them in order,
+		// messages are committed in the order they are received. Commit is deferred so its failure doesn't reach
+		// the consume strategy (no ErrCommitFailed), it is logged and reported by the health handler
+		Keyed bool `json:"keyed" mapstructure:"keyed"`
+	}
+
 	Consumer struct {
 		listener         Listener
 		listenerPools    []ListenerWorkerPool
@@ -65,6 +84,8 @@ type (
 		eventConfig      *EventConfig
 		workerPoolConfig *WorkerPoolConfig
 		consumeStrategy  ConsumeStrategy
+		batchStrategy    BatchConsumeStrategy
+		payload          *PayloadTransformer
 		running          uint32
 		lock             sync.Mutex
 		stopch           chan bool
@@ -76,6 +97,8 @@ type (
 		EventConfig      *EventConfig      `json:"event_config" mapstructure:"event_config"`
 		WorkerPoolConfig *WorkerPoolConfig `json:"worker_pool_config" mapstructure:"worker_pool_config"`
 		ConsumeStrategy  *DriverConfig     `json:"consume_strategy" mapstructure:"consume_strategy"`
+		// Payload decrypt payload encrypted by the emitter, compressed payload is always decompressed
+		Payload *PayloadConfig `json:"payload" mapstructure:"payload"`
 	}
 
 	EventConsumeMessage struct {
@@ -100,12 +123,17 @@ type (
 	ctxKey int
 )
 
-// NewEventConsumeMessage return event consume message from byte data
+// NewEventConsumeMessage return event consume message from byte data,
+// data could be the default encoding or structured mode cloudevents
 func NewEventConsumeMessage(v []byte) (*EventConsumeMessage, error) {
 	if v == nil {
 		return &EventConsumeMessage{}, nil
 	}
 
+	if em, ok, err := parseCloudEventsStructured(v); ok {
+		return em, err
+	}
+
 	var readmsg eventConsumeMessageRead
 	if err := json.Unmarshal(v, &readmsg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal value: %w", err)
@@ -134,32 +162,64 @@ func (fn IteratorFunc) Next(ctx context.Context) (ConsumeMessage, error) {
 }
 
 func (c WorkerPoolConfig) getWorkers(topic, group string) int {
+	return c.getOption(topic, group).Workers
+}
+
+func (c WorkerPoolConfig) getOption(topic, group string) WorkerPoolOption {
 	config, err := c.parseTopicConfig(topic)
 	if err != nil {
 		if !errors.Is(err, errConfigNotFound) {
 			log.WithError(err).Warnln("failed to parse topic config, fallback to default")
 		}
 
-		return c.getDefaultWorkers(topic, group)
+		return WorkerPoolOption{Workers: c.getDefaultWorkers(topic, group)}
 	}
 
-	if val, ok := config[group]; ok && val > 0 {
-		return val
+	groupOpt, hasGroup := config[group]
+	defaultOpt := config[MetaDefault]
+
+	opt := defaultOpt
+	if hasGroup {
+		opt = groupOpt
 	}
 
-	if val, ok := config[MetaDefault]; ok && val > 0 {
-		return val
+	switch {
+	case groupOpt.Workers > 0:
+		opt.Workers = groupOpt.Workers
+	case defaultOpt.Workers > 0:
+		opt.Workers = defaultOpt.Workers
+	default:
+		opt.Workers = c.getDefaultWorkers(topic, group)
 	}
 
-	return c.getDefaultWorkers(topic, group)
+	return opt
 }
 
-func (c WorkerPoolConfig) parseTopicConfig(topic string) (map[string]int, error) {
+func (c WorkerPoolConfig) parseTopicConfig(topic string) (map[string]WorkerPoolOption, error) {
 	if val, ok := c[topic]; ok && val != nil {
-		config := map[string]int{}
+		raw := map[string]interface{}{}
+		if err := mapstructure.Decode(val, &raw); err != nil {
+			return map[string]WorkerPoolOption{}, fmt.Errorf("failed to decode config: %w", err)
+		}
+
+		config := make(map[string]WorkerPoolOption, len(raw))
+		for name, v := range raw {
+			var opt WorkerPoolOption
+
+			// value can be either number of workers or worker pool option
+			var err error
+			switch reflect.Indirect(reflect.ValueOf(v)).Kind() {
+			case reflect.Map, reflect.Struct:
+				err = mapstructure.Decode(v, &opt)
+			default:
+				err = mapstructure.Decode(v, &opt.Workers)
+			}
+
+			if err != nil {
+				return map[string]WorkerPoolOption{}, fmt.Errorf("failed to decode config: %w", err)
+			}
 
-		if err := mapstructure.Decode(val, &config); err != nil {
-			return map[string]int{}, fmt.Errorf("failed to decode config: %w", err)
+			config[name] = opt
 		}
 
 		return config, nil
@@ -189,6 +249,7 @@ func NewConsumer(ctx context.Context, config *ConsumerConfig) (*Consumer, error)
 		eventConfig:      &EventConfig{},
 		workerPoolConfig: &WorkerPoolConfig{},
 		consumeStrategy:  CommitOnSuccessStrategy,
+		batchStrategy:    CommitBatchOnSuccessStrategy,
 		running:          stop,
 		lock:             sync.Mutex{},
 		stopch:           make(chan bool, 2),
@@ -221,6 +282,12 @@ func NewConsumer(ctx context.Context, config *ConsumerConfig) (*Consumer, error)
 		}
 	}
 
+	payload, err := NewPayloadTransformer(config.Payload)
+	if err != nil {
+		return nil, err
+	}
+	consumer.payload = payload
+
 	if config.Listener == nil {
 		return nil, errors.New("[event/consumer] missing listener driver config")
 	}
@@ -241,6 +308,12 @@ func NewConsumer(ctx context.Context, config *ConsumerConfig) (*Consumer, error)
 	return consumer, nil
 }
 
+// WithPayloadTransformer decode consumed payload with the transformer, e.g. with custom encryptor.
+// Please set it before calling subscribe
+func (c *Consumer) WithPayloadTransformer(payload *PayloadTransformer) {
+	c.payload = payload
+}
+
 // Use add middlewares to actual event handler before accessing the actual handler
 // Please add your middlewares before calling subscribe or it may not work properly
 func (c *Consumer) Use(middlewares ...EventMiddleware) {
@@ -273,14 +346,22 @@ func (c *Consumer) Subscribe(ctx context.Context, topic, group string,
 		}
 	}
 
+	// decode payload before the middlewares
+	handler = c.payload.Middleware(handler)
+
+	option := c.workerPoolConfig.getOption(topic, group)
+
 	c.listenerPools = append(c.listenerPools, ListenerWorkerPool{
-		workers:         c.workerPoolConfig.getWorkers(topic, group),
+		workers:         option.Workers,
+		keyed:           option.Keyed,
 		iterator:        iterator,
 		handler:         handler,
 		consumeStrategy: c.consumeStrategy,
 		topic:           topic,
 		group:           group,
 		tracer:          otel.Tracer("event/consumer"),
+		state:           newPoolState(topic, group),
+		control:         newPoolControl(option.Workers, !option.Keyed),
 	})
 
 	return nil
@@ -313,9 +394,11 @@ func (c *Consumer) Start() error {
 	for i := range c.listenerPools {
 		//NOTE: https://github.com/golang/go/wiki/CommonMistakes#using-goroutines-on-loop-iterator-variables
 		pool := c.listenerPools[i]
+		pool.workers = pool.control.getWorkers()
 
 		//must be assign here, else race will be detected
 		wg.Add(pool.workers)
+		pool.state.setAlive(true)
 		go pool.run(c.stopch, &wg)
 	}
 
@@ -377,17 +460,41 @@ func (c *Consumer) isRunning() bool {
 
 type ListenerWorkerPool struct {
 	workers         int
+	keyed           bool
 	iterator        Iterator
 	handler         EventHandler
 	topic           string
 	group           string
 	consumeStrategy ConsumeStrategy
 	tracer          trace.Tracer
+	state           *poolState
+	control         *poolControl
+
+	batchHandler  BatchEventHandler
+	batchSize     int
+	batchMaxWait  time.Duration
+	batchStrategy BatchConsumeStrategy
 }
 
 func (k *ListenerWorkerPool) run(stop <-chan bool, wg *sync.WaitGroup) {
+	defer k.state.setAlive(false)
+
+	if k.batchHandler != nil {
+		k.runBatch(stop, wg)
+		return
+	}
+
+	if k.keyed {
+		k.runKeyed(stop, wg)
+		return
+	}
+
+	workers := sync.WaitGroup{}
+
 	if closer, ok := k.iterator.(Closer); ok {
 		defer func(closer Closer) {
+			// close iterator only after workers complete, they may still be fetching
+			workers.Wait()
 			if err := closer.Close(); err != nil {
 				log.WithError(err).
 					Errorln("[listener/workerpool] failed to close iterator")
@@ -396,9 +503,14 @@ func (k *ListenerWorkerPool) run(stop <-chan bool, wg *sync.WaitGroup) {
 	}
 
 	jobs := make(chan Job, k.workers)
+	quits := make([]chan bool, 0, k.workers)
 
+	// workers of the initial count are already added to the wait group on start
 	for i := 0; i < k.workers; i++ {
-		go worker(jobs, stop, wg)
+		quit := make(chan bool)
+		quits = append(quits, quit)
+		workers.Add(1)
+		go worker(jobs, stop, quit, wg, &workers)
 	}
 
 	for {
@@ -406,21 +518,65 @@ func (k *ListenerWorkerPool) run(stop <-chan bool, wg *sync.WaitGroup) {
 		case <-stop:
 			close(jobs)
 			return
-		default:
-			jobs <- k.retrieveMessage
+		case <-k.control.resized:
+			select {
+			case <-stop:
+				close(jobs)
+				return
+			default:
+			}
+
+			quits = k.resize(jobs, stop, quits, wg, &workers)
+		case jobs <- k.retrieveMessage:
 		}
 	}
 
 }
 
+// resize start or quit workers to match the worker count of the control
+func (k *ListenerWorkerPool) resize(jobs chan Job, stop <-chan bool, quits []chan bool,
+	wg, running *sync.WaitGroup) []chan bool {
+	workers := k.control.getWorkers()
+
+	for len(quits) < workers {
+		quit := make(chan bool)
+		quits = append(quits, quit)
+		wg.Add(1)
+		running.Add(1)
+		go worker(jobs, stop, quit, wg, running)
+	}
+
+	for len(quits) > workers {
+		close(quits[len(quits)-1])
+		quits = quits[:len(quits)-1]
+	}
+
+	log.WithFields(log.Fields{"topic": k.topic, "group": k.group, "workers": workers}).
+		Infoln("[listener/workerpool] worker pool resized")
+
+	return quits
+}
+
 func (k *ListenerWorkerPool) retrieveMessage(ctx context.Context) error {
 	ctx = context.WithValue(ctx, consumerGroupKey, k.group)
 
+	if err := k.control.wait(ctx); err != nil {
+		return fmt.Errorf("failed to wait worker pool control: %w", err)
+	}
+
 	message, err := k.iterator.Next(ctx)
+	if ctx.Err() == nil {
+		k.state.fetched(err)
+	}
+
 	if err != nil {
 		return fmt.Errorf("failed to get next item: %w", err)
 	}
 
+	return k.consumeMessage(ctx, message)
+}
+
+func (k *ListenerWorkerPool) consumeMessage(ctx context.Context, message ConsumeMessage) error {
 	if carrier, ok := message.(propagation.TextMapCarrier); ok {
 		ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
 	}
@@ -430,30 +586,47 @@ func (k *ListenerWorkerPool) retrieveMessage(ctx context.Context) error {
 	defer span.End()
 
 	start := time.Now()
+	k.state.begin(1)
+
+	err := k.consumeStrategy(ctx, message, k.handler)
+	k.state.done(1, err)
+	monitor.FeedConsumerMetrics(k.topic, k.group, getMetricStatusFromError(err),
+		time.Since(start))
 
-	err = k.consumeStrategy(ctx, message, k.handler)
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
 
+		if nacker, ok := message.(Nacker); ok && !errors.Is(err, ErrCommitFailed) && !IsNonRetryable(err) {
+			if nerr := nacker.Nack(ctx); nerr != nil {
+				log.WithContext(ctx).WithError(nerr).
+					Errorln("[listener/worker] failed to nack message")
+			}
+		}
+
 		return fmt.Errorf("failed to consume message topic [%s] group [%s]: %w",
 			k.topic, k.group, err)
 	}
 
-	monitor.FeedConsumerMetrics(k.topic, k.group, getMetricStatusFromError(err),
-		time.Since(start))
-
 	return nil
 }
 
-func worker(jobs <-chan Job, stop <-chan bool, wg *sync.WaitGroup) {
+// worker process jobs until stop, or until quit when the pool is scaled down
+// in which case the running job is completed
+func worker(jobs <-chan Job, stop, quit <-chan bool, wg, workers *sync.WaitGroup) {
 	defer wg.Done()
+	defer workers.Done()
 
 	for {
 		select {
 		case <-stop:
 			log.Println("[listener/worker] stop processing job")
 			return
-		case j := <-jobs:
+		case <-quit:
+			return
+		case j, ok := <-jobs:
+			if !ok {
+				return
+			}
 
 			ctx, cancel := context.WithCancel(context.Background())
 			resultCh := make(chan error, 1)
@@ -2,7 +2,9 @@ package event
 
 import (
 	"context"
+	"errors"
 	"math/rand"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -249,3 +251,183 @@ func TestConsumerForceStop(t *testing.T) {
 	assert.ErrorIs(t, err, context.DeadlineExceeded)
 	assert.Equal(t, totalPools, atomic.LoadInt32(&pools))
 }
+
+func TestWorkerPoolConfigOption(t *testing.T) {
+	conf := WorkerPoolConfig{
+		"default": 2,
+		"topic1": map[string]interface{}{
+			"default": 3,
+			"group1":  4,
+			"group2": map[string]interface{}{
+				"keyed": true,
+			},
+		},
+		"topic2": map[string]interface{}{
+			"default": WorkerPoolOption{Workers: 5, Keyed: true},
+			"group1":  map[string]interface{}{"workers": 6, "keyed": false},
+		},
+	}
+
+	tests := []struct {
+		topic, group string
+		expected     WorkerPoolOption
+	}{
+		{"unknown", "group1", WorkerPoolOption{Workers: 2}},
+		{"topic1", "unknown", WorkerPoolOption{Workers: 3}},
+		{"topic1", "group1", WorkerPoolOption{Workers: 4}},
+		{"topic1", "group2", WorkerPoolOption{Workers: 3, Keyed: true}},
+		{"topic2", "unknown", WorkerPoolOption{Workers: 5, Keyed: true}},
+		{"topic2", "group1", WorkerPoolOption{Workers: 6}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, conf.getOption(tt.topic, tt.group), "%s/%s", tt.topic, tt.group)
+		assert.Equal(t, tt.expected.Workers, conf.getWorkers(tt.topic, tt.group), "%s/%s", tt.topic, tt.group)
+	}
+}
+
+type orderedConsumeMessage struct {
+	em      *EventConsumeMessage
+	seq     int
+	commits chan<- int
+}
+
+func (o *orderedConsumeMessage) GetEventConsumeMessage(ctx context.Context) (*EventConsumeMessage, error) {
+	return o.em, nil
+}
+
+func (o *orderedConsumeMessage) Commit(ctx context.Context) error {
+	o.commits <- o.seq
+	return nil
+}
+
+func TestConsumerKeyedDispatch(t *testing.T) {
+	conf := &ConsumerConfig{
+		Listener: &DriverConfig{Type: "TestConsumerKeyedDispatch"},
+		WorkerPoolConfig: &WorkerPoolConfig{
+			"test": map[string]interface{}{
+				"default": map[string]interface{}{"workers": 4, "keyed": true},
+			},
+		},
+	}
+
+	testListener := newTestListener()
+	RegisterListener("TestConsumerKeyedDispatch", testListener.factory)
+
+	ctx := context.Background()
+
+	consumer, err := NewConsumer(ctx, conf)
+	require.NoError(t, err)
+
+	const total = 100
+	keys := []string{"a", "b", "c", "d", "e"}
+
+	var lock sync.Mutex
+	processed := map[string][]int{}
+
+	err = consumer.Subscribe(ctx, "test", "test", func(_ context.Context, msg *EventConsumeMessage) error {
+		time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+
+		lock.Lock()
+		defer lock.Unlock()
+		processed[msg.Key] = append(processed[msg.Key], int(msg.Data[0]))
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, consumer.Start())
+
+	commits := make(chan int, total)
+	go func() {
+		for i := 0; i < total; i++ {
+			testListener.ch <- &orderedConsumeMessage{
+				em: &EventConsumeMessage{
+					Key:  keys[i%len(keys)],
+					Data: []byte{byte(i)},
+				},
+				seq:     i,
+				commits: commits,
+			}
+		}
+	}()
+
+	for i := 0; i < total; i++ {
+		select {
+		case seq := <-commits:
+			require.Equal(t, i, seq, "message should be committed in order")
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting message %d to be committed", i)
+		}
+	}
+
+	require.NoError(t, consumer.Stop())
+
+	lock.Lock()
+	defer lock.Unlock()
+	for _, key := range keys {
+		seqs := processed[key]
+		require.Len(t, seqs, total/len(keys))
+		for i := 1; i < len(seqs); i++ {
+			assert.Less(t, seqs[i-1], seqs[i], "message with key %s should be processed in order", key)
+		}
+	}
+}
+
+type nackConsumeMessage struct {
+	*testConsumeMessage
+	nacked chan string
+}
+
+func (n *nackConsumeMessage) Nack(ctx context.Context) error {
+	n.nacked <- n.em.Key
+	return nil
+}
+
+func TestConsumerKeyedNack(t *testing.T) {
+	conf := &ConsumerConfig{
+		Listener: &DriverConfig{Type: "TestConsumerKeyedNack"},
+		WorkerPoolConfig: &WorkerPoolConfig{
+			"test": map[string]interface{}{
+				"default": map[string]interface{}{"workers": 2, "keyed": true},
+			},
+		},
+	}
+
+	testListener := newTestListener()
+	RegisterListener("TestConsumerKeyedNack", testListener.factory)
+
+	ctx := context.Background()
+
+	consumer, err := NewConsumer(ctx, conf)
+	require.NoError(t, err)
+
+	err = consumer.Subscribe(ctx, "test", "test", func(_ context.Context, msg *EventConsumeMessage) error {
+		if msg.Key == "fail" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, consumer.Start())
+	defer consumer.Stop()
+
+	nacked := make(chan string, 2)
+	for _, key := range []string{"ok", "fail"} {
+		testListener.sendMessage(&nackConsumeMessage{
+			testConsumeMessage: newTestConsumeMessage(&EventConsumeMessage{Key: key}),
+			nacked:             nacked,
+		})
+	}
+
+	select {
+	case key := <-nacked:
+		assert.Equal(t, "fail", key, "only failed message is nacked")
+	case <-time.After(time.Second):
+		t.Fatal("failed message of keyed pool should be nacked")
+	}
+
+	select {
+	case key := <-nacked:
+		t.Fatalf("unexpected nack of message %s", key)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
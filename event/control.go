@@ -0,0 +1,226 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+
+	"github.com/diki-haryadi/govega/log"
+	"golang.org/x/time/rate"
+)
+
+var (
+	ErrPoolNotFound     = errors.New("Worker pool not found")
+	ErrScaleUnsupported = errors.New("Worker pool doesn't support scaling")
+)
+
+type (
+	// PoolStatus runtime control status of a single listener worker pool
+	PoolStatus struct {
+		Topic  string `json:"topic"`
+		Group  string `json:"group"`
+		Paused bool   `json:"paused"`
+		// RateLimit maximum messages per second, 0 means unlimited
+		RateLimit float64 `json:"rate_limit"`
+		Workers   int     `json:"workers"`
+		// Scalable the worker count can be changed at runtime
+		Scalable bool `json:"scalable"`
+	}
+
+	// poolControl runtime control shared by a worker pool and the consumer,
+	// workers wait on the control before fetching each message
+	poolControl struct {
+		lock     sync.Mutex
+		paused   bool
+		resume   chan struct{}
+		limiter  *rate.Limiter
+		workers  int
+		scalable bool
+		resized  chan struct{}
+	}
+)
+
+func newPoolControl(workers int, scalable bool) *poolControl {
+	return &poolControl{
+		limiter:  rate.NewLimiter(rate.Inf, 1),
+		workers:  workers,
+		scalable: scalable,
+		resized:  make(chan struct{}, 1),
+	}
+}
+
+// wait block while the pool is paused and until the rate limit allow next message
+func (c *poolControl) wait(ctx context.Context) error {
+	c.lock.Lock()
+	paused, resume := c.paused, c.resume
+	c.lock.Unlock()
+
+	if paused {
+		select {
+		case <-resume:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return c.limiter.Wait(ctx)
+}
+
+func (c *poolControl) pause() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.paused {
+		return
+	}
+
+	c.paused = true
+	c.resume = make(chan struct{})
+}
+
+func (c *poolControl) unpause() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.paused {
+		return
+	}
+
+	c.paused = false
+	close(c.resume)
+}
+
+func (c *poolControl) setRateLimit(limit float64) {
+	if limit <= 0 {
+		c.limiter.SetLimit(rate.Inf)
+		return
+	}
+
+	// allow burst of a second worth of messages so the rate is reachable by concurrent workers
+	c.limiter.SetBurst(int(math.Max(1, math.Ceil(limit))))
+	c.limiter.SetLimit(rate.Limit(limit))
+}
+
+func (c *poolControl) getWorkers() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.workers
+}
+
+func (c *poolControl) scale(workers int) error {
+	if !c.scalable {
+		return ErrScaleUnsupported
+	}
+
+	c.lock.Lock()
+	c.workers = workers
+	c.lock.Unlock()
+
+	// notify running pool, pending notification is enough as the pool read the latest count
+	select {
+	case c.resized <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (c *poolControl) status(topic, group string) PoolStatus {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	s := PoolStatus{
+		Topic:    topic,
+		Group:    group,
+		Paused:   c.paused,
+		Workers:  c.workers,
+		Scalable: c.scalable,
+	}
+
+	if limit := c.limiter.Limit(); limit != rate.Inf {
+		s.RateLimit = float64(limit)
+	}
+
+	return s
+}
+
+// Pause stop fetching new messages of the topic group subscription, messages being processed are completed.
+// Topic and group are mapped with the event config the same way as subscribe
+func (c *Consumer) Pause(topic, group string) error {
+	return c.control(topic, group, func(pc *poolControl) error {
+		pc.pause()
+		return nil
+	})
+}
+
+// Resume continue fetching messages of the paused topic group subscription
+func (c *Consumer) Resume(topic, group string) error {
+	return c.control(topic, group, func(pc *poolControl) error {
+		pc.unpause()
+		return nil
+	})
+}
+
+// SetRateLimit throttle the topic group subscription to limit messages per second,
+// limit 0 remove the throttling. Batch subscription count each message of the batch
+func (c *Consumer) SetRateLimit(topic, group string, limit float64) error {
+	if limit < 0 {
+		return errors.New("[event/consumer] rate limit should not be negative")
+	}
+
+	return c.control(topic, group, func(pc *poolControl) error {
+		pc.setRateLimit(limit)
+		return nil
+	})
+}
+
+// Scale change number of workers of the topic group subscription, running workers are stopped
+// after completing their current message. Keyed and batch subscriptions can't be scaled
+// as the number of workers determine the message ordering
+func (c *Consumer) Scale(topic, group string, workers int) error {
+	if workers <= 0 {
+		return errors.New("[event/consumer] workers should be greater than 0")
+	}
+
+	return c.control(topic, group, func(pc *poolControl) error {
+		return pc.scale(workers)
+	})
+}
+
+// Pools return control status of each subscribed worker pool
+func (c *Consumer) Pools() []PoolStatus {
+	pools := make([]PoolStatus, 0, len(c.listenerPools))
+	for _, pool := range c.listenerPools {
+		pools = append(pools, pool.control.status(pool.topic, pool.group))
+	}
+
+	return pools
+}
+
+// control apply fn to all worker pools of the topic group
+func (c *Consumer) control(topic, group string, fn func(pc *poolControl) error) error {
+	topic = c.eventConfig.getTopic(topic)
+	group = c.eventConfig.getGroup(group)
+
+	found := false
+	for _, pool := range c.listenerPools {
+		if pool.topic != topic || pool.group != group {
+			continue
+		}
+
+		found = true
+		if err := fn(pool.control); err != nil {
+			return err
+		}
+	}
+
+	if !found {
+		return ErrPoolNotFound
+	}
+
+	log.WithFields(log.Fields{"topic": topic, "group": group}).
+		Infoln("[event/consumer] worker pool control updated")
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/diki-haryadi/govega/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newControlTestConsumer(t *testing.T, name string, listener *testListener) *Consumer {
+	RegisterListener(name, listener.factory)
+
+	consumer, err := NewConsumer(context.Background(), &ConsumerConfig{
+		Listener: &DriverConfig{Type: name},
+		WorkerPoolConfig: &WorkerPoolConfig{
+			"keyed": map[string]interface{}{
+				MetaDefault: map[string]interface{}{"workers": 2, "keyed": true},
+			},
+		},
+	})
+	require.NoError(t, err)
+	return consumer
+}
+
+func TestConsumerPauseResume(t *testing.T) {
+	ctx := context.Background()
+	listener := newTestListener()
+	consumer := newControlTestConsumer(t, "TestConsumerPauseResume", listener)
+
+	var handled int32
+	require.NoError(t, consumer.Subscribe(ctx, "test", "test", func(ctx context.Context, message *EventConsumeMessage) error {
+		atomic.AddInt32(&handled, 1)
+		return nil
+	}))
+
+	assert.ErrorIs(t, consumer.Pause("test", "other"), ErrPoolNotFound)
+	require.NoError(t, consumer.Pause("test", "test"))
+	assert.True(t, consumer.Pools()[0].Paused)
+
+	require.NoError(t, consumer.Start())
+	defer consumer.Stop()
+
+	listener.sendMessage(newTestConsumeMessage(&EventConsumeMessage{}))
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&handled), "paused pool doesn't fetch message")
+
+	require.NoError(t, consumer.Resume("test", "test"))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&handled) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.False(t, consumer.Pools()[0].Paused)
+}
+
+func TestConsumerRateLimit(t *testing.T) {
+	ctx := context.Background()
+	listener := newTestListener()
+	listener.customIterator = IteratorFunc(func(ctx context.Context) (ConsumeMessage, error) {
+		return newTestConsumeMessage(&EventConsumeMessage{}), nil
+	})
+	consumer := newControlTestConsumer(t, "TestConsumerRateLimit", listener)
+
+	var handled int32
+	require.NoError(t, consumer.Subscribe(ctx, "test", "test", func(ctx context.Context, message *EventConsumeMessage) error {
+		atomic.AddInt32(&handled, 1)
+		return nil
+	}))
+
+	assert.Error(t, consumer.SetRateLimit("test", "test", -1))
+	require.NoError(t, consumer.SetRateLimit("test", "test", 10))
+	assert.Equal(t, float64(10), consumer.Pools()[0].RateLimit)
+
+	require.NoError(t, consumer.Start())
+	time.Sleep(300 * time.Millisecond)
+	require.NoError(t, consumer.Stop())
+
+	// burst of a second worth of messages plus the rate during the test
+	count := atomic.LoadInt32(&handled)
+	assert.Greater(t, count, int32(0))
+	assert.LessOrEqual(t, count, int32(15))
+
+	require.NoError(t, consumer.SetRateLimit("test", "test", 0))
+	assert.Equal(t, float64(0), consumer.Pools()[0].RateLimit)
+}
+
+func TestConsumerScale(t *testing.T) {
+	ctx := context.Background()
+	listener := newTestListener()
+	listener.customIterator = IteratorFunc(func(ctx context.Context) (ConsumeMessage, error) {
+		return newTestConsumeMessage(&EventConsumeMessage{}), nil
+	})
+	consumer := newControlTestConsumer(t, "TestConsumerScale", listener)
+
+	release := make(chan struct{})
+	require.NoError(t, consumer.Subscribe(ctx, "test", "test", func(ctx context.Context, message *EventConsumeMessage) error {
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil
+	}))
+	require.NoError(t, consumer.Subscribe(ctx, "keyed", "test", func(ctx context.Context, message *EventConsumeMessage) error {
+		return nil
+	}))
+
+	assert.Error(t, consumer.Scale("test", "test", 0))
+	assert.ErrorIs(t, consumer.Scale("keyed", "test", 3), ErrScaleUnsupported)
+
+	inflight := func() int64 {
+		return consumer.Health(0).Pools[0].Inflight
+	}
+
+	require.NoError(t, consumer.Start())
+
+	assert.Eventually(t, func() bool { return inflight() == 1 }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, consumer.Scale("test", "test", 3))
+	assert.Equal(t, 3, consumer.Pools()[0].Workers)
+	assert.Eventually(t, func() bool { return inflight() == 3 }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, consumer.Scale("test", "test", 1))
+	close(release)
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, consumer.Stop())
+
+	// restart with the scaled worker count
+	require.NoError(t, consumer.Scale("test", "test", 2))
+	require.NoError(t, consumer.Start())
+	require.NoError(t, consumer.Stop())
+	assert.Equal(t, 2, consumer.Pools()[0].Workers)
+}
+
+func TestConsumerAdminHandler(t *testing.T) {
+	ctx := context.Background()
+	listener := newTestListener()
+	consumer := newControlTestConsumer(t, "TestConsumerAdminHandler", listener)
+
+	require.NoError(t, consumer.Subscribe(ctx, "test", "test", func(ctx context.Context, message *EventConsumeMessage) error {
+		return nil
+	}))
+
+	r := router.New(&router.Options{Timeout: 5})
+	r.Group("/admin/consumer", consumer.AdminHandler)
+
+	call := func(method, path, body string) (int, []PoolStatus) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+
+		var resp struct {
+			Data []PoolStatus `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		return rec.Code, resp.Data
+	}
+
+	code, pools := call(http.MethodGet, "/admin/consumer/pools", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []PoolStatus{{Topic: "test", Group: "test", Workers: 1, Scalable: true}}, pools)
+
+	code, pools = call(http.MethodPost, "/admin/consumer/pools/test/test/pause", "")
+	assert.Equal(t, http.StatusOK, code)
+	require.Len(t, pools, 1)
+	assert.True(t, pools[0].Paused)
+
+	code, pools = call(http.MethodPut, "/admin/consumer/pools/test/test/rate_limit", `{"limit": 5}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(5), pools[0].RateLimit)
+
+	code, pools = call(http.MethodPut, "/admin/consumer/pools/test/test/workers", `{"workers": 4}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 4, pools[0].Workers)
+
+	code, _ = call(http.MethodPut, "/admin/consumer/pools/test/test/workers", `{"workers": 0}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = call(http.MethodPost, "/admin/consumer/pools/test/unknown/resume", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, pools = call(http.MethodPost, "/admin/consumer/pools/test/test/resume", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, pools[0].Paused)
+}
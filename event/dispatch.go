@@ -0,0 +1,268 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/diki-haryadi/govega/log"
+)
+
+// keyedQueueSize number of messages buffered for each keyed worker
+const keyedQueueSize = 16
+
+type (
+	// commitTracker keep track of messages in the order they are received
+	// and only commit a message once all messages received before it are settled,
+	// so committing an offset never skip a message which is still being processed
+	commitTracker struct {
+		lock       sync.Mutex
+		commitLock sync.Mutex
+		pending    []*trackedMessage
+		inflight   chan struct{}
+		state      *poolState
+	}
+
+	trackedMessage struct {
+		ConsumeMessage
+		tracker *commitTracker
+		once    sync.Once
+		em      *EventConsumeMessage
+		err     error
+		settled bool
+		commit  bool
+	}
+)
+
+func newCommitTracker(maxInflight int, state *poolState) *commitTracker {
+	return &commitTracker{
+		pending:  make([]*trackedMessage, 0, maxInflight),
+		inflight: make(chan struct{}, maxInflight),
+		state:    state,
+	}
+}
+
+// acquire wait until there is a room for new message, return false if context is done
+func (t *commitTracker) acquire(ctx context.Context) bool {
+	select {
+	case t.inflight <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (t *commitTracker) release() {
+	<-t.inflight
+}
+
+func (t *commitTracker) track(message ConsumeMessage) *trackedMessage {
+	tm := &trackedMessage{
+		ConsumeMessage: message,
+		tracker:        t,
+	}
+
+	t.lock.Lock()
+	t.pending = append(t.pending, tm)
+	t.lock.Unlock()
+
+	return tm
+}
+
+// settle mark message as processed and commit all settled messages in front of the queue
+func (t *commitTracker) settle(ctx context.Context, tm *trackedMessage) {
+	// commit lock make sure messages are committed in the order they are received
+	// even when settled from different workers
+	t.commitLock.Lock()
+	defer t.commitLock.Unlock()
+
+	t.lock.Lock()
+	tm.settled = true
+
+	ready := make([]*trackedMessage, 0)
+	i := 0
+	for ; i < len(t.pending) && t.pending[i].settled; i++ {
+		if t.pending[i].commit {
+			ready = append(ready, t.pending[i])
+		}
+	}
+	t.pending = t.pending[i:]
+	t.lock.Unlock()
+
+	for j := 0; j < i; j++ {
+		t.release()
+	}
+
+	for _, m := range ready {
+		if err := m.ConsumeMessage.Commit(ctx); err != nil {
+			// message identity is kept in the health state since the error can't be returned to the strategy
+			if em, eerr := m.GetEventConsumeMessage(ctx); eerr == nil {
+				err = fmt.Errorf("%w: topic %s key %s", err, em.Topic, em.Key)
+			}
+			t.state.commitFailed(err)
+			log.WithContext(ctx).WithError(err).
+				Errorln("[listener/keyed] failed to commit message")
+		}
+	}
+}
+
+// GetEventConsumeMessage return the event message, parsed only once
+// since it is needed for dispatching and by the consume strategy
+func (tm *trackedMessage) GetEventConsumeMessage(ctx context.Context) (*EventConsumeMessage, error) {
+	tm.once.Do(func() {
+		tm.em, tm.err = tm.ConsumeMessage.GetEventConsumeMessage(ctx)
+	})
+
+	return tm.em, tm.err
+}
+
+// Commit mark the message to be committed once all previous messages are settled, it always succeed.
+// The actual commit failure is only logged and recorded in the pool health state
+func (tm *trackedMessage) Commit(_ context.Context) error {
+	tm.tracker.lock.Lock()
+	tm.commit = true
+	tm.tracker.lock.Unlock()
+
+	return nil
+}
+
+// Nack negatively acknowledge the underlying message if supported
+func (tm *trackedMessage) Nack(ctx context.Context) error {
+	if nacker, ok := tm.ConsumeMessage.(Nacker); ok {
+		return nacker.Nack(ctx)
+	}
+	return nil
+}
+
+// Get retrieves a single value for a given key from the underlying message carrier if any.
+func (tm *trackedMessage) Get(key string) string {
+	if carrier, ok := tm.ConsumeMessage.(interface{ Get(string) string }); ok {
+		return carrier.Get(key)
+	}
+	return ""
+}
+
+// Set sets a value on the underlying message carrier if any.
+func (tm *trackedMessage) Set(key, val string) {
+	if carrier, ok := tm.ConsumeMessage.(interface{ Set(string, string) }); ok {
+		carrier.Set(key, val)
+	}
+}
+
+// Keys returns a slice of all key identifiers in the underlying message carrier if any.
+func (tm *trackedMessage) Keys() []string {
+	if carrier, ok := tm.ConsumeMessage.(interface{ Keys() []string }); ok {
+		return carrier.Keys()
+	}
+	return nil
+}
+
+// runKeyed fetch messages on a single routine and dispatch them to the worker
+// by hashing the message key, so messages with the same key are processed in order
+func (k *ListenerWorkerPool) runKeyed(stop <-chan bool, wg *sync.WaitGroup) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = context.WithValue(ctx, consumerGroupKey, k.group)
+
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	tracker := newCommitTracker(k.workers*keyedQueueSize, k.state)
+	queues := make([]chan *trackedMessage, k.workers)
+	workers := sync.WaitGroup{}
+
+	for i := range queues {
+		queues[i] = make(chan *trackedMessage, keyedQueueSize)
+		workers.Add(1)
+		go k.keyedWorker(ctx, queues[i], tracker, wg, &workers)
+	}
+
+	defer func() {
+		for _, q := range queues {
+			close(q)
+		}
+
+		// close iterator only after workers complete, pending commits need it
+		workers.Wait()
+		if closer, ok := k.iterator.(Closer); ok {
+			if err := closer.Close(); err != nil {
+				log.WithError(err).
+					Errorln("[listener/workerpool] failed to close iterator")
+			}
+		}
+	}()
+
+	var counter uint32
+	for {
+		if !tracker.acquire(ctx) {
+			return
+		}
+
+		if err := k.control.wait(ctx); err != nil {
+			return
+		}
+
+		message, err := k.iterator.Next(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		k.state.fetched(err)
+
+		if err != nil || message == nil {
+			tracker.release()
+			if err != nil {
+				log.WithContext(ctx).WithError(err).
+					Errorln("[listener/keyed] failed to get next item")
+			}
+			continue
+		}
+
+		tm := tracker.track(message)
+
+		select {
+		case queues[k.keyIndex(ctx, tm, &counter)] <- tm:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (k *ListenerWorkerPool) keyIndex(ctx context.Context, tm *trackedMessage, counter *uint32) int {
+	em, err := tm.GetEventConsumeMessage(ctx)
+	if err != nil || em == nil || em.Key == "" {
+		// message without key doesn't need ordering, spread them evenly
+		return int(atomic.AddUint32(counter, 1) % uint32(k.workers))
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(em.Key))
+	return int(h.Sum32() % uint32(k.workers))
+}
+
+func (k *ListenerWorkerPool) keyedWorker(ctx context.Context, queue <-chan *trackedMessage,
+	tracker *commitTracker, wg, workers *sync.WaitGroup) {
+	defer wg.Done()
+	defer workers.Done()
+
+	for tm := range queue {
+		if ctx.Err() != nil {
+			// stopping, leave the rest uncommitted so they are redelivered
+			continue
+		}
+
+		if err := k.consumeMessage(ctx, tm); err != nil {
+			log.WithContext(ctx).WithError(err).
+				Errorln("[listener/worker] failed to complete job")
+		}
+
+		// don't cancel the commit of the message which is already processed
+		tracker.settle(context.WithoutCancel(ctx), tm)
+	}
+}
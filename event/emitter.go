@@ -6,8 +6,12 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"sync"
 	"time"
 
+	"github.com/diki-haryadi/govega/lock"
 	"github.com/diki-haryadi/govega/log"
 	"github.com/sirupsen/logrus"
 )
@@ -18,9 +22,23 @@ const (
 	MetaEvent   = "event"
 	MetaVersion = "version"
 	MetaDefault = "default"
+
+	// OverflowBlock wait until the queue has free space
+	OverflowBlock = "block"
+	// OverflowDrop drop the message, message of hybrid mode is left in the writer
+	OverflowDrop = "drop"
+	// OverflowSpill write the message into the writer to be sent by the outbox relay
+	OverflowSpill = "spill"
 )
 
 var (
+	ErrEmitterClosed = errors.New("[event/emitter] emitter is closed")
+	// ErrEmitterOverflow returned by PublishAsync when the queue is full on drop overflow policy
+	ErrEmitterOverflow = errors.New("[event/emitter] publish queue is full")
+	// ErrMessageSpilled passed to the PublishAsync callback when the message is written into the writer
+	// because the queue is full, it is sent later by the outbox relay
+	ErrMessageSpilled = errors.New("[event/emitter] message is spilled to writer")
+
 	senders = map[string]SenderFactory{
 		"logger": EventLoggerSender,
 	}
@@ -39,6 +57,11 @@ type (
 		Delete(ctx context.Context, message *EventMessage) error
 	}
 
+	// ContextCloser sender or writer closed within the context deadline, preferred over io.Closer on emitter close
+	ContextCloser interface {
+		CloseContext(ctx context.Context) error
+	}
+
 	EventMessage struct {
 		Topic    string                 `json:"-"`
 		Key      string                 `json:"-"`
@@ -61,14 +84,53 @@ type (
 	Emitter struct {
 		sender      Sender
 		writer      Writer
-		channel     chan *EventMessage
+		queue       chan *publishJob
+		overflow    string
 		eventConfig *EventConfig
+		relay       *OutboxRelay
+		scheduler   *Scheduler
+		locker      lock.DLocker
+
+		schemaRegistry SchemaRegistry
+		strictSchema   bool
+		payload        *PayloadTransformer
+
+		// lock guard queue from being closed while publishing
+		lock      sync.RWMutex
+		closed    bool
+		done      chan struct{}
+		closeOnce sync.Once
+		closeErr  error
+	}
+
+	// PublishCallback called once the message of PublishAsync is sent or failed
+	PublishCallback func(message *EventMessage, err error)
+
+	publishJob struct {
+		ctx      context.Context
+		message  *EventMessage
+		written  bool
+		callback PublishCallback
 	}
 
 	EmitterConfig struct {
 		Sender      *DriverConfig `json:"sender" mapstructure:"sender"`
 		Writer      *DriverConfig `json:"writer" mapstructure:"writer"`
 		EventConfig *EventConfig  `json:"event_config" mapstructure:"event_config"`
+		// Relay enable outbox relay for hybrid mode, writer driver should support outbox relay
+		Relay *OutboxRelayConfig `json:"relay" mapstructure:"relay"`
+		// Schema enable payload validation against schema registered for the event version
+		Schema *SchemaConfig `json:"schema" mapstructure:"schema"`
+		// Buffer size of the queue used by hybrid mode and PublishAsync, default: 0 unbuffered
+		Buffer int `json:"buffer" mapstructure:"buffer"`
+		// Overflow policy when the queue is full: block, drop or spill (requires writer), default: block
+		Overflow string `json:"overflow" mapstructure:"overflow"`
+		// Scheduler enable releasing scheduled messages, writer driver should support scheduled message
+		Scheduler *SchedulerConfig `json:"scheduler" mapstructure:"scheduler"`
+		// Payload compress and/or encrypt the message data
+		Payload *PayloadConfig `json:"payload" mapstructure:"payload"`
+		// Fallback spill messages to the fallback writer when the sender is unavailable
+		Fallback *FallbackConfig `json:"fallback" mapstructure:"fallback"`
 	}
 
 	SenderFactory func(ctx context.Context, config interface{}) (Sender, error)
@@ -160,21 +222,12 @@ func New(ctx context.Context, config *EmitterConfig) (*Emitter, error) {
 		return nil, errors.New("[event/emitter] missing config")
 	}
 
-	em := &Emitter{
-		channel:     make(chan *EventMessage),
-		eventConfig: config.EventConfig,
-	}
-
 	if config.Sender == nil {
 		config.Sender = &DriverConfig{Type: "logger"}
 		log.GetLogger(ctx, "event/emitter", "New").Info("empty sender, using logger by default")
 		//return nil, errors.New("[event/emitter] missing sender driver config")
 	}
 
-	if em.eventConfig == nil {
-		em.eventConfig = NewEventConfig()
-	}
-
 	sf, ok := senders[config.Sender.Type]
 	if !ok {
 		return nil, errors.New("[event/emitter] unsupported sender driver")
@@ -184,34 +237,299 @@ func New(ctx context.Context, config *EmitterConfig) (*Emitter, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	return NewWithSender(ctx, sd, config)
+}
+
+// NewWithSender create emitter sending to the sender without looking up the sender driver, config sender is ignored
+func NewWithSender(ctx context.Context, sd Sender, config *EmitterConfig) (*Emitter, error) {
+
+	if config == nil {
+		return nil, errors.New("[event/emitter] missing config")
+	}
+
+	if sd == nil {
+		return nil, errors.New("[event/emitter] missing sender")
+	}
+
+	em := &Emitter{
+		queue:       make(chan *publishJob, config.Buffer),
+		overflow:    config.Overflow,
+		eventConfig: config.EventConfig,
+		done:        make(chan struct{}),
+	}
+
+	switch em.overflow {
+	case "":
+		em.overflow = OverflowBlock
+	case OverflowBlock, OverflowDrop:
+	case OverflowSpill:
+		if config.Writer == nil {
+			return nil, errors.New("[event/emitter] spill overflow policy requires writer driver")
+		}
+	default:
+		return nil, fmt.Errorf("[event/emitter] unsupported overflow policy: %s", em.overflow)
+	}
+
+	if em.eventConfig == nil {
+		em.eventConfig = NewEventConfig()
+	}
+
 	em.sender = sd
 
-	if config.Writer != nil {
+	// release the resources created so far when the emitter fails to be created
+	var cleanup []func() error
+	created := false
+	defer func() {
+		if created {
+			return
+		}
+		for i := len(cleanup) - 1; i >= 0; i-- {
+			if err := cleanup[i](); err != nil {
+				log.WithContext(ctx).WithError(err).Errorln("[event/emitter] failed to release resource")
+			}
+		}
+	}()
 
-		wf, ok := writers[config.Writer.Type]
-		if !ok {
-			return nil, errors.New("[event/emitter] unsupported writer driver")
+	if config.Schema != nil {
+		registry, err := NewSchemaRegistry(ctx, config.Schema.Registry)
+		if err != nil {
+			return nil, err
 		}
 
-		wr, err := wf(ctx, config.Writer.Config)
+		em.WithSchemaRegistry(registry, config.Schema.Strict)
+	}
+
+	if config.Payload != nil {
+		payload, err := NewPayloadTransformer(config.Payload)
+		if err != nil {
+			return nil, err
+		}
+
+		em.WithPayloadTransformer(payload)
+	}
+
+	var fallback *FallbackSender
+	if config.Fallback != nil {
+		if config.Fallback.Writer == nil {
+			return nil, errors.New("[event/emitter] fallback requires writer driver")
+		}
+
+		fw, err := newWriter(ctx, config.Fallback.Writer)
+		if err != nil {
+			return nil, err
+		}
+		cleanup = append(cleanup, func() error { return closeContext(ctx, fw) })
+
+		if fallback, err = NewFallbackSender(sd, fw, config.Fallback); err != nil {
+			return nil, err
+		}
+
+		// relay and scheduler keep using the sender as failed records stay in the writer
+		em.sender = fallback
+	}
+
+	if config.Writer != nil {
+
+		wr, err := newWriter(ctx, config.Writer)
 		if err != nil {
 			return nil, err
 		}
 
 		em.writer = wr
+		cleanup = append(cleanup, func() error { return closeContext(ctx, wr) })
 		log.GetLogger(ctx, "event/emitter", "New").Info("enable hybrid mode")
-		//running hybrid mode
-		//don't use parent context on routine
-		//because it might be canceled from parent routine when they finish
-		//causing whatever logic inside the routine to be canceled right away
-		//when they checking if the context is done
-		go em.worker(context.Background())
+
+		if config.Relay != nil {
+			relay, err := NewOutboxRelay(wr, sd, config.Relay)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := relay.Start(); err != nil {
+				return nil, err
+			}
+			cleanup = append(cleanup, relay.Stop)
+
+			em.relay = relay
+			log.GetLogger(ctx, "event/emitter", "New").Info("enable outbox relay")
+		}
+
+		if config.Scheduler != nil {
+			locker, err := lock.New(config.Scheduler.Lock)
+			if err != nil {
+				return nil, err
+			}
+			cleanup = append(cleanup, locker.Close)
+
+			scheduler, err := NewScheduler(wr, sd, locker, config.Scheduler)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := scheduler.Start(); err != nil {
+				return nil, err
+			}
+			cleanup = append(cleanup, scheduler.Stop)
+
+			em.scheduler = scheduler
+			em.locker = locker
+			log.GetLogger(ctx, "event/emitter", "New").Info("enable scheduler")
+		}
+	} else if config.Relay != nil {
+		return nil, errors.New("[event/emitter] outbox relay requires writer driver")
+	} else if config.Scheduler != nil {
+		return nil, errors.New("[event/emitter] scheduler requires writer driver")
 	}
 
+	// fallback is started once everything else is created so it is not left running on failure
+	if fallback != nil {
+		if err := fallback.Start(); err != nil {
+			return nil, err
+		}
+		log.GetLogger(ctx, "event/emitter", "New").Info("enable fallback sender")
+	}
+
+	created = true
+
+	//don't use parent context on routine
+	//because it might be canceled from parent routine when they finish
+	//causing whatever logic inside the routine to be canceled right away
+	//when they checking if the context is done
+	go em.worker(context.Background())
+
 	return em, nil
 }
 
+func newWriter(ctx context.Context, config *DriverConfig) (Writer, error) {
+	wf, ok := writers[config.Type]
+	if !ok {
+		return nil, errors.New("[event/emitter] unsupported writer driver")
+	}
+
+	return wf(ctx, config.Config)
+}
+
+// WithSchemaRegistry validate published payload against schema from registry,
+// on strict mode event without registered schema will be rejected
+func (e *Emitter) WithSchemaRegistry(registry SchemaRegistry, strict bool) {
+	e.schemaRegistry = registry
+	e.strictSchema = strict
+}
+
+// WithPayloadTransformer compress and/or encrypt published payload with the transformer
+func (e *Emitter) WithPayloadTransformer(payload *PayloadTransformer) {
+	e.payload = payload
+}
+
+// Publish send the message through the sender, on hybrid mode the message is written into the writer
+// and sent asynchronously
 func (e *Emitter) Publish(ctx context.Context, event, key string, message interface{}, metadata map[string]interface{}) error {
+	msg, err := e.prepare(ctx, event, key, message, metadata)
+	if err != nil {
+		return err
+	}
+
+	if e.writer == nil {
+		if e.isClosed() {
+			return ErrEmitterClosed
+		}
+		return e.sender.Send(ctx, msg)
+	}
+
+	//Using hybrid mode
+	if err := e.writer.Send(ctx, msg); err != nil {
+		return err
+	}
+
+	job := &publishJob{ctx: context.WithoutCancel(ctx), message: msg, written: true}
+	if err := e.enqueue(ctx, job); err != nil {
+		if errors.Is(err, ErrEmitterClosed) {
+			return err
+		}
+
+		// message is kept by the writer
+		log.WithContext(ctx).WithError(err).WithField("topic", msg.Topic).
+			Warnln("[event/emitter] message is left in the writer")
+	}
+
+	return nil
+}
+
+// PublishAsync queue the message to be sent in background, callback is called once the message is sent or failed.
+// When the queue is full the message is handled by the overflow policy, ErrEmitterOverflow is returned on drop
+// and callback is called with ErrMessageSpilled on spill
+func (e *Emitter) PublishAsync(ctx context.Context, event, key string, message interface{},
+	metadata map[string]interface{}, callback PublishCallback) error {
+	msg, err := e.prepare(ctx, event, key, message, metadata)
+	if err != nil {
+		return err
+	}
+
+	job := &publishJob{ctx: context.WithoutCancel(ctx), message: msg, callback: callback}
+	err = e.enqueue(ctx, job)
+	if !errors.Is(err, ErrEmitterOverflow) || e.overflow != OverflowSpill {
+		return err
+	}
+
+	if err := e.writer.Send(ctx, msg); err != nil {
+		return err
+	}
+
+	job.done(ErrMessageSpilled)
+	return nil
+}
+
+// PublishAt write the message into the writer to be sent at the given time by the scheduler,
+// the scheduler may run on another instance sharing the same writer
+func (e *Emitter) PublishAt(ctx context.Context, at time.Time, event, key string, message interface{},
+	metadata map[string]interface{}) error {
+	sw, ok := e.writer.(ScheduleWriter)
+	if !ok {
+		return ErrScheduleUnsupported
+	}
+
+	if e.isClosed() {
+		return ErrEmitterClosed
+	}
+
+	msg, err := e.prepare(ctx, event, key, message, metadata)
+	if err != nil {
+		return err
+	}
+	msg.Metadata[MetaDueAt] = at
+
+	record, err := OutboxFromMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	return sw.Schedule(ctx, record, at)
+}
+
+// PublishAfter write the message into the writer to be sent after the delay, see PublishAt
+func (e *Emitter) PublishAfter(ctx context.Context, delay time.Duration, event, key string, message interface{},
+	metadata map[string]interface{}) error {
+	return e.PublishAt(ctx, time.Now().Add(delay), event, key, message, metadata)
+}
+
+// CancelScheduled cancel scheduled messages of the event and key which are not sent yet,
+// it return number of messages canceled
+func (e *Emitter) CancelScheduled(ctx context.Context, event, key string) (int, error) {
+	sw, ok := e.writer.(ScheduleWriter)
+	if !ok {
+		return 0, ErrScheduleUnsupported
+	}
+
+	if key == "" {
+		return 0, errors.New("[event/emitter] missing key of scheduled message")
+	}
+
+	return sw.CancelSchedule(ctx, e.eventConfig.getTopic(event), key)
+}
+
+func (e *Emitter) prepare(ctx context.Context, event, key string, message interface{},
+	metadata map[string]interface{}) (*EventMessage, error) {
 	logger := log.WithContext(ctx).WithFields(logrus.Fields{
 		"pkg":      "event",
 		"function": "Publish",
@@ -219,7 +537,7 @@ func (e *Emitter) Publish(ctx context.Context, event, key string, message interf
 
 	if e.sender == nil {
 		logger.Error("driver is not set")
-		return errors.New("[event/emitter] driver is not set")
+		return nil, errors.New("[event/emitter] driver is not set")
 	}
 
 	topic := e.eventConfig.getTopic(event)
@@ -234,9 +552,15 @@ func (e *Emitter) Publish(ctx context.Context, event, key string, message interf
 		metadata[k] = v
 	}
 
+	if e.schemaRegistry != nil {
+		if err := e.validate(ctx, event, message, metadata); err != nil {
+			return nil, err
+		}
+	}
+
 	mhash, err := hash(message)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	metadata[MetaHash] = mhash
@@ -249,34 +573,137 @@ func (e *Emitter) Publish(ctx context.Context, event, key string, message interf
 		Metadata: metadata,
 	}
 
-	if e.writer != nil {
-		//Using hybrid mode
-		if err := e.writer.Send(ctx, msg); err != nil {
-			return err
+	if e.payload != nil {
+		if err := e.payload.Encode(msg); err != nil {
+			return nil, err
+		}
+
+		// hash of the plain payload would leak low-entropy payload in the clear metadata
+		if encrypted, _ := msg.Metadata[MetaEncrypted].(bool); encrypted {
+			if metadata[MetaHash], err = hash(msg.Data); err != nil {
+				return nil, err
+			}
 		}
-		e.channel <- msg
+	}
+
+	return msg, nil
+}
+
+// enqueue put the job into the queue following the overflow policy,
+// block policy wait until the context is done
+func (e *Emitter) enqueue(ctx context.Context, job *publishJob) error {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	if e.closed {
+		return ErrEmitterClosed
+	}
+
+	if e.overflow != OverflowBlock {
+		select {
+		case e.queue <- job:
+			return nil
+		default:
+			return ErrEmitterOverflow
+		}
+	}
+
+	select {
+	case e.queue <- job:
 		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	return e.sender.Send(ctx, msg)
+func (e *Emitter) isClosed() bool {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	return e.closed
+}
+
+// Close stop accepting new message and wait for the queued messages to be sent or until the context is done,
+// outbox relay and scheduler are stopped and sender or writer implementing ContextCloser or io.Closer is closed
+func (e *Emitter) Close(ctx context.Context) error {
+	e.closeOnce.Do(func() {
+		e.lock.Lock()
+		e.closed = true
+		close(e.queue)
+		e.lock.Unlock()
+
+		var errs []error
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			log.Errorln("[event/emitter] timeout waiting queued messages to be sent")
+			errs = append(errs, ctx.Err())
+		}
+
+		if e.relay != nil {
+			if err := e.relay.StopContext(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		if e.scheduler != nil {
+			if err := e.scheduler.StopContext(ctx); err != nil {
+				errs = append(errs, err)
+			}
+
+			if err := e.locker.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("[event/emitter] failed to close locker: %w", err))
+			}
+		}
+
+		if err := closeContext(ctx, e.sender); err != nil {
+			errs = append(errs, fmt.Errorf("[event/emitter] failed to close sender: %w", err))
+		}
+
+		if err := closeContext(ctx, e.writer); err != nil {
+			errs = append(errs, fmt.Errorf("[event/emitter] failed to close writer: %w", err))
+		}
+
+		e.closeErr = errors.Join(errs...)
+	})
+
+	return e.closeErr
+}
+
+// closeContext close v using CloseContext or Close if supported
+func closeContext(ctx context.Context, v interface{}) error {
+	switch closer := v.(type) {
+	case ContextCloser:
+		return closer.CloseContext(ctx)
+	case io.Closer:
+		return closer.Close()
+	}
+	return nil
 }
 
 func (e *Emitter) worker(ctx context.Context) {
+	defer close(e.done)
+
 	logger := log.WithContext(ctx).WithFields(logrus.Fields{
 		"pkg":      "event",
 		"function": "worker",
 	})
-	logger.Info("Running event emitter in hybrid mode")
-	for msg := range e.channel {
 
-		if err := e.sender.Send(ctx, msg); err != nil {
+	for job := range e.queue {
+		err := e.sender.Send(job.ctx, job.message)
+		if err != nil {
 			logger.WithError(err).Error("Error sending message through sender")
-			continue
+		} else if job.written {
+			if err = e.writer.Delete(job.ctx, job.message); err != nil {
+				logger.WithError(err).Error("Error deleting message through writer")
+			}
 		}
 
-		if err := e.writer.Delete(ctx, msg); err != nil {
-			logger.WithError(err).Error("Error deleting message through writer")
-			continue
-		}
+		job.done(err)
+	}
+}
+
+func (j *publishJob) done(err error) {
+	if j.callback != nil {
+		j.callback(j.message, err)
 	}
 }
@@ -122,3 +122,167 @@ func TestMetadata(t *testing.T) {
 	assert.Contains(t, logStr, "baz:qux")
 
 }
+
+type blockingSender struct {
+	testSender
+	started chan struct{}
+	release chan struct{}
+	closed  bool
+}
+
+func newBlockingSender() *blockingSender {
+	return &blockingSender{
+		started: make(chan struct{}, 10),
+		release: make(chan struct{}),
+	}
+}
+
+func (s *blockingSender) Send(ctx context.Context, message *EventMessage) error {
+	s.started <- struct{}{}
+	<-s.release
+	return s.testSender.Send(ctx, message)
+}
+
+func (s *blockingSender) Close() error {
+	s.closed = true
+	return nil
+}
+
+type testWriter struct {
+	testSender
+	deleted int
+}
+
+func (w *testWriter) Delete(ctx context.Context, message *EventMessage) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.deleted++
+	return nil
+}
+
+func newTestEmitter(t *testing.T, sender Sender, writer Writer, buffer int, overflow string) *Emitter {
+	RegisterSender("emitter-test", func(ctx context.Context, config interface{}) (Sender, error) {
+		return sender, nil
+	})
+	RegisterWriter("emitter-test", func(ctx context.Context, config interface{}) (Writer, error) {
+		return writer, nil
+	})
+
+	conf := &EmitterConfig{
+		Sender:   &DriverConfig{Type: "emitter-test"},
+		Buffer:   buffer,
+		Overflow: overflow,
+	}
+	if writer != nil {
+		conf.Writer = &DriverConfig{Type: "emitter-test"}
+	}
+
+	em, err := New(context.Background(), conf)
+	require.Nil(t, err)
+	return em
+}
+
+func TestEmitterPublishAsync(t *testing.T) {
+	sender := newBlockingSender()
+	em := newTestEmitter(t, sender, nil, 5, "")
+	ctx := context.Background()
+
+	var lock sync.Mutex
+	var results []error
+	callback := func(message *EventMessage, err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		results = append(results, err)
+	}
+
+	for i := 0; i < 3; i++ {
+		require.Nil(t, em.PublishAsync(ctx, "test", "t123", i, nil, callback))
+	}
+
+	closed := make(chan error)
+	go func() {
+		closed <- em.Close(ctx)
+	}()
+
+	close(sender.release)
+	require.Nil(t, <-closed)
+
+	assert.Equal(t, 3, sender.count(), "queued messages are sent before closed")
+	assert.Equal(t, []error{nil, nil, nil}, results)
+	assert.True(t, sender.closed, "sender is closed")
+
+	assert.ErrorIs(t, em.PublishAsync(ctx, "test", "t123", "data", nil, callback), ErrEmitterClosed)
+	assert.ErrorIs(t, em.Publish(ctx, "test", "t123", "data", nil), ErrEmitterClosed)
+	assert.Nil(t, em.Close(ctx))
+}
+
+func TestEmitterOverflowDrop(t *testing.T) {
+	sender := newBlockingSender()
+	em := newTestEmitter(t, sender, nil, 1, OverflowDrop)
+	ctx := context.Background()
+
+	require.Nil(t, em.PublishAsync(ctx, "test", "1", "data", nil, nil))
+	<-sender.started
+	require.Nil(t, em.PublishAsync(ctx, "test", "2", "data", nil, nil))
+	assert.ErrorIs(t, em.PublishAsync(ctx, "test", "3", "data", nil, nil), ErrEmitterOverflow)
+
+	close(sender.release)
+	require.Nil(t, em.Close(ctx))
+	assert.Equal(t, 2, sender.count())
+}
+
+func TestEmitterOverflowSpill(t *testing.T) {
+	sender := newBlockingSender()
+	writer := &testWriter{}
+	em := newTestEmitter(t, sender, writer, 1, OverflowSpill)
+	ctx := context.Background()
+
+	require.Nil(t, em.PublishAsync(ctx, "test", "1", "data", nil, nil))
+	<-sender.started
+	require.Nil(t, em.PublishAsync(ctx, "test", "2", "data", nil, nil))
+
+	var spilled error
+	require.Nil(t, em.PublishAsync(ctx, "test", "3", "data", nil, func(message *EventMessage, err error) {
+		spilled = err
+	}))
+	assert.ErrorIs(t, spilled, ErrMessageSpilled)
+	assert.Equal(t, 1, writer.count())
+
+	// hybrid message is left in the writer
+	require.Nil(t, em.Publish(ctx, "test", "4", "data", nil))
+	assert.Equal(t, 2, writer.count())
+
+	close(sender.release)
+	require.Nil(t, em.Close(ctx))
+	assert.Equal(t, 2, sender.count())
+	assert.Equal(t, 0, writer.deleted)
+}
+
+func TestEmitterCloseTimeout(t *testing.T) {
+	sender := newBlockingSender()
+	writer := &testWriter{}
+	em := newTestEmitter(t, sender, writer, 0, "")
+	ctx := context.Background()
+
+	require.Nil(t, em.Publish(ctx, "test", "1", "data", nil))
+	<-sender.started
+
+	tctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, em.Close(tctx), context.DeadlineExceeded)
+
+	close(sender.release)
+	assert.Eventually(t, func() bool {
+		writer.lock.Lock()
+		defer writer.lock.Unlock()
+		return writer.deleted == 1
+	}, time.Second, 10*time.Millisecond, "hybrid message is deleted once sent")
+}
+
+func TestEmitterInvalidOverflow(t *testing.T) {
+	_, err := New(context.Background(), &EmitterConfig{Overflow: "unknown"})
+	assert.Error(t, err)
+
+	_, err = New(context.Background(), &EmitterConfig{Overflow: OverflowSpill})
+	assert.Error(t, err, "spill requires writer")
+}
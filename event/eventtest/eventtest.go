@@ -0,0 +1,151 @@
+package eventtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/diki-haryadi/govega/event"
+)
+
+// RecordingSender sender which record the messages sent for assertion in unit test
+type RecordingSender struct {
+	lock     sync.Mutex
+	messages []*event.EventMessage
+	notify   chan struct{}
+	err      error
+}
+
+// NewRecordingSender create new recording sender
+func NewRecordingSender() *RecordingSender {
+	return &RecordingSender{
+		notify: make(chan struct{}),
+	}
+}
+
+// Register register the sender as sender driver with the name, so it can be used by emitter config.
+// Sender registry is global and not synchronized, register once e.g. in TestMain; prefer NewEmitter
+func (s *RecordingSender) Register(name string) *RecordingSender {
+	event.RegisterSender(name, func(ctx context.Context, config interface{}) (event.Sender, error) {
+		return s, nil
+	})
+	return s
+}
+
+// NewEmitter create emitter sending to the recording sender, config sender is ignored.
+// The sender is not registered so it is safe for parallel tests
+func (s *RecordingSender) NewEmitter(ctx context.Context, config *event.EmitterConfig) (*event.Emitter, error) {
+	conf := event.EmitterConfig{}
+	if config != nil {
+		conf = *config
+	}
+
+	return event.NewWithSender(ctx, s, &conf)
+}
+
+// Send record the message, or return the error set by Fail without recording
+func (s *RecordingSender) Send(ctx context.Context, message *event.EventMessage) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	s.messages = append(s.messages, message)
+
+	// wake up all waiters
+	close(s.notify)
+	s.notify = make(chan struct{})
+
+	return nil
+}
+
+// Fail make the next sends fail with the error, nil error make them succeed again
+func (s *RecordingSender) Fail(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.err = err
+}
+
+// Messages return all recorded messages in the order they are sent
+func (s *RecordingSender) Messages() []*event.EventMessage {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]*event.EventMessage{}, s.messages...)
+}
+
+// Topic return recorded messages of the topic
+func (s *RecordingSender) Topic(topic string) []*event.EventMessage {
+	messages := make([]*event.EventMessage, 0)
+	for _, m := range s.Messages() {
+		if m.Topic == topic {
+			messages = append(messages, m)
+		}
+	}
+	return messages
+}
+
+// Len return number of recorded messages
+func (s *RecordingSender) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.messages)
+}
+
+// Reset remove all recorded messages
+func (s *RecordingSender) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.messages = nil
+}
+
+// Wait wait until at least n messages are recorded or timeout, e.g. for PublishAsync.
+// It return the recorded messages, with error on timeout
+func (s *RecordingSender) Wait(n int, timeout time.Duration) ([]*event.EventMessage, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		s.lock.Lock()
+		messages := append([]*event.EventMessage{}, s.messages...)
+		notify := s.notify
+		s.lock.Unlock()
+
+		if len(messages) >= n {
+			return messages, nil
+		}
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			return messages, fmt.Errorf("[event/eventtest] timeout waiting %d messages, got %d", n, len(messages))
+		}
+	}
+}
+
+// Payload decode the message data into generic json value, e.g. map[string]interface{}
+func Payload(message *event.EventMessage) (interface{}, error) {
+	raw, err := payloadBytes(message)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload interface{}
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// payloadBytes return the message data as json, raw data is used when available
+func payloadBytes(message *event.EventMessage) ([]byte, error) {
+	if message.RawData != nil {
+		return message.RawData, nil
+	}
+
+	return json.Marshal(message.Data)
+}
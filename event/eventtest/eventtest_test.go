@@ -0,0 +1,97 @@
+package eventtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/diki-haryadi/govega/event"
+	. "github.com/onsi/gomega"
+)
+
+type testOrder struct {
+	ID     string `json:"id"`
+	Amount int    `json:"amount"`
+}
+
+func TestRecordingSender(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+	sender := NewRecordingSender()
+
+	em, err := sender.NewEmitter(ctx, &event.EmitterConfig{
+		EventConfig: &event.EventConfig{
+			EventMap: map[string]string{"order_created": "order-topic"},
+		},
+	})
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	g.Expect(em.Publish(ctx, "order_created", "o1", &testOrder{ID: "o1", Amount: 100}, nil)).Should(Succeed())
+	g.Expect(em.Publish(ctx, "order_paid", "o1", map[string]interface{}{"id": "o1"}, map[string]interface{}{"channel": "va"})).Should(Succeed())
+
+	g.Expect(sender).Should(HavePublished("order-topic", "o1"))
+	g.Expect(sender).ShouldNot(HavePublished("order-topic", "o2"))
+	g.Expect(sender).Should(HavePublishedCount(Equal(2)))
+	g.Expect(sender).Should(HavePublishedMessage(SatisfyAll(
+		HaveTopic(Equal("order-topic")),
+		HavePayloadMatching(HaveKeyWithValue("amount", BeEquivalentTo(100))),
+		HavePayloadJSON(`{"id": "o1", "amount": 100}`),
+		HaveMetadata(event.MetaEvent, Equal("order_created")),
+	)))
+	g.Expect(sender.Topic("order_paid")).Should(ConsistOf(HaveMetadata("channel", Equal("va"))))
+
+	sender.Fail(errors.New("failed"))
+	g.Expect(em.Publish(ctx, "order_created", "o2", &testOrder{ID: "o2"}, nil)).ShouldNot(Succeed())
+	g.Expect(sender.Len()).Should(Equal(2))
+
+	sender.Fail(nil)
+	sender.Reset()
+	g.Expect(sender.Messages()).Should(BeEmpty())
+}
+
+func TestRecordingSenderParallel(t *testing.T) {
+	for _, key := range []string{"p1", "p2", "p3", "p4"} {
+		key := key
+		t.Run(key, func(t *testing.T) {
+			t.Parallel()
+			g := NewGomegaWithT(t)
+			ctx := context.Background()
+			sender := NewRecordingSender()
+
+			em, err := sender.NewEmitter(ctx, nil)
+			g.Expect(err).ShouldNot(HaveOccurred())
+			g.Expect(em.Publish(ctx, "order_created", key, &testOrder{ID: key}, nil)).Should(Succeed())
+			g.Expect(em.Close(ctx)).Should(Succeed())
+
+			g.Expect(sender.Messages()).Should(ConsistOf(HaveMessageKey(Equal(key))))
+		})
+	}
+}
+
+func TestRecordingSenderWait(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+	sender := NewRecordingSender()
+
+	go func() {
+		for _, key := range []string{"k1", "k2", "k3"} {
+			time.Sleep(10 * time.Millisecond)
+			_ = sender.Send(ctx, &event.EventMessage{Topic: "test", Key: key})
+		}
+	}()
+
+	messages, err := sender.Wait(3, time.Second)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(messages).Should(HaveLen(3))
+
+	messages, err = sender.Wait(4, 50*time.Millisecond)
+	g.Expect(err).Should(HaveOccurred())
+	g.Expect(messages).Should(HaveLen(3))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = sender.Send(ctx, &event.EventMessage{Topic: "test", Key: "k4"})
+	}()
+	g.Eventually(sender).Should(HavePublished("test", "k4"))
+}
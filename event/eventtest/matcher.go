@@ -0,0 +1,46 @@
+package eventtest
+
+import (
+	"github.com/diki-haryadi/govega/event"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/types"
+)
+
+// HavePublished succeed when the recording sender has message of the topic and key
+func HavePublished(topic, key string) types.GomegaMatcher {
+	return HavePublishedMessage(SatisfyAll(HaveTopic(Equal(topic)), HaveMessageKey(Equal(key))))
+}
+
+// HavePublishedMessage succeed when any message of the recording sender match m
+func HavePublishedMessage(m types.GomegaMatcher) types.GomegaMatcher {
+	return WithTransform(func(s *RecordingSender) []*event.EventMessage { return s.Messages() }, ContainElement(m))
+}
+
+// HavePublishedCount match number of messages of the recording sender
+func HavePublishedCount(m types.GomegaMatcher) types.GomegaMatcher {
+	return WithTransform(func(s *RecordingSender) int { return s.Len() }, m)
+}
+
+func HaveTopic(m types.GomegaMatcher) types.GomegaMatcher {
+	return WithTransform(func(e *event.EventMessage) string { return e.Topic }, m)
+}
+
+func HaveMessageKey(m types.GomegaMatcher) types.GomegaMatcher {
+	return WithTransform(func(e *event.EventMessage) string { return e.Key }, m)
+}
+
+// HaveMetadata match the metadata value of the key, e.g. HaveMetadata(event.MetaVersion, BeEquivalentTo(2))
+func HaveMetadata(key string, m types.GomegaMatcher) types.GomegaMatcher {
+	return WithTransform(func(e *event.EventMessage) interface{} { return e.Metadata[key] }, m)
+}
+
+// HavePayloadMatching match the message data decoded as generic json value,
+// e.g. HavePayloadMatching(HaveKeyWithValue("amount", 100.0))
+func HavePayloadMatching(m types.GomegaMatcher) types.GomegaMatcher {
+	return WithTransform(Payload, m)
+}
+
+// HavePayloadJSON match the message data with the json
+func HavePayloadJSON(expected interface{}) types.GomegaMatcher {
+	return WithTransform(payloadBytes, MatchJSON(expected))
+}
@@ -0,0 +1,366 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/diki-haryadi/govega/log"
+	"github.com/diki-haryadi/govega/monitor"
+)
+
+const (
+	DefaultFallbackThreshold   = 5
+	DefaultFallbackOpenTimeout = 30 * time.Second
+	DefaultFallbackInterval    = 5 * time.Second
+
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+var ErrFallbackStarted = errors.New("Fallback sender already started")
+
+type (
+	FallbackConfig struct {
+		// Writer store the messages while the sender is unavailable, driver should support outbox relay.
+		// Don't share the outbox of the hybrid mode as the spilled messages are forwarded without min age
+		Writer *DriverConfig `json:"writer" mapstructure:"writer"`
+		// Threshold consecutive send failures to open the breaker, default: 5
+		Threshold int `json:"threshold" mapstructure:"threshold"`
+		// OpenTimeout duration the breaker stay open before forwarding is retried, default: 30s
+		OpenTimeout string `json:"open_timeout" mapstructure:"open_timeout"`
+		// Interval between forwarding of the spilled messages, default: 5s
+		Interval string `json:"interval" mapstructure:"interval"`
+		// BatchSize maximum number of messages forwarded on each interval, default: 100
+		BatchSize int `json:"batch_size" mapstructure:"batch_size"`
+		// ClaimTimeout duration before a claimed message can be reclaimed, default: 5m
+		ClaimTimeout string `json:"claim_timeout" mapstructure:"claim_timeout"`
+	}
+
+	// FallbackSender circuit breaking sender which spill messages to the writer when the sender fails,
+	// spilled messages are forwarded in order once the sender is available. New messages are spilled
+	// until all of the spilled messages are forwarded to keep the order
+	FallbackSender struct {
+		sender      Sender
+		writer      Writer
+		relay       *OutboxRelay
+		forwarder   *forwardSender
+		threshold   int
+		openTimeout time.Duration
+		interval    time.Duration
+
+		// lock guard spilled flag from being cleared while a message is spilled
+		lock        sync.RWMutex
+		spilled     uint32
+		forwardLock sync.Mutex
+
+		breakerLock sync.Mutex
+		state       string
+		failures    int
+		openedAt    time.Time
+
+		running   uint32
+		stateLock sync.Mutex
+		stopch    chan bool
+		shutdown  chan bool
+	}
+
+	// forwardSender send the spilled messages through the fallback sender breaker
+	forwardSender struct {
+		fallback *FallbackSender
+		claimed  int
+		failed   bool
+	}
+)
+
+// NewFallbackSender create fallback sender of the sender, writer should implement OutboxRelayer
+func NewFallbackSender(sender Sender, writer Writer, config *FallbackConfig) (*FallbackSender, error) {
+	if sender == nil || writer == nil {
+		return nil, errors.New("[event/fallback] missing sender or writer")
+	}
+
+	if config == nil {
+		config = &FallbackConfig{}
+	}
+
+	f := &FallbackSender{
+		sender:      sender,
+		writer:      writer,
+		threshold:   DefaultFallbackThreshold,
+		openTimeout: DefaultFallbackOpenTimeout,
+		interval:    DefaultFallbackInterval,
+		state:       BreakerClosed,
+		running:     stop,
+	}
+
+	f.forwarder = &forwardSender{fallback: f}
+	relay, err := NewOutboxRelay(writer, f.forwarder, &OutboxRelayConfig{
+		BatchSize:    config.BatchSize,
+		ClaimTimeout: config.ClaimTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("[event/fallback] writer doesn't support forwarding: %w", err)
+	}
+	relay.minAge = 0
+	f.relay = relay
+
+	if config.Threshold > 0 {
+		f.threshold = config.Threshold
+	}
+
+	if config.OpenTimeout != "" {
+		timeout, err := time.ParseDuration(config.OpenTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("[event/fallback] invalid open timeout value: %w", err)
+		}
+
+		f.openTimeout = timeout
+	}
+
+	if config.Interval != "" {
+		interval, err := time.ParseDuration(config.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("[event/fallback] invalid interval value: %w", err)
+		}
+
+		f.interval = interval
+	}
+
+	return f, nil
+}
+
+// Send send the message through the sender, message is spilled to the writer when the send fails,
+// the breaker is open or there are spilled messages not yet forwarded
+func (f *FallbackSender) Send(ctx context.Context, message *EventMessage) error {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	if atomic.LoadUint32(&f.spilled) == 0 && f.allow() {
+		err := f.sender.Send(ctx, message)
+		f.record(err)
+		if err == nil {
+			return nil
+		}
+
+		log.WithContext(ctx).WithError(err).Warnln("[event/fallback] failed to send message, spilling to writer")
+	}
+
+	if err := f.writer.Send(ctx, message); err != nil {
+		return fmt.Errorf("[event/fallback] failed to spill message: %w", err)
+	}
+
+	atomic.StoreUint32(&f.spilled, 1)
+	monitor.FeedSenderSpillMetrics(message.Topic)
+
+	return nil
+}
+
+// ForwardOnce forward a batch of spilled messages when the breaker allows, it return number of
+// messages forwarded
+func (f *FallbackSender) ForwardOnce(ctx context.Context) (int, error) {
+	f.forwardLock.Lock()
+	defer f.forwardLock.Unlock()
+
+	if atomic.LoadUint32(&f.spilled) == 0 || !f.allow() {
+		return 0, nil
+	}
+
+	n, claimed, err := f.forward(ctx)
+	if err != nil || n < claimed || claimed >= f.relay.batchSize {
+		return n, err
+	}
+
+	// all spilled messages are forwarded, make sure no message is spilled meanwhile
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	m, claimed, err := f.forward(ctx)
+	if err == nil && claimed == 0 {
+		atomic.StoreUint32(&f.spilled, 0)
+	}
+
+	return n + m, err
+}
+
+// forward relay a batch of spilled messages, it return number of messages forwarded and claimed
+func (f *FallbackSender) forward(ctx context.Context) (int, int, error) {
+	f.forwarder.claimed = 0
+	f.forwarder.failed = false
+	n, err := f.relay.RelayOnce(ctx)
+	return n, f.forwarder.claimed, err
+}
+
+// State return the breaker state
+func (f *FallbackSender) State() string {
+	f.breakerLock.Lock()
+	defer f.breakerLock.Unlock()
+
+	if f.state == BreakerOpen && time.Since(f.openedAt) >= f.openTimeout {
+		return BreakerHalfOpen
+	}
+
+	return f.state
+}
+
+// allow return whether sending is allowed, open breaker allow a probe after the open timeout
+func (f *FallbackSender) allow() bool {
+	return f.State() != BreakerOpen
+}
+
+// record update the breaker with the send result, a failure on half open breaker reopen it
+func (f *FallbackSender) record(err error) {
+	f.breakerLock.Lock()
+	defer f.breakerLock.Unlock()
+
+	if err == nil {
+		if f.state != BreakerClosed {
+			log.Infoln("[event/fallback] sender recovered, breaker closed")
+		}
+
+		f.state = BreakerClosed
+		f.failures = 0
+		return
+	}
+
+	f.failures++
+	if f.state == BreakerOpen || f.failures >= f.threshold {
+		if f.state != BreakerOpen {
+			log.WithError(err).Warnln("[event/fallback] sender keep failing, breaker opened")
+		}
+
+		f.state = BreakerOpen
+		f.openedAt = time.Now()
+	}
+}
+
+// Start forward the spilled messages periodically in background
+func (f *FallbackSender) Start() error {
+	f.stateLock.Lock()
+	defer f.stateLock.Unlock()
+
+	if f.isRunning() {
+		return ErrFallbackStarted
+	}
+
+	// messages may be left spilled by previous run
+	atomic.StoreUint32(&f.spilled, 1)
+
+	f.stopch = make(chan bool)
+	f.shutdown = make(chan bool)
+
+	go f.run(f.stopch, f.shutdown)
+
+	atomic.StoreUint32(&f.running, start)
+
+	return nil
+}
+
+// Stop stop forwarding waiting for the running batch to complete
+func (f *FallbackSender) Stop() error {
+	return f.StopContext(context.Background())
+}
+
+// StopContext stop forwarding or until the context timeout
+func (f *FallbackSender) StopContext(ctx context.Context) error {
+	f.stateLock.Lock()
+	defer f.stateLock.Unlock()
+
+	if !f.isRunning() {
+		return nil
+	}
+
+	close(f.stopch)
+
+	var err error
+	select {
+	case <-ctx.Done():
+		log.Errorln("[event/fallback] timeout waiting fallback sender to stop")
+		err = ctx.Err()
+	case <-f.shutdown:
+	}
+
+	f.stopch = nil
+	f.shutdown = nil
+	atomic.StoreUint32(&f.running, stop)
+
+	return err
+}
+
+// Close stop forwarding and close the sender and writer
+func (f *FallbackSender) Close() error {
+	return f.CloseContext(context.Background())
+}
+
+// CloseContext stop forwarding or until the context timeout, then close the sender and writer
+func (f *FallbackSender) CloseContext(ctx context.Context) error {
+	return errors.Join(
+		f.StopContext(ctx),
+		closeContext(ctx, f.sender),
+		closeContext(ctx, f.writer),
+	)
+}
+
+func (f *FallbackSender) run(stop <-chan bool, shutdown chan<- bool) {
+	defer close(shutdown)
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	// forward messages left by previous run without waiting for the first tick
+	f.forwardAndLog()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			f.forwardAndLog()
+		}
+	}
+}
+
+func (f *FallbackSender) forwardAndLog() {
+	// don't use cancelable context, let the running batch complete
+	// so claimed messages are released properly
+	n, err := f.ForwardOnce(context.Background())
+	if err != nil {
+		log.WithError(err).Errorln("[event/fallback] failed to forward spilled messages")
+		return
+	}
+
+	if n > 0 {
+		log.WithFields(log.Fields{"forwarded": n}).Infoln("[event/fallback] spilled messages forwarded")
+	}
+}
+
+func (f *FallbackSender) isRunning() bool {
+	return atomic.LoadUint32(&f.running) == start
+}
+
+// Send forward the message, messages are not sent once the breaker is open. The rest of the batch
+// is skipped after a failure regardless of the key to keep the order of all spilled messages
+func (s *forwardSender) Send(ctx context.Context, message *EventMessage) error {
+	s.claimed++
+	if s.failed {
+		return errRelaySkipped
+	}
+
+	if !s.fallback.allow() {
+		s.failed = true
+		return errors.New("[event/fallback] breaker is open")
+	}
+
+	err := s.fallback.sender.Send(ctx, message)
+	s.fallback.record(err)
+	monitor.FeedSenderForwardMetrics(message.Topic, getMetricStatusFromError(err))
+
+	if err != nil {
+		s.failed = true
+	}
+
+	return err
+}
@@ -0,0 +1,186 @@
+package event
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSpillWriter relay writer which store the messages sent
+type testSpillWriter struct {
+	testRelayWriter
+}
+
+func (w *testSpillWriter) Send(ctx context.Context, message *EventMessage) error {
+	rec, err := OutboxFromMessage(message)
+	if err != nil {
+		return err
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.records = append(w.records, rec)
+	return nil
+}
+
+func TestFallbackSender(t *testing.T) {
+	ctx := context.Background()
+
+	var failing uint32 = 1
+	sender := &testSender{failFn: func(message *EventMessage) bool {
+		return atomic.LoadUint32(&failing) == 1
+	}}
+	writer := &testSpillWriter{}
+
+	_, err := NewFallbackSender(sender, &testWriter{}, nil)
+	assert.Error(t, err, "writer should support relay")
+
+	fallback, err := NewFallbackSender(sender, writer, &FallbackConfig{Threshold: 2, OpenTimeout: "50ms"})
+	require.NoError(t, err)
+
+	require.NoError(t, fallback.Send(ctx, &EventMessage{Topic: "test", Key: "k0"}))
+	assert.Equal(t, BreakerClosed, fallback.State())
+	assert.Equal(t, 1, writer.count(), "failed message is spilled")
+
+	n, err := fallback.ForwardOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, BreakerOpen, fallback.State(), "breaker open after reaching the threshold")
+
+	for _, key := range []string{"k1", "k2"} {
+		require.NoError(t, fallback.Send(ctx, &EventMessage{Topic: "test", Key: key}))
+	}
+	assert.Equal(t, 3, writer.count())
+
+	atomic.StoreUint32(&failing, 0)
+	n, err = fallback.ForwardOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "nothing is forwarded while the breaker is open")
+
+	assert.Eventually(t, func() bool {
+		return fallback.State() == BreakerHalfOpen
+	}, time.Second, 10*time.Millisecond)
+
+	// new message is spilled while there is backlog to keep the order
+	require.NoError(t, fallback.Send(ctx, &EventMessage{Topic: "test", Key: "k3"}))
+	assert.Equal(t, 0, sender.count())
+
+	n, err = fallback.ForwardOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, BreakerClosed, fallback.State())
+	assert.Equal(t, 0, writer.count())
+
+	var keys []string
+	for _, msg := range sender.sent {
+		keys = append(keys, msg.Key)
+	}
+	assert.Equal(t, []string{"k0", "k1", "k2", "k3"}, keys, "spilled messages are forwarded in order")
+
+	require.NoError(t, fallback.Send(ctx, &EventMessage{Topic: "test", Key: "k4"}))
+	assert.Equal(t, 5, sender.count(), "message is sent directly once the backlog is forwarded")
+	assert.Equal(t, 0, writer.count())
+}
+
+func TestFallbackSenderStart(t *testing.T) {
+	ctx := context.Background()
+	sender := &testSender{}
+	writer := &testSpillWriter{}
+
+	// messages left by previous run
+	writer.add(t, &EventMessage{Topic: "test", Key: "k0"}, time.Now().Add(-time.Minute))
+	writer.add(t, &EventMessage{Topic: "test", Key: "k1"}, time.Now().Add(-time.Second))
+
+	fallback, err := NewFallbackSender(sender, writer, &FallbackConfig{Interval: "10ms"})
+	require.NoError(t, err)
+
+	require.NoError(t, fallback.Start())
+	assert.ErrorIs(t, fallback.Start(), ErrFallbackStarted)
+
+	require.NoError(t, fallback.Send(ctx, &EventMessage{Topic: "test", Key: "k2"}))
+
+	assert.Eventually(t, func() bool {
+		return sender.count() == 3
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, fallback.Close())
+
+	var keys []string
+	for _, msg := range sender.sent {
+		keys = append(keys, msg.Key)
+	}
+	assert.Equal(t, []string{"k0", "k1", "k2"}, keys)
+}
+
+func TestFallbackSenderCloseContext(t *testing.T) {
+	sender := newBlockingSender()
+	writer := &testSpillWriter{}
+	writer.add(t, &EventMessage{Topic: "test", Key: "k0"}, time.Now().Add(-time.Minute))
+
+	fallback, err := NewFallbackSender(sender, writer, &FallbackConfig{Interval: "10ms"})
+	require.NoError(t, err)
+	require.NoError(t, fallback.Start())
+
+	em := newTestEmitter(t, fallback, nil, 0, "")
+	<-sender.started
+
+	// emitter close context bound the wait for the running forward
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, em.Close(ctx), context.DeadlineExceeded)
+	assert.True(t, sender.closed, "sender is closed")
+
+	close(sender.release)
+}
+
+// closingSpillWriter spill writer which record whether it is closed
+type closingSpillWriter struct {
+	testSpillWriter
+	closed bool
+}
+
+func (w *closingSpillWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestEmitterFallbackCleanup(t *testing.T) {
+	writer := &closingSpillWriter{}
+	RegisterWriter("fallback-cleanup", func(ctx context.Context, config interface{}) (Writer, error) {
+		return writer, nil
+	})
+
+	_, err := NewWithSender(context.Background(), &testSender{}, &EmitterConfig{
+		Fallback: &FallbackConfig{Writer: &DriverConfig{Type: "fallback-cleanup"}},
+		Writer:   &DriverConfig{Type: "unknown"},
+	})
+	require.Error(t, err)
+	assert.True(t, writer.closed, "fallback writer is closed when emitter fails to be created")
+}
+
+func TestFallbackSenderForwardStopOnFailure(t *testing.T) {
+	ctx := context.Background()
+
+	var attempts int32
+	sender := &testSender{failFn: func(message *EventMessage) bool {
+		atomic.AddInt32(&attempts, 1)
+		return true
+	}}
+	writer := &testSpillWriter{}
+	for i := 0; i < 3; i++ {
+		writer.add(t, &EventMessage{Topic: "test"}, time.Now().Add(-time.Duration(3-i)*time.Second))
+	}
+
+	fallback, err := NewFallbackSender(sender, writer, &FallbackConfig{Threshold: 10})
+	require.NoError(t, err)
+	atomic.StoreUint32(&fallback.spilled, 1)
+
+	n, err := fallback.ForwardOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts), "unkeyed messages after the failure are not sent")
+	assert.Equal(t, 3, writer.count())
+}
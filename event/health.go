@@ -0,0 +1,207 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/diki-haryadi/govega/monitor"
+)
+
+// DefaultStallTimeout duration without progress before a worker pool is reported as stalled
+const DefaultStallTimeout = 5 * time.Minute
+
+type (
+	// PoolHealth health report of a single listener worker pool
+	PoolHealth struct {
+		Topic string `json:"topic"`
+		Group string `json:"group"`
+		// Alive the pool is running and fetching messages
+		Alive bool `json:"alive"`
+		// Progressing the pool is idle or completed a message within the stall timeout,
+		// and it is not failing to fetch messages longer than the stall timeout
+		Progressing  bool      `json:"progressing"`
+		Inflight     int64     `json:"inflight"`
+		Processed    uint64    `json:"processed"`
+		Failed       uint64    `json:"failed"`
+		LastProgress time.Time `json:"last_progress"`
+		LastError    string    `json:"last_error,omitempty"`
+	}
+
+	// ConsumerHealth health report of consumer and all of its worker pools
+	ConsumerHealth struct {
+		Running bool         `json:"running"`
+		Pools   []PoolHealth `json:"pools"`
+	}
+
+	// poolState shared state of a worker pool, updated by the workers and read by health check
+	poolState struct {
+		topic        string
+		group        string
+		alive        int32
+		inflight     int64
+		processed    uint64
+		failed       uint64
+		lastProgress int64
+		failingSince int64
+		lock         sync.Mutex
+		lastError    string
+	}
+)
+
+func newPoolState(topic, group string) *poolState {
+	return &poolState{
+		topic: topic,
+		group: group,
+	}
+}
+
+func (s *poolState) setAlive(alive bool) {
+	if alive {
+		atomic.StoreInt32(&s.alive, 1)
+		return
+	}
+	atomic.StoreInt32(&s.alive, 0)
+}
+
+// begin mark n messages as being processed
+func (s *poolState) begin(n int) {
+	// pool was idle, stall timer start from now
+	if atomic.AddInt64(&s.inflight, int64(n)) == int64(n) {
+		atomic.StoreInt64(&s.lastProgress, time.Now().UnixNano())
+	}
+
+	monitor.FeedConsumerInflightMetrics(s.topic, s.group, n)
+}
+
+// done mark n messages as completed with the consume result
+func (s *poolState) done(n int, err error) {
+	atomic.AddInt64(&s.inflight, -int64(n))
+	atomic.StoreInt64(&s.lastProgress, time.Now().UnixNano())
+	monitor.FeedConsumerInflightMetrics(s.topic, s.group, -n)
+
+	if err == nil {
+		atomic.AddUint64(&s.processed, uint64(n))
+		return
+	}
+
+	atomic.AddUint64(&s.failed, uint64(n))
+	s.setError(err)
+
+	if errors.Is(err, ErrCommitFailed) {
+		s.commitFailed(err)
+	}
+}
+
+// fetched record the result of fetching message from the iterator
+func (s *poolState) fetched(err error) {
+	if err == nil {
+		atomic.StoreInt64(&s.failingSince, 0)
+		return
+	}
+
+	atomic.CompareAndSwapInt64(&s.failingSince, 0, time.Now().UnixNano())
+	s.setError(err)
+}
+
+func (s *poolState) commitFailed(err error) {
+	s.setError(err)
+	monitor.FeedConsumerCommitFailureMetrics(s.topic, s.group)
+}
+
+func (s *poolState) setError(err error) {
+	s.lock.Lock()
+	s.lastError = err.Error()
+	s.lock.Unlock()
+}
+
+func (s *poolState) health(now time.Time, stallTimeout time.Duration) PoolHealth {
+	s.lock.Lock()
+	lastError := s.lastError
+	s.lock.Unlock()
+
+	h := PoolHealth{
+		Topic:     s.topic,
+		Group:     s.group,
+		Alive:     atomic.LoadInt32(&s.alive) == 1,
+		Inflight:  atomic.LoadInt64(&s.inflight),
+		Processed: atomic.LoadUint64(&s.processed),
+		Failed:    atomic.LoadUint64(&s.failed),
+		LastError: lastError,
+	}
+
+	if lp := atomic.LoadInt64(&s.lastProgress); lp > 0 {
+		h.LastProgress = time.Unix(0, lp)
+	}
+
+	h.Progressing = true
+	if h.Inflight > 0 && now.Sub(h.LastProgress) > stallTimeout {
+		h.Progressing = false
+	}
+
+	if fs := atomic.LoadInt64(&s.failingSince); fs > 0 && now.Sub(time.Unix(0, fs)) > stallTimeout {
+		h.Progressing = false
+	}
+
+	return h
+}
+
+// Healthy return true when the pool is alive and progressing
+func (h PoolHealth) Healthy() bool {
+	return h.Alive && h.Progressing
+}
+
+// Healthy return true when consumer is running and all of its pools are healthy
+func (h ConsumerHealth) Healthy() bool {
+	if !h.Running {
+		return false
+	}
+
+	for _, p := range h.Pools {
+		if !p.Healthy() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Health return health report of each subscribed worker pool,
+// pool processing messages without any progress longer than stallTimeout is reported as not progressing
+func (c *Consumer) Health(stallTimeout time.Duration) ConsumerHealth {
+	if stallTimeout <= 0 {
+		stallTimeout = DefaultStallTimeout
+	}
+
+	now := time.Now()
+	h := ConsumerHealth{
+		Running: c.isRunning(),
+		Pools:   make([]PoolHealth, 0, len(c.listenerPools)),
+	}
+
+	for _, pool := range c.listenerPools {
+		h.Pools = append(h.Pools, pool.state.health(now, stallTimeout))
+	}
+
+	return h
+}
+
+// HealthHandler return http handler reporting consumer health,
+// respond with 503 status code when the consumer is not healthy so it can be used as readiness check
+func (c *Consumer) HealthHandler(stallTimeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := c.Health(stallTimeout)
+
+		status := http.StatusOK
+		if !h.Healthy() {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(h)
+	})
+}
@@ -0,0 +1,168 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failedCommitMessage struct {
+	*testConsumeMessage
+}
+
+func (f *failedCommitMessage) Commit(ctx context.Context) error {
+	return errors.New("commit failed")
+}
+
+func getHealth(t *testing.T, handler http.Handler) (int, ConsumerHealth) {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	var h ConsumerHealth
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&h))
+	return rec.Code, h
+}
+
+func TestConsumerHealthHandler(t *testing.T) {
+	conf := &ConsumerConfig{
+		Listener: &DriverConfig{Type: "TestConsumerHealthHandler"},
+	}
+
+	testListener := newTestListener()
+	RegisterListener("TestConsumerHealthHandler", testListener.factory)
+
+	ctx := context.Background()
+
+	consumer, err := NewConsumer(ctx, conf)
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	err = consumer.Subscribe(ctx, "test", "test", func(_ context.Context, _ *EventConsumeMessage) error {
+		<-release
+		return nil
+	})
+	require.NoError(t, err)
+
+	handler := consumer.HealthHandler(50 * time.Millisecond)
+
+	code, h := getHealth(t, handler)
+	assert.Equal(t, http.StatusServiceUnavailable, code, "consumer not started")
+	assert.False(t, h.Running)
+
+	require.NoError(t, consumer.Start())
+
+	code, h = getHealth(t, handler)
+	assert.Equal(t, http.StatusOK, code)
+	require.Len(t, h.Pools, 1)
+	assert.True(t, h.Pools[0].Alive)
+	assert.True(t, h.Pools[0].Progressing)
+
+	testListener.sendMessage(newTestConsumeMessage(&EventConsumeMessage{}))
+	time.Sleep(100 * time.Millisecond)
+
+	code, h = getHealth(t, handler)
+	assert.Equal(t, http.StatusServiceUnavailable, code, "handler stalled")
+	assert.Equal(t, int64(1), h.Pools[0].Inflight)
+	assert.False(t, h.Pools[0].Progressing)
+
+	close(release)
+	time.Sleep(50 * time.Millisecond)
+
+	code, h = getHealth(t, handler)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(0), h.Pools[0].Inflight)
+	assert.Equal(t, uint64(1), h.Pools[0].Processed)
+
+	require.NoError(t, consumer.Stop())
+	assert.False(t, consumer.Health(0).Healthy())
+}
+
+func TestConsumerHealthFailure(t *testing.T) {
+	conf := &ConsumerConfig{
+		Listener: &DriverConfig{Type: "TestConsumerHealthFailure"},
+	}
+
+	testListener := newTestListener()
+	RegisterListener("TestConsumerHealthFailure", testListener.factory)
+
+	ctx := context.Background()
+
+	consumer, err := NewConsumer(ctx, conf)
+	require.NoError(t, err)
+
+	err = consumer.Subscribe(ctx, "test", "test", func(_ context.Context, message *EventConsumeMessage) error {
+		if message.Key == "failed" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, consumer.Start())
+
+	testListener.sendMessage(newTestConsumeMessage(&EventConsumeMessage{Key: "failed"}))
+	time.Sleep(50 * time.Millisecond)
+	testListener.sendMessage(&failedCommitMessage{newTestConsumeMessage(&EventConsumeMessage{})})
+	time.Sleep(50 * time.Millisecond)
+
+	h := consumer.Health(0)
+	require.NoError(t, consumer.Stop())
+
+	require.Len(t, h.Pools, 1)
+	assert.Equal(t, uint64(2), h.Pools[0].Failed)
+	assert.Equal(t, uint64(0), h.Pools[0].Processed)
+	assert.Contains(t, h.Pools[0].LastError, ErrCommitFailed.Error())
+}
+
+func TestConsumerHealthKeyedCommitFailure(t *testing.T) {
+	conf := &ConsumerConfig{
+		Listener: &DriverConfig{Type: "TestConsumerHealthKeyedCommitFailure"},
+		WorkerPoolConfig: &WorkerPoolConfig{
+			"test": map[string]interface{}{
+				"default": map[string]interface{}{"workers": 2, "keyed": true},
+			},
+		},
+	}
+
+	testListener := newTestListener()
+	RegisterListener("TestConsumerHealthKeyedCommitFailure", testListener.factory)
+
+	ctx := context.Background()
+
+	consumer, err := NewConsumer(ctx, conf)
+	require.NoError(t, err)
+
+	err = consumer.Subscribe(ctx, "test", "test", func(_ context.Context, _ *EventConsumeMessage) error {
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, consumer.Start())
+
+	testListener.sendMessage(&failedCommitMessage{newTestConsumeMessage(&EventConsumeMessage{Topic: "test", Key: "key-1"})})
+	time.Sleep(50 * time.Millisecond)
+
+	h := consumer.Health(0)
+	require.NoError(t, consumer.Stop())
+
+	require.Len(t, h.Pools, 1)
+	assert.Contains(t, h.Pools[0].LastError, "commit failed")
+	assert.Contains(t, h.Pools[0].LastError, "key key-1")
+}
+
+func TestPoolStateFetchFailure(t *testing.T) {
+	state := newPoolState("test", "test")
+	state.setAlive(true)
+
+	state.fetched(errors.New("broker unavailable"))
+	assert.True(t, state.health(time.Now(), time.Minute).Healthy())
+	assert.False(t, state.health(time.Now().Add(2*time.Minute), time.Minute).Healthy())
+
+	state.fetched(nil)
+	assert.True(t, state.health(time.Now().Add(2*time.Minute), time.Minute).Healthy())
+}
@@ -0,0 +1,427 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/diki-haryadi/govega/cache"
+	"github.com/diki-haryadi/govega/constant"
+	"github.com/diki-haryadi/govega/docstore"
+	"github.com/diki-haryadi/govega/log"
+	"github.com/diki-haryadi/govega/monitor"
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultIdempotencyTTL      = 24 * time.Hour
+	DefaultIdempotencyLockTTL  = 30 * time.Second
+	DefaultIdempotencyWait     = 5 * time.Second
+	defaultIdempotencyInterval = 100 * time.Millisecond
+
+	idempotencyProcessing = "processing"
+	idempotencyProcessed  = "processed"
+)
+
+// ErrDuplicateInflight returned when the same message is still being processed by other worker
+var ErrDuplicateInflight = errors.New("[event/idempotency] duplicate message is being processed")
+
+// ErrIdempotencyLockLost returned when the expired lock is taken over by other worker before it is released
+var ErrIdempotencyLockLost = errors.New("[event/idempotency] lock is taken over by other worker")
+
+// idempotencyReleased expiration of released docstore lock record
+var idempotencyReleased = time.Unix(0, 0).UTC()
+
+type (
+	// IdempotencyStore keep track of processed message identities
+	IdempotencyStore interface {
+		// Lock mark the key as being processed until ttl, return false when the key is locked or processed.
+		// The returned token identify the lock owner on Unlock and MarkProcessed
+		Lock(ctx context.Context, key string, ttl time.Duration) (string, bool, error)
+		// Unlock release the lock of the key held by the token without marking it as processed
+		Unlock(ctx context.Context, key, token string) error
+		// MarkProcessed record the key locked by the token as processed until ttl and release the lock
+		MarkProcessed(ctx context.Context, key, token string, ttl time.Duration) error
+		// IsProcessed return true if the key is already processed
+		IsProcessed(ctx context.Context, key string) (bool, error)
+	}
+
+	// IdempotencyKeyFunc return identity of the message, empty key skip the idempotency check
+	IdempotencyKeyFunc func(ctx context.Context, message *EventConsumeMessage) string
+
+	// IdempotencyOption idempotent consumer option
+	IdempotencyOption struct {
+		// Key message identity, default to the message hash
+		Key IdempotencyKeyFunc
+		// TTL how long the processed message is remembered, default 24h
+		TTL time.Duration
+		// LockTTL how long the message is locked while being processed, default 30s.
+		// It should be longer than the handler duration
+		LockTTL time.Duration
+		// Wait how long to wait for in-flight duplicate to complete before returning ErrDuplicateInflight, default 5s
+		Wait time.Duration
+	}
+
+	// CacheIdempotencyStore idempotency store backed by cache,
+	// lock is atomic when the cache support Increment. Lock owner is not checked, the lock is only released by ttl
+	// once expired
+	CacheIdempotencyStore struct {
+		cache  cache.Cache
+		prefix string
+		lock   sync.Mutex
+	}
+
+	// DocstoreIdempotencyStore idempotency store backed by docstore driver,
+	// driver ID field should be "id" and expired records are not removed from the storage.
+	// Taking over expired record and checking the lock owner require driver implementing docstore.Conditional
+	DocstoreIdempotencyStore struct {
+		driver docstore.Driver
+		prefix string
+	}
+
+	idempotencyRecord struct {
+		ID        string    `json:"id"`
+		Status    string    `json:"status"`
+		Owner     string    `json:"owner"`
+		ExpiredAt time.Time `json:"expired_at"`
+	}
+)
+
+// MessageHashKey return the hash metadata set by the emitter
+func MessageHashKey(_ context.Context, message *EventConsumeMessage) string {
+	if h, ok := message.Metadata[MetaHash].(string); ok {
+		return h
+	}
+	return ""
+}
+
+func (o *IdempotencyOption) init() {
+	if o.Key == nil {
+		o.Key = MessageHashKey
+	}
+
+	if o.TTL <= 0 {
+		o.TTL = DefaultIdempotencyTTL
+	}
+
+	if o.LockTTL <= 0 {
+		o.LockTTL = DefaultIdempotencyLockTTL
+	}
+
+	if o.Wait <= 0 {
+		o.Wait = DefaultIdempotencyWait
+	}
+}
+
+// IdempotentMiddleware skip message which is already processed by the consumer group,
+// the message identity is recorded only when the handler succeed so failed message can be retried.
+// Duplicate being processed concurrently is waited until it is completed
+func IdempotentMiddleware(store IdempotencyStore, opt *IdempotencyOption) EventMiddleware {
+	o := IdempotencyOption{}
+	if opt != nil {
+		o = *opt
+	}
+	o.init()
+
+	return func(next EventHandler) EventHandler {
+		return func(ctx context.Context, message *EventConsumeMessage) error {
+			id := o.Key(ctx, message)
+			if id == "" {
+				return next(ctx, message)
+			}
+
+			group := GetConsumerGroupFromContext(ctx)
+			key := fmt.Sprintf("%s:%s:%s", group, message.Topic, id)
+
+			token, locked, err := acquireIdempotency(ctx, store, key, &o)
+			if err != nil {
+				if errors.Is(err, errDuplicate) {
+					monitor.FeedConsumerDuplicateMetrics(message.Topic, group)
+					log.WithContext(ctx).WithField("key", key).
+						Debugln("[event/idempotency] skip duplicate message")
+					return nil
+				}
+				return err
+			}
+
+			if !locked {
+				monitor.FeedConsumerDuplicateMetrics(message.Topic, group)
+				return ErrDuplicateInflight
+			}
+
+			if err := next(ctx, message); err != nil {
+				if uerr := store.Unlock(context.WithoutCancel(ctx), key, token); uerr != nil {
+					log.WithContext(ctx).WithError(uerr).
+						Errorln("[event/idempotency] failed to unlock message")
+				}
+				return err
+			}
+
+			return store.MarkProcessed(context.WithoutCancel(ctx), key, token, o.TTL)
+		}
+	}
+}
+
+var errDuplicate = errors.New("duplicate")
+
+// acquireIdempotency lock the key, waiting for in-flight duplicate to be completed,
+// return errDuplicate when the key is processed
+func acquireIdempotency(ctx context.Context, store IdempotencyStore, key string, o *IdempotencyOption) (string, bool, error) {
+	deadline := time.Now().Add(o.Wait)
+
+	for {
+		processed, err := store.IsProcessed(ctx, key)
+		if err != nil {
+			return "", false, err
+		}
+
+		if processed {
+			return "", false, errDuplicate
+		}
+
+		token, locked, err := store.Lock(ctx, key, o.LockTTL)
+		if err != nil {
+			return "", false, err
+		}
+
+		if locked {
+			// the duplicate may complete between the check and the lock
+			if processed, err := store.IsProcessed(ctx, key); err != nil || processed {
+				if uerr := store.Unlock(ctx, key, token); uerr != nil {
+					log.WithContext(ctx).WithError(uerr).
+						Errorln("[event/idempotency] failed to unlock message")
+				}
+				if err != nil {
+					return "", false, err
+				}
+				return "", false, errDuplicate
+			}
+			return token, true, nil
+		}
+
+		if time.Now().After(deadline) {
+			return "", false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", false, ctx.Err()
+		case <-time.After(defaultIdempotencyInterval):
+		}
+	}
+}
+
+// NewCacheIdempotencyStore create idempotency store using cache with key prefix
+func NewCacheIdempotencyStore(c cache.Cache, prefix string) *CacheIdempotencyStore {
+	return &CacheIdempotencyStore{
+		cache:  c,
+		prefix: prefix,
+	}
+}
+
+func (s *CacheIdempotencyStore) lockKey(key string) string {
+	return fmt.Sprintf("%s%s:%s", s.prefix, idempotencyProcessing, key)
+}
+
+func (s *CacheIdempotencyStore) processedKey(key string) string {
+	return fmt.Sprintf("%s%s:%s", s.prefix, idempotencyProcessed, key)
+}
+
+func (s *CacheIdempotencyStore) Lock(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	lk := s.lockKey(key)
+
+	n, err := s.cache.Increment(ctx, lk, expirationSeconds(ttl))
+	if err == nil {
+		return "", n == 1, nil
+	}
+
+	if !errors.Is(err, cache.NotSupported) {
+		return "", false, err
+	}
+
+	// cache without increment is expected to be local, e.g. memory cache
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.cache.Exist(ctx, lk) {
+		return "", false, nil
+	}
+
+	return "", true, s.cache.Set(ctx, lk, 1, expirationSeconds(ttl))
+}
+
+func (s *CacheIdempotencyStore) Unlock(ctx context.Context, key, _ string) error {
+	return s.cache.Delete(ctx, s.lockKey(key))
+}
+
+func (s *CacheIdempotencyStore) MarkProcessed(ctx context.Context, key, token string, ttl time.Duration) error {
+	if err := s.cache.Set(ctx, s.processedKey(key), 1, expirationSeconds(ttl)); err != nil {
+		return err
+	}
+
+	return s.Unlock(ctx, key, token)
+}
+
+func (s *CacheIdempotencyStore) IsProcessed(ctx context.Context, key string) (bool, error) {
+	return s.cache.Exist(ctx, s.processedKey(key)), nil
+}
+
+// expirationSeconds convert ttl to cache expiration, rounded up to a second
+func expirationSeconds(ttl time.Duration) int {
+	sec := int((ttl + time.Second - 1) / time.Second)
+	if sec < 1 {
+		return 1
+	}
+	return sec
+}
+
+// NewDocstoreIdempotencyStore create idempotency store using docstore driver with ID prefix
+func NewDocstoreIdempotencyStore(driver docstore.Driver, prefix string) *DocstoreIdempotencyStore {
+	return &DocstoreIdempotencyStore{
+		driver: driver,
+		prefix: prefix,
+	}
+}
+
+// record return the stored record including expired one, nil when not found
+func (s *DocstoreIdempotencyStore) record(ctx context.Context, id string) (*idempotencyRecord, error) {
+	var rec idempotencyRecord
+	if err := s.driver.Get(ctx, id, &rec); err != nil {
+		if errors.Is(err, docstore.NotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &rec, nil
+}
+
+func (s *DocstoreIdempotencyStore) get(ctx context.Context, id string) (*idempotencyRecord, error) {
+	rec, err := s.record(ctx, id)
+	if err != nil || rec == nil {
+		return nil, err
+	}
+
+	if time.Now().After(rec.ExpiredAt) {
+		return nil, nil
+	}
+
+	return rec, nil
+}
+
+// Lock create the lock record, expired record is taken over by compare-and-swap on its expiration
+func (s *DocstoreIdempotencyStore) Lock(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	id := s.prefix + key
+	rec := &idempotencyRecord{
+		ID:        id,
+		Status:    idempotencyProcessing,
+		Owner:     uuid.NewString(),
+		ExpiredAt: time.Now().Add(ttl),
+	}
+
+	if err := s.driver.Create(ctx, rec); err == nil {
+		return rec.Owner, true, nil
+	}
+
+	cur, err := s.record(ctx, id)
+	if err != nil {
+		return "", false, err
+	}
+
+	if cur == nil {
+		// record is released in between, other worker may create it first
+		if err := s.driver.Create(ctx, rec); err != nil {
+			return "", false, nil
+		}
+		return rec.Owner, true, nil
+	}
+
+	if !time.Now().After(cur.ExpiredAt) {
+		return "", false, nil
+	}
+
+	cas, ok := s.driver.(docstore.Conditional)
+	if !ok {
+		return "", false, errors.New("[event/idempotency] expired lock can't be acquired, driver doesn't support conditional update")
+	}
+
+	// only the worker observing the same expiration is able to take over the record
+	err = cas.UpdateFieldIf(ctx, id,
+		[]docstore.FilterOpt{{Field: "expired_at", Ops: constant.EQ, Value: cur.ExpiredAt}},
+		[]docstore.Field{
+			{Name: "status", Value: rec.Status},
+			{Name: "owner", Value: rec.Owner},
+			{Name: "expired_at", Value: rec.ExpiredAt},
+		})
+	if errors.Is(err, docstore.NotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	return rec.Owner, true, nil
+}
+
+// Unlock expire the record locked by the token, processed record is never released.
+// Record is deleted when the driver doesn't support conditional update, the lock can't be taken over in that case
+func (s *DocstoreIdempotencyStore) Unlock(ctx context.Context, key, token string) error {
+	id := s.prefix + key
+
+	cas, ok := s.driver.(docstore.Conditional)
+	if !ok {
+		rec, err := s.record(ctx, id)
+		if err != nil || rec == nil || rec.Status == idempotencyProcessed {
+			return err
+		}
+		return s.driver.Delete(ctx, id)
+	}
+
+	err := cas.UpdateFieldIf(ctx, id, lockedBy(token), []docstore.Field{
+		{Name: "expired_at", Value: idempotencyReleased},
+	})
+	if errors.Is(err, docstore.NotFound) {
+		return ErrIdempotencyLockLost
+	}
+	return err
+}
+
+// MarkProcessed mark the record locked by the token as processed,
+// ErrIdempotencyLockLost is returned when the lock is taken over by other worker
+func (s *DocstoreIdempotencyStore) MarkProcessed(ctx context.Context, key, token string, ttl time.Duration) error {
+	id := s.prefix + key
+	fields := []docstore.Field{
+		{Name: "status", Value: idempotencyProcessed},
+		{Name: "expired_at", Value: time.Now().Add(ttl)},
+	}
+
+	cas, ok := s.driver.(docstore.Conditional)
+	if !ok {
+		return s.driver.UpdateField(ctx, id, fields)
+	}
+
+	err := cas.UpdateFieldIf(ctx, id, lockedBy(token), fields)
+	if errors.Is(err, docstore.NotFound) {
+		return ErrIdempotencyLockLost
+	}
+	return err
+}
+
+// lockedBy filter record being processed by the lock owner
+func lockedBy(token string) []docstore.FilterOpt {
+	return []docstore.FilterOpt{
+		{Field: "owner", Ops: constant.EQ, Value: token},
+		{Field: "status", Ops: constant.EQ, Value: idempotencyProcessing},
+	}
+}
+
+func (s *DocstoreIdempotencyStore) IsProcessed(ctx context.Context, key string) (bool, error) {
+	rec, err := s.get(ctx, s.prefix+key)
+	if err != nil {
+		return false, err
+	}
+
+	return rec != nil && rec.Status == idempotencyProcessed, nil
+}
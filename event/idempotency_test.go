@@ -0,0 +1,256 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/diki-haryadi/govega/cache/mem"
+	"github.com/diki-haryadi/govega/cache/redis"
+	"github.com/diki-haryadi/govega/docstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testIdempotentMiddleware(t *testing.T, store IdempotencyStore) {
+	var called int32
+	fail := true
+	handler := IdempotentMiddleware(store, &IdempotencyOption{Wait: time.Second})(
+		func(ctx context.Context, message *EventConsumeMessage) error {
+			atomic.AddInt32(&called, 1)
+			if message.Key == "failed" && fail {
+				return errors.New("failed")
+			}
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		})
+
+	ctx := context.WithValue(context.Background(), consumerGroupKey, "group")
+	msg := func(hash, key string) *EventConsumeMessage {
+		return &EventConsumeMessage{
+			Topic:    "test",
+			Key:      key,
+			Metadata: map[string]interface{}{MetaHash: hash},
+		}
+	}
+
+	require.NoError(t, handler(ctx, msg("hash-1", "")))
+	require.NoError(t, handler(ctx, msg("hash-1", "")))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&called), "duplicate skipped")
+
+	other := context.WithValue(context.Background(), consumerGroupKey, "other")
+	require.NoError(t, handler(other, msg("hash-1", "")))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&called), "processed by other group")
+
+	require.Error(t, handler(ctx, msg("hash-2", "failed")))
+	fail = false
+	require.NoError(t, handler(ctx, msg("hash-2", "failed")))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&called), "failed message is retried")
+
+	require.NoError(t, handler(ctx, &EventConsumeMessage{Topic: "test"}))
+	require.NoError(t, handler(ctx, &EventConsumeMessage{Topic: "test"}))
+	assert.Equal(t, int32(6), atomic.LoadInt32(&called), "message without hash is not checked")
+
+	atomic.StoreInt32(&called, 0)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, handler(ctx, msg("hash-3", "")))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&called), "concurrent duplicate")
+}
+
+func TestIdempotentMiddlewareCache(t *testing.T) {
+	testIdempotentMiddleware(t, NewCacheIdempotencyStore(mem.NewMemoryCache(), "idempotency:"))
+}
+
+func TestIdempotentMiddlewareRedis(t *testing.T) {
+	s := miniredis.RunT(t)
+	c, err := redis.NewRedisCache("ns:", redis.DefaultOption(s.Addr(), ""))
+	require.NoError(t, err)
+
+	testIdempotentMiddleware(t, NewCacheIdempotencyStore(c, "idempotency:"))
+}
+
+func TestCacheIdempotencyStoreRedis(t *testing.T) {
+	s := miniredis.RunT(t)
+	c, err := redis.NewRedisCache("ns:", redis.DefaultOption(s.Addr(), ""))
+	require.NoError(t, err)
+
+	store := NewCacheIdempotencyStore(c, "idem:")
+	ctx := context.Background()
+
+	_, ok, err := store.Lock(ctx, "key", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, ok, err = store.Lock(ctx, "key", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, store.Unlock(ctx, "key", ""))
+	assert.False(t, s.Exists("ns:idem:processing:key"), "lock is released")
+
+	_, ok, err = store.Lock(ctx, "key", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok, "lock is acquired again after unlock")
+
+	require.NoError(t, store.MarkProcessed(ctx, "key", "", time.Minute))
+	assert.False(t, s.Exists("ns:idem:processing:key"), "lock is released once processed")
+
+	processed, err := store.IsProcessed(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, processed)
+}
+
+func TestIdempotentMiddlewareDocstore(t *testing.T) {
+	testIdempotentMiddleware(t, NewDocstoreIdempotencyStore(docstore.NewMemoryStore("idempotency", "id"), ""))
+}
+
+func TestIdempotentMiddlewareInflight(t *testing.T) {
+	store := NewCacheIdempotencyStore(mem.NewMemoryCache(), "")
+	release := make(chan struct{})
+	handler := IdempotentMiddleware(store, &IdempotencyOption{Wait: 100 * time.Millisecond})(
+		func(ctx context.Context, message *EventConsumeMessage) error {
+			<-release
+			return nil
+		})
+
+	ctx := context.Background()
+	message := &EventConsumeMessage{Metadata: map[string]interface{}{MetaHash: "hash"}}
+
+	done := make(chan error)
+	go func() {
+		done <- handler(ctx, message)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	assert.ErrorIs(t, handler(ctx, message), ErrDuplicateInflight)
+
+	close(release)
+	require.NoError(t, <-done)
+}
+
+func TestDocstoreIdempotencyStoreExpired(t *testing.T) {
+	store := NewDocstoreIdempotencyStore(docstore.NewMemoryStore("idempotency", "id"), "")
+	ctx := context.Background()
+
+	_, ok, err := store.Lock(ctx, "key", time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, ok, err = store.Lock(ctx, "key", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	time.Sleep(5 * time.Millisecond)
+	token, ok, err := store.Lock(ctx, "key", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok, "expired lock is acquired")
+
+	require.NoError(t, store.MarkProcessed(ctx, "key", token, time.Millisecond))
+	processed, err := store.IsProcessed(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, processed)
+
+	time.Sleep(5 * time.Millisecond)
+	processed, err = store.IsProcessed(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, processed)
+}
+
+func TestDocstoreIdempotencyStoreOwner(t *testing.T) {
+	store := NewDocstoreIdempotencyStore(docstore.NewMemoryStore("idempotency", "id"), "")
+	ctx := context.Background()
+
+	tokenA, ok, err := store.Lock(ctx, "key", time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	time.Sleep(5 * time.Millisecond)
+	tokenB, ok, err := store.Lock(ctx, "key", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok, "expired lock is taken over")
+
+	assert.ErrorIs(t, store.MarkProcessed(ctx, "key", tokenA, time.Minute), ErrIdempotencyLockLost)
+	processed, err := store.IsProcessed(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, processed, "previous owner can't mark the record")
+
+	require.NoError(t, store.MarkProcessed(ctx, "key", tokenB, time.Minute))
+	assert.ErrorIs(t, store.Unlock(ctx, "key", tokenA), ErrIdempotencyLockLost)
+	assert.ErrorIs(t, store.Unlock(ctx, "key", tokenB), ErrIdempotencyLockLost)
+
+	processed, err = store.IsProcessed(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, processed, "processed record is never released")
+
+	token, ok, err := store.Lock(ctx, "released", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, store.Unlock(ctx, "released", token))
+
+	_, ok, err = store.Lock(ctx, "released", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok, "released lock is acquired again")
+}
+
+func TestDocstoreIdempotencyStoreExpiredConcurrent(t *testing.T) {
+	store := NewDocstoreIdempotencyStore(docstore.NewMemoryStore("idempotency", "id"), "")
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		_, ok, err := store.Lock(ctx, key, time.Millisecond)
+		require.NoError(t, err)
+		require.True(t, ok)
+		time.Sleep(2 * time.Millisecond)
+
+		var (
+			wg       sync.WaitGroup
+			acquired int32
+		)
+		for w := 0; w < 10; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, ok, err := store.Lock(ctx, key, time.Minute)
+				assert.NoError(t, err)
+				if ok {
+					atomic.AddInt32(&acquired, 1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), acquired, "expired lock is acquired by one worker")
+	}
+}
+
+// noConditionalDriver hide the conditional update of the wrapped driver
+type noConditionalDriver struct {
+	docstore.Driver
+}
+
+func TestDocstoreIdempotencyStoreExpiredUnsupported(t *testing.T) {
+	store := NewDocstoreIdempotencyStore(noConditionalDriver{docstore.NewMemoryStore("idempotency", "id")}, "")
+	ctx := context.Background()
+
+	_, ok, err := store.Lock(ctx, "key", time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	time.Sleep(5 * time.Millisecond)
+	_, ok, err = store.Lock(ctx, "key", time.Minute)
+	assert.Error(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,510 @@
+package inmem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/diki-haryadi/govega/event"
+	"github.com/mitchellh/mapstructure"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const DefaultBroker = "default"
+
+var (
+	ErrIteratorClosed = errors.New("iterator closed")
+
+	brokers    = map[string]*Broker{}
+	brokerLock sync.Mutex
+)
+
+type (
+	// Broker in process message broker, topics are append only log
+	// and each consumer group keep its own committed offset
+	Broker struct {
+		lock   sync.Mutex
+		topics map[string]*topic
+	}
+
+	// Record message stored in broker
+	Record struct {
+		Offset  int64
+		Key     string
+		Value   []byte
+		Headers map[string]string
+		Time    time.Time
+	}
+
+	topic struct {
+		records []*Record
+		groups  map[string]*group
+		notify  chan struct{}
+	}
+
+	group struct {
+		// offset all records before offset are committed
+		offset    int64
+		next      int64
+		inflight  map[int64]time.Time
+		committed map[int64]bool
+		redeliver []int64
+	}
+
+	InmemSender struct {
+		// Broker name of the broker or *Broker, default: default
+		Broker     interface{} `json:"broker" mapstructure:"broker"`
+		broker     *Broker
+		propagator propagation.TextMapPropagator
+	}
+
+	InmemListener struct {
+		// Broker name of the broker or *Broker, default: default
+		Broker interface{} `json:"broker" mapstructure:"broker"`
+		// RedeliveryTimeout redeliver message which is not committed within the timeout,
+		// by default uncommitted message is only redelivered after the iterator is closed
+		RedeliveryTimeout string `json:"redelivery_timeout" mapstructure:"redelivery_timeout"`
+		broker            *Broker
+		timeout           time.Duration
+	}
+
+	InmemIterator struct {
+		broker    *Broker
+		topic     string
+		group     string
+		timeout   time.Duration
+		lock      sync.Mutex
+		delivered map[int64]bool
+		closed    bool
+	}
+
+	InmemConsumeMessage struct {
+		*Record
+		iterator *InmemIterator
+	}
+)
+
+func init() {
+	event.RegisterSender("inmem", NewInmemSender)
+	event.RegisterListener("inmem", NewInmemListener)
+}
+
+// GetBroker return broker with given name, broker is created if not exist
+func GetBroker(name string) *Broker {
+	brokerLock.Lock()
+	defer brokerLock.Unlock()
+
+	if b, ok := brokers[name]; ok {
+		return b
+	}
+
+	b := NewBroker()
+	brokers[name] = b
+	return b
+}
+
+// NewBroker create new isolated broker
+func NewBroker() *Broker {
+	return &Broker{
+		topics: make(map[string]*topic),
+	}
+}
+
+func getBroker(broker interface{}) (*Broker, error) {
+	switch b := broker.(type) {
+	case nil:
+		return GetBroker(DefaultBroker), nil
+	case string:
+		if b == "" {
+			return GetBroker(DefaultBroker), nil
+		}
+		return GetBroker(b), nil
+	case *Broker:
+		return b, nil
+	default:
+		return nil, errors.New("[event/inmem] unsupported broker type")
+	}
+}
+
+func NewInmemSender(_ context.Context, config interface{}) (event.Sender, error) {
+	var sender InmemSender
+	if err := mapstructure.Decode(config, &sender); err != nil {
+		return nil, err
+	}
+
+	broker, err := getBroker(sender.Broker)
+	if err != nil {
+		return nil, err
+	}
+
+	sender.broker = broker
+	sender.propagator = otel.GetTextMapPropagator()
+	return &sender, nil
+}
+
+func NewInmemListener(_ context.Context, config interface{}) (event.Listener, error) {
+	var listener InmemListener
+	if err := mapstructure.Decode(config, &listener); err != nil {
+		return nil, err
+	}
+
+	broker, err := getBroker(listener.Broker)
+	if err != nil {
+		return nil, err
+	}
+
+	if listener.RedeliveryTimeout != "" {
+		timeout, err := time.ParseDuration(listener.RedeliveryTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid inmem listener redelivery timeout value: %w", err)
+		}
+
+		listener.timeout = timeout
+	}
+
+	listener.broker = broker
+	return &listener, nil
+}
+
+func (s *InmemSender) Send(ctx context.Context, message *event.EventMessage) error {
+	mb, err := message.ToBytes()
+	if err != nil {
+		return err
+	}
+
+	record := &Record{
+		Key:     message.Key,
+		Value:   mb,
+		Headers: make(map[string]string),
+		Time:    time.Now(),
+	}
+	s.propagator.Inject(ctx, propagation.MapCarrier(record.Headers))
+
+	s.broker.publish(message.Topic, record)
+	return nil
+}
+
+func (l *InmemListener) Listen(_ context.Context, topic, group string) (event.Iterator, error) {
+	l.broker.join(topic, group)
+
+	return &InmemIterator{
+		broker:    l.broker,
+		topic:     topic,
+		group:     group,
+		timeout:   l.timeout,
+		delivered: make(map[int64]bool),
+	}, nil
+}
+
+// Next wait for the next message available for the group
+func (it *InmemIterator) Next(ctx context.Context) (event.ConsumeMessage, error) {
+	for {
+		// hold the lock across the claim so Close can't miss a message claimed concurrently
+		it.lock.Lock()
+		if it.closed {
+			it.lock.Unlock()
+			return nil, ErrIteratorClosed
+		}
+
+		record, notify, redeliverIn := it.broker.claim(it.topic, it.group, it.timeout)
+		if record != nil {
+			it.delivered[record.Offset] = true
+		}
+		it.lock.Unlock()
+
+		if record != nil {
+			return &InmemConsumeMessage{Record: record.clone(), iterator: it}, nil
+		}
+
+		if err := wait(ctx, notify, redeliverIn); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func wait(ctx context.Context, notify <-chan struct{}, timeout time.Duration) error {
+	var timer <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-notify:
+	case <-timer:
+	}
+
+	return nil
+}
+
+// Close return uncommitted messages delivered by this iterator to the group
+func (it *InmemIterator) Close() error {
+	it.lock.Lock()
+	defer it.lock.Unlock()
+
+	if it.closed {
+		return nil
+	}
+	it.closed = true
+
+	offsets := make([]int64, 0, len(it.delivered))
+	for offset := range it.delivered {
+		offsets = append(offsets, offset)
+	}
+
+	it.broker.release(it.topic, it.group, offsets)
+	return nil
+}
+
+func (m *InmemConsumeMessage) GetEventConsumeMessage(ctx context.Context) (*event.EventConsumeMessage, error) {
+	em, err := event.NewEventConsumeMessage(m.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode message: %w", err)
+	}
+
+	em.Topic = m.iterator.topic
+	em.Key = m.Key
+	return em, nil
+}
+
+func (m *InmemConsumeMessage) Commit(ctx context.Context) error {
+	m.iterator.lock.Lock()
+	delete(m.iterator.delivered, m.Offset)
+	m.iterator.lock.Unlock()
+
+	m.iterator.broker.commit(m.iterator.topic, m.iterator.group, m.Offset)
+	return nil
+}
+
+// Get retrieves a single value for a given key.
+func (m *InmemConsumeMessage) Get(key string) string {
+	return m.Headers[key]
+}
+
+// Set sets a header.
+func (m *InmemConsumeMessage) Set(key, val string) {
+	m.Headers[key] = val
+}
+
+// Keys returns a slice of all key identifiers in the carrier.
+func (m *InmemConsumeMessage) Keys() []string {
+	out := make([]string, 0, len(m.Headers))
+	for k := range m.Headers {
+		out = append(out, k)
+	}
+	return out
+}
+
+// clone copy record so headers can be modified by consumer
+func (r *Record) clone() *Record {
+	c := *r
+	c.Headers = make(map[string]string, len(r.Headers))
+	for k, v := range r.Headers {
+		c.Headers[k] = v
+	}
+	return &c
+}
+
+func (b *Broker) getTopic(name string) *topic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &topic{
+			records: make([]*Record, 0),
+			groups:  make(map[string]*group),
+			notify:  make(chan struct{}),
+		}
+		b.topics[name] = t
+	}
+	return t
+}
+
+func (t *topic) getGroup(name string) *group {
+	g, ok := t.groups[name]
+	if !ok {
+		g = &group{
+			inflight:  make(map[int64]time.Time),
+			committed: make(map[int64]bool),
+		}
+		t.groups[name] = g
+	}
+	return g
+}
+
+// wakeup notify all waiting iterators
+func (t *topic) wakeup() {
+	close(t.notify)
+	t.notify = make(chan struct{})
+}
+
+func (b *Broker) publish(name string, record *Record) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	t := b.getTopic(name)
+	record.Offset = int64(len(t.records))
+	t.records = append(t.records, record)
+	t.wakeup()
+}
+
+func (b *Broker) join(name, groupName string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.getTopic(name).getGroup(groupName)
+}
+
+// claim return next record for the group, if there is no record available
+// it return channel to wait for new record and duration until the earliest redelivery
+func (b *Broker) claim(name, groupName string, timeout time.Duration) (*Record, <-chan struct{}, time.Duration) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	t := b.getTopic(name)
+	g := t.getGroup(groupName)
+	now := time.Now()
+
+	offset := int64(-1)
+	for offset < 0 && len(g.redeliver) > 0 {
+		// skip message committed after released
+		if o := g.redeliver[0]; o >= g.offset && !g.committed[o] {
+			offset = o
+		}
+		g.redeliver = g.redeliver[1:]
+	}
+
+	var wait time.Duration
+	if offset < 0 && timeout > 0 {
+		for o, deliveredAt := range g.inflight {
+			remaining := deliveredAt.Add(timeout).Sub(now)
+			if remaining <= 0 && (offset < 0 || o < offset) {
+				offset = o
+			} else if remaining > 0 && (wait == 0 || remaining < wait) {
+				wait = remaining
+			}
+		}
+	}
+
+	if offset < 0 && g.next < int64(len(t.records)) {
+		offset = g.next
+		g.next++
+	}
+
+	if offset < 0 {
+		return nil, t.notify, wait
+	}
+
+	g.inflight[offset] = now
+	return t.records[offset], nil, 0
+}
+
+func (b *Broker) commit(name, groupName string, offset int64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	g := b.getTopic(name).getGroup(groupName)
+	if offset < g.offset {
+		return
+	}
+
+	delete(g.inflight, offset)
+	g.committed[offset] = true
+
+	for g.committed[g.offset] {
+		delete(g.committed, g.offset)
+		g.offset++
+	}
+}
+
+func (b *Broker) release(name, groupName string, offsets []int64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	t := b.getTopic(name)
+	g := t.getGroup(groupName)
+
+	for _, offset := range offsets {
+		if _, ok := g.inflight[offset]; !ok {
+			continue
+		}
+		delete(g.inflight, offset)
+		g.redeliver = append(g.redeliver, offset)
+	}
+
+	sort.Slice(g.redeliver, func(i, j int) bool { return g.redeliver[i] < g.redeliver[j] })
+	t.wakeup()
+}
+
+// Records return all records published to the topic
+func (b *Broker) Records(name string) []Record {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	t, ok := b.topics[name]
+	if !ok {
+		return []Record{}
+	}
+
+	out := make([]Record, len(t.records))
+	for i, r := range t.records {
+		out[i] = *r
+	}
+	return out
+}
+
+// Messages return all messages published to the topic
+func (b *Broker) Messages(name string) ([]*event.EventConsumeMessage, error) {
+	records := b.Records(name)
+
+	out := make([]*event.EventConsumeMessage, len(records))
+	for i, r := range records {
+		em, err := event.NewEventConsumeMessage(r.Value)
+		if err != nil {
+			return nil, err
+		}
+		em.Topic = name
+		em.Key = r.Key
+		out[i] = em
+	}
+	return out, nil
+}
+
+// CommittedOffset return offset of the group, all records before the offset are committed
+func (b *Broker) CommittedOffset(name, groupName string) int64 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	t, ok := b.topics[name]
+	if !ok {
+		return 0
+	}
+
+	if g, ok := t.groups[groupName]; ok {
+		return g.offset
+	}
+	return 0
+}
+
+// Pending return number of records not yet committed by the group
+func (b *Broker) Pending(name, groupName string) int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	t, ok := b.topics[name]
+	if !ok {
+		return 0
+	}
+
+	g, ok := t.groups[groupName]
+	if !ok {
+		return len(t.records)
+	}
+
+	return len(t.records) - int(g.offset) - len(g.committed)
+}
@@ -0,0 +1,193 @@
+package inmem
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/diki-haryadi/govega/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInmemEmitterConsumer(t *testing.T) {
+	ctx := context.Background()
+	broker := NewBroker()
+
+	em, err := event.New(ctx, &event.EmitterConfig{
+		Sender: &event.DriverConfig{
+			Type:   "inmem",
+			Config: map[string]interface{}{"broker": broker},
+		},
+	})
+	require.NoError(t, err)
+
+	consumer, err := event.NewConsumer(ctx, &event.ConsumerConfig{
+		Listener: &event.DriverConfig{
+			Type:   "inmem",
+			Config: map[string]interface{}{"broker": broker},
+		},
+	})
+	require.NoError(t, err)
+
+	var lock sync.Mutex
+	received := map[string][]string{}
+	handler := func(group string) event.EventHandler {
+		return func(ctx context.Context, message *event.EventConsumeMessage) error {
+			lock.Lock()
+			defer lock.Unlock()
+			received[group] = append(received[group], message.Key)
+			return nil
+		}
+	}
+
+	require.NoError(t, consumer.Subscribe(ctx, "test", "group1", handler("group1")))
+	require.NoError(t, consumer.Subscribe(ctx, "test", "group2", handler("group2")))
+	require.NoError(t, consumer.Start())
+
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, em.Publish(ctx, "test", key, "testdata", nil))
+	}
+
+	assert.Eventually(t, func() bool {
+		return broker.Pending("test", "group1") == 0 && broker.Pending("test", "group2") == 0
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, consumer.Stop())
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, received["group1"])
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, received["group2"])
+	assert.Equal(t, int64(3), broker.CommittedOffset("test", "group1"))
+
+	msgs, err := broker.Messages("test")
+	require.NoError(t, err)
+	require.Len(t, msgs, 3)
+	assert.Equal(t, "a", msgs[0].Key)
+	assert.JSONEq(t, `"testdata"`, string(msgs[0].Data))
+}
+
+func TestInmemGroupMembers(t *testing.T) {
+	ctx := context.Background()
+	broker := NewBroker()
+
+	listener, err := NewInmemListener(ctx, map[string]interface{}{"broker": broker})
+	require.NoError(t, err)
+
+	it1, err := listener.Listen(ctx, "test", "group")
+	require.NoError(t, err)
+	it2, err := listener.Listen(ctx, "test", "group")
+	require.NoError(t, err)
+
+	publish(t, broker, "a", "b")
+
+	m1, err := it1.Next(ctx)
+	require.NoError(t, err)
+	m2, err := it2.Next(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(0), m1.(*InmemConsumeMessage).Offset)
+	assert.Equal(t, int64(1), m2.(*InmemConsumeMessage).Offset)
+
+	// commit out of order, offset only move after all previous records are committed
+	require.NoError(t, m2.Commit(ctx))
+	assert.Equal(t, int64(0), broker.CommittedOffset("test", "group"))
+	assert.Equal(t, 1, broker.Pending("test", "group"))
+
+	require.NoError(t, m1.Commit(ctx))
+	assert.Equal(t, int64(2), broker.CommittedOffset("test", "group"))
+	assert.Equal(t, 0, broker.Pending("test", "group"))
+
+	tctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = it1.Next(tctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestInmemRedeliverOnClose(t *testing.T) {
+	ctx := context.Background()
+	broker := NewBroker()
+
+	listener, err := NewInmemListener(ctx, map[string]interface{}{"broker": broker})
+	require.NoError(t, err)
+
+	it1, err := listener.Listen(ctx, "test", "group")
+	require.NoError(t, err)
+
+	publish(t, broker, "a", "b")
+
+	m1, err := it1.Next(ctx)
+	require.NoError(t, err)
+	_, err = it1.Next(ctx)
+	require.NoError(t, err)
+	require.NoError(t, m1.Commit(ctx))
+
+	require.NoError(t, it1.(*InmemIterator).Close())
+	_, err = it1.Next(ctx)
+	assert.ErrorIs(t, err, ErrIteratorClosed)
+
+	it2, err := listener.Listen(ctx, "test", "group")
+	require.NoError(t, err)
+
+	m, err := it2.Next(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "b", m.(*InmemConsumeMessage).Key)
+}
+
+func TestInmemRedeliveryTimeout(t *testing.T) {
+	ctx := context.Background()
+	broker := NewBroker()
+
+	listener, err := NewInmemListener(ctx, map[string]interface{}{
+		"broker":             broker,
+		"redelivery_timeout": "50ms",
+	})
+	require.NoError(t, err)
+
+	it, err := listener.Listen(ctx, "test", "group")
+	require.NoError(t, err)
+
+	publish(t, broker, "a")
+
+	m, err := it.Next(ctx)
+	require.NoError(t, err)
+
+	start := time.Now()
+	tctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	redelivered, err := it.Next(tctx)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	assert.Equal(t, m.(*InmemConsumeMessage).Offset, redelivered.(*InmemConsumeMessage).Offset)
+
+	require.NoError(t, redelivered.Commit(ctx))
+	assert.Equal(t, 0, broker.Pending("test", "group"))
+}
+
+func TestInmemNamedBroker(t *testing.T) {
+	ctx := context.Background()
+
+	sender, err := NewInmemSender(ctx, map[string]interface{}{"broker": "TestInmemNamedBroker"})
+	require.NoError(t, err)
+
+	before := len(GetBroker("TestInmemNamedBroker").Records("test"))
+	require.NoError(t, sender.Send(ctx, &event.EventMessage{Topic: "test", Key: "a", Data: "testdata"}))
+	assert.Len(t, GetBroker("TestInmemNamedBroker").Records("test"), before+1)
+	assert.Len(t, GetBroker(DefaultBroker).Records("test"), 0)
+
+	_, err = NewInmemSender(ctx, map[string]interface{}{"broker": 1})
+	assert.Error(t, err)
+}
+
+func publish(t *testing.T, broker *Broker, keys ...string) {
+	sender, err := NewInmemSender(context.Background(), map[string]interface{}{"broker": broker})
+	require.NoError(t, err)
+
+	for _, key := range keys {
+		require.NoError(t, sender.Send(context.Background(),
+			&event.EventMessage{Topic: "test", Key: key, Data: "testdata"}))
+	}
+}
@@ -0,0 +1,197 @@
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/diki-haryadi/govega/event"
+	"github.com/mitchellh/mapstructure"
+)
+
+const recordExt = ".json"
+
+// fileName replace base64 characters not safe for file name
+var fileName = strings.NewReplacer("+", "-", "/", "_", "=", "")
+
+// JournalWriter local on disk outbox, each message is stored as a json file named by its
+// creation time so the messages are relayed in the order they are written
+type JournalWriter struct {
+	// Dir directory of the journal files, created if not exist
+	Dir  string `json:"dir" mapstructure:"dir"`
+	lock sync.Mutex
+	last int64
+}
+
+func init() {
+	event.RegisterWriter("journal", NewJournalWriter)
+}
+
+func NewJournalWriter(ctx context.Context, config interface{}) (event.Writer, error) {
+	return NewJournal(ctx, config)
+}
+
+func NewJournal(_ context.Context, config interface{}) (*JournalWriter, error) {
+	var jw JournalWriter
+	if err := mapstructure.Decode(config, &jw); err != nil {
+		return nil, err
+	}
+
+	if jw.Dir == "" {
+		return nil, errors.New("[event/journal] missing dir param")
+	}
+
+	if err := os.MkdirAll(jw.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("[event/journal] failed to create dir: %w", err)
+	}
+
+	return &jw, nil
+}
+
+// Send write the message to the journal, file is synced before it is visible to the relay
+func (j *JournalWriter) Send(ctx context.Context, message *event.EventMessage) error {
+	record, err := event.OutboxFromMessage(message)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	// keep the file names increasing for messages written within the same nanosecond
+	ts := record.CreatedAt.UnixNano()
+	if ts <= j.last {
+		ts = j.last + 1
+	}
+	j.last = ts
+
+	name := filepath.Join(j.Dir, fmt.Sprintf("%020d-%s%s", ts, fileName.Replace(record.ID), recordExt))
+
+	return writeFile(name, b)
+}
+
+// writeFile write the data to temporary file and rename it once synced
+func writeFile(name string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), name)
+}
+
+// Delete remove the journal files of the message
+func (j *JournalWriter) Delete(ctx context.Context, message *event.EventMessage) error {
+	record, err := event.OutboxFromMessage(message)
+	if err != nil {
+		return err
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	files, err := filepath.Glob(filepath.Join(j.Dir, "*-"+fileName.Replace(record.ID)+recordExt))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RelayOutbox pass the journal records to fn in the order they are written, the journal is locked
+// until all of the records are relayed so ClaimTimeout is not used
+func (j *JournalWriter) RelayOutbox(ctx context.Context, opt *event.RelayOption, fn event.RelayFunc) (int, error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	files, err := j.files()
+	if err != nil {
+		return 0, err
+	}
+
+	relayed := 0
+	claimed := 0
+	for _, file := range files {
+		if claimed >= opt.Limit {
+			break
+		}
+
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return relayed, err
+		}
+
+		var record event.OutboxRecord
+		if err := json.Unmarshal(b, &record); err != nil {
+			return relayed, fmt.Errorf("[event/journal] invalid record %s: %w", filepath.Base(file), err)
+		}
+
+		if !record.CreatedAt.Before(opt.CreatedBefore) {
+			break
+		}
+
+		claimed++
+		if err := fn(ctx, &record); err != nil {
+			continue
+		}
+
+		if err := os.Remove(file); err != nil {
+			return relayed, err
+		}
+		relayed++
+	}
+
+	return relayed, nil
+}
+
+// Len return number of messages in the journal
+func (j *JournalWriter) Len() (int, error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	files, err := j.files()
+	return len(files), err
+}
+
+// files return the journal files sorted by the writing order
+func (j *JournalWriter) files() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(j.Dir, "*"+recordExt))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
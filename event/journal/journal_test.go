@@ -0,0 +1,129 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/diki-haryadi/govega/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSender struct {
+	lock sync.Mutex
+	fail bool
+	keys []string
+}
+
+func (s *testSender) Send(ctx context.Context, message *event.EventMessage) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.fail {
+		return errors.New("failed")
+	}
+
+	s.keys = append(s.keys, message.Key)
+	return nil
+}
+
+func (s *testSender) setFail(fail bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.fail = fail
+}
+
+func (s *testSender) sent() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string{}, s.keys...)
+}
+
+func TestJournalWriter(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	_, err := NewJournalWriter(ctx, map[string]interface{}{})
+	assert.Error(t, err)
+
+	jw, err := NewJournal(ctx, map[string]interface{}{"dir": dir})
+	require.NoError(t, err)
+
+	for _, key := range []string{"k0", "k1", "k2", "k3"} {
+		require.NoError(t, jw.Send(ctx, &event.EventMessage{Topic: "test", Key: key, Data: "data-" + key}))
+	}
+
+	require.NoError(t, jw.Delete(ctx, &event.EventMessage{Topic: "test", Key: "k1", Data: "data-k1"}))
+
+	n, err := jw.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	var keys []string
+	relay := func(ctx context.Context, record *event.OutboxRecord) error {
+		keys = append(keys, record.Key)
+		if record.Key == "k2" {
+			return errors.New("failed")
+		}
+
+		msg, err := record.ToMessage()
+		require.NoError(t, err)
+		assert.Equal(t, `"data-`+record.Key+`"`, string(msg.RawData))
+		return nil
+	}
+
+	relayed, err := jw.RelayOutbox(ctx, &event.RelayOption{CreatedBefore: time.Now(), Limit: 2}, relay)
+	require.NoError(t, err)
+	assert.Equal(t, 1, relayed)
+	assert.Equal(t, []string{"k0", "k2"}, keys, "relayed in order up to the limit")
+
+	// journal survive writer restart
+	jw, err = NewJournal(ctx, map[string]interface{}{"dir": dir})
+	require.NoError(t, err)
+
+	keys = nil
+	relayed, err = jw.RelayOutbox(ctx, &event.RelayOption{CreatedBefore: time.Now(), Limit: 10}, relay)
+	require.NoError(t, err)
+	assert.Equal(t, 1, relayed)
+	assert.Equal(t, []string{"k2", "k3"}, keys)
+
+	n, err = jw.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n, "failed record is kept")
+}
+
+func TestJournalFallback(t *testing.T) {
+	ctx := context.Background()
+	sender := &testSender{fail: true}
+
+	event.RegisterSender("TestJournalFallback", func(ctx context.Context, config interface{}) (event.Sender, error) {
+		return sender, nil
+	})
+
+	em, err := event.New(ctx, &event.EmitterConfig{
+		Sender: &event.DriverConfig{Type: "TestJournalFallback"},
+		Fallback: &event.FallbackConfig{
+			Writer:      &event.DriverConfig{Type: "journal", Config: map[string]interface{}{"dir": t.TempDir()}},
+			Threshold:   1,
+			OpenTimeout: "10ms",
+			Interval:    "10ms",
+		},
+	})
+	require.NoError(t, err)
+	defer em.Close(ctx)
+
+	keys := []string{"k0", "k1", "k2", "k3", "k4"}
+	for _, key := range keys {
+		require.NoError(t, em.Publish(ctx, "test", key, "data", nil), "message is spilled to the journal")
+	}
+	assert.Empty(t, sender.sent())
+
+	sender.setFail(false)
+	assert.Eventually(t, func() bool {
+		return len(sender.sent()) == len(keys)
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, keys, sender.sent(), "spilled messages are forwarded in order")
+}
@@ -0,0 +1,459 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/diki-haryadi/govega/event"
+	"github.com/diki-haryadi/govega/log"
+	"github.com/mitchellh/mapstructure"
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DriftTopic drift field of missing topic
+	DriftTopic             = "topic"
+	DriftPartitions        = "partitions"
+	DriftReplicationFactor = "replication_factor"
+
+	ActionCreateTopic   = "create_topic"
+	ActionAddPartitions = "add_partitions"
+	ActionAlterConfig   = "alter_config"
+
+	configRetention = "retention.ms"
+)
+
+var ErrTopicDrift = errors.New("[event/kafka] topics drift from the declared config")
+
+type (
+	// TopicSpec declared config of the topic, zero value use the broker default
+	TopicSpec struct {
+		Partitions        int `json:"partitions" mapstructure:"partitions"`
+		ReplicationFactor int `json:"replication_factor" mapstructure:"replication_factor"`
+		// Retention retention time of the topic messages, e.g. 168h
+		Retention string `json:"retention" mapstructure:"retention"`
+		// Configs other topic configs, e.g. cleanup.policy
+		Configs map[string]string `json:"configs" mapstructure:"configs"`
+	}
+
+	AdminConfig struct {
+		Brokers       []string `json:"brokers" mapstructure:"brokers"`
+		KeyFile       string   `json:"key_file" mapstructure:"key_file"`
+		CertFile      string   `json:"cert_file" mapstructure:"cert_file"`
+		CACertificate string   `json:"ca_cert" mapstructure:"ca_cert"`
+		AuthType      string   `json:"auth_type" mapstructure:"auth_type"`
+		Username      string   `json:"username" mapstructure:"username"`
+		Password      string   `json:"password" mapstructure:"password"`
+
+		// Topics declared topics by topic name
+		Topics map[string]*TopicSpec `json:"topics" mapstructure:"topics"`
+		// Default spec of the topics mapped on the event config without declared spec
+		Default *TopicSpec `json:"default" mapstructure:"default"`
+		// DryRun report the drift and the actions without applying them
+		DryRun bool `json:"dry_run" mapstructure:"dry_run"`
+		// AllowAddPartitions add partitions to topic with less partitions than declared,
+		// keyed messages may be sent to different partition after the partitions are added
+		AllowAddPartitions bool `json:"allow_add_partitions" mapstructure:"allow_add_partitions"`
+		// FailOnDrift return ErrTopicDrift when there is unresolved drift
+		FailOnDrift bool `json:"fail_on_drift" mapstructure:"fail_on_drift"`
+	}
+
+	// TopicAdmin provision the declared topics and report the drift against the cluster
+	TopicAdmin struct {
+		config *AdminConfig
+		topics map[string]*TopicSpec
+	}
+
+	// TopicDrift difference between the declared and the actual topic config
+	TopicDrift struct {
+		Topic    string
+		Field    string
+		Expected string
+		Actual   string
+		// Action to resolve the drift, empty when it should be resolved manually
+		Action string
+	}
+
+	ProvisionReport struct {
+		DryRun bool
+		Drifts []TopicDrift
+	}
+
+	// topicState actual state of the topic on the cluster
+	topicState struct {
+		exists            bool
+		partitions        int
+		replicationFactor int
+		configs           map[string]string
+	}
+)
+
+// NewTopicAdmin create topic admin of the declared topics and the topics mapped on the event configs,
+// e.g. event config of the emitter and consumer
+func NewTopicAdmin(config interface{}, eventConfigs ...*event.EventConfig) (*TopicAdmin, error) {
+	var conf AdminConfig
+	if err := mapstructure.Decode(config, &conf); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	if len(conf.Brokers) == 0 {
+		return nil, errors.New("[event/kafka] missing brokers")
+	}
+
+	topics := make(map[string]*TopicSpec)
+	for _, ec := range eventConfigs {
+		if ec == nil {
+			continue
+		}
+
+		for _, topic := range ec.EventMap {
+			if conf.Default != nil {
+				topics[topic] = conf.Default
+			} else {
+				topics[topic] = &TopicSpec{}
+			}
+		}
+	}
+
+	for topic, spec := range conf.Topics {
+		if spec == nil {
+			spec = &TopicSpec{}
+		}
+		topics[topic] = spec
+	}
+
+	for topic, spec := range topics {
+		if spec.Retention == "" {
+			continue
+		}
+
+		if _, err := time.ParseDuration(spec.Retention); err != nil {
+			return nil, fmt.Errorf("[event/kafka] invalid retention of topic %s: %w", topic, err)
+		}
+	}
+
+	return &TopicAdmin{config: &conf, topics: topics}, nil
+}
+
+func (a *TopicAdmin) client() (*kafka.Client, error) {
+	dialer, err := dial(a.config.CertFile, a.config.KeyFile, a.config.CACertificate,
+		a.config.Username, a.config.Password, a.config.AuthType)
+	if err != nil {
+		return nil, err
+	}
+
+	return newClient(a.config.Brokers, dialer), nil
+}
+
+// Check report the drift of the declared topics without applying any action
+func (a *TopicAdmin) Check(ctx context.Context) (*ProvisionReport, error) {
+	client, err := a.client()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Transport.(*kafka.Transport).CloseIdleConnections()
+
+	drifts, err := a.drifts(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProvisionReport{DryRun: true, Drifts: drifts}, nil
+}
+
+// Ensure create the missing topics, alter the configs and add partitions when allowed. Nothing is applied
+// on dry run. The drifts are logged, ErrTopicDrift is returned with the report when FailOnDrift is set and
+// any drift is unresolved
+func (a *TopicAdmin) Ensure(ctx context.Context) (*ProvisionReport, error) {
+	client, err := a.client()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Transport.(*kafka.Transport).CloseIdleConnections()
+
+	drifts, err := a.drifts(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ProvisionReport{DryRun: a.config.DryRun, Drifts: drifts}
+	for _, d := range drifts {
+		log.WithContext(ctx).WithFields(logrus.Fields{
+			"topic":    d.Topic,
+			"field":    d.Field,
+			"expected": d.Expected,
+			"actual":   d.Actual,
+			"action":   d.Action,
+			"dry_run":  a.config.DryRun,
+		}).Warnln("[event/kafka] topic drift")
+	}
+
+	if !a.config.DryRun {
+		if err := a.apply(ctx, client, drifts); err != nil {
+			return report, err
+		}
+	}
+
+	if a.config.FailOnDrift && len(report.Unresolved()) > 0 {
+		return report, ErrTopicDrift
+	}
+
+	return report, nil
+}
+
+// drifts compare the declared topics with the cluster
+func (a *TopicAdmin) drifts(ctx context.Context, client *kafka.Client) ([]TopicDrift, error) {
+	names := make([]string, 0, len(a.topics))
+	for topic := range a.topics {
+		names = append(names, topic)
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	states, err := describeTopics(ctx, client, names)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []TopicDrift
+	for _, topic := range names {
+		drifts = append(drifts, a.topics[topic].diff(topic, states[topic], a.config.AllowAddPartitions)...)
+	}
+
+	return drifts, nil
+}
+
+func (a *TopicAdmin) apply(ctx context.Context, client *kafka.Client, drifts []TopicDrift) error {
+	var create []kafka.TopicConfig
+	var partitions []kafka.TopicPartitionsConfig
+	alter := make(map[string][]kafka.IncrementalAlterConfigsRequestConfig)
+
+	for _, d := range drifts {
+		spec := a.topics[d.Topic]
+
+		switch d.Action {
+		case ActionCreateTopic:
+			create = append(create, spec.topicConfig(d.Topic))
+		case ActionAddPartitions:
+			partitions = append(partitions, kafka.TopicPartitionsConfig{Name: d.Topic, Count: int32(spec.Partitions)})
+		case ActionAlterConfig:
+			alter[d.Topic] = append(alter[d.Topic], kafka.IncrementalAlterConfigsRequestConfig{
+				Name:            d.Field,
+				Value:           d.Expected,
+				ConfigOperation: kafka.ConfigOperationSet,
+			})
+		}
+	}
+
+	if len(create) > 0 {
+		res, err := client.CreateTopics(ctx, &kafka.CreateTopicsRequest{Topics: create})
+		if err != nil {
+			return fmt.Errorf("[event/kafka] failed to create topics: %w", err)
+		}
+		if err := joinErrors("create topic", res.Errors); err != nil {
+			return err
+		}
+	}
+
+	if len(partitions) > 0 {
+		res, err := client.CreatePartitions(ctx, &kafka.CreatePartitionsRequest{Topics: partitions})
+		if err != nil {
+			return fmt.Errorf("[event/kafka] failed to add partitions: %w", err)
+		}
+		if err := joinErrors("add partitions of topic", res.Errors); err != nil {
+			return err
+		}
+	}
+
+	if len(alter) > 0 {
+		req := &kafka.IncrementalAlterConfigsRequest{}
+		for topic, configs := range alter {
+			req.Resources = append(req.Resources, kafka.IncrementalAlterConfigsRequestResource{
+				ResourceType: kafka.ResourceTypeTopic,
+				ResourceName: topic,
+				Configs:      configs,
+			})
+		}
+
+		res, err := client.IncrementalAlterConfigs(ctx, req)
+		if err != nil {
+			return fmt.Errorf("[event/kafka] failed to alter topic configs: %w", err)
+		}
+
+		errs := make(map[string]error)
+		for _, r := range res.Resources {
+			errs[r.ResourceName] = r.Error
+		}
+		if err := joinErrors("alter config of topic", errs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Unresolved return the drifts which should be resolved manually
+func (r *ProvisionReport) Unresolved() []TopicDrift {
+	var drifts []TopicDrift
+	for _, d := range r.Drifts {
+		if d.Action == "" {
+			drifts = append(drifts, d)
+		}
+	}
+	return drifts
+}
+
+// configs return the declared topic configs
+func (s *TopicSpec) configs() map[string]string {
+	configs := make(map[string]string, len(s.Configs)+1)
+	for k, v := range s.Configs {
+		configs[k] = v
+	}
+
+	if s.Retention != "" {
+		retention, _ := time.ParseDuration(s.Retention)
+		configs[configRetention] = strconv.FormatInt(retention.Milliseconds(), 10)
+	}
+
+	return configs
+}
+
+func (s *TopicSpec) topicConfig(topic string) kafka.TopicConfig {
+	conf := kafka.TopicConfig{Topic: topic, NumPartitions: -1, ReplicationFactor: -1}
+	if s.Partitions > 0 {
+		conf.NumPartitions = s.Partitions
+	}
+	if s.ReplicationFactor > 0 {
+		conf.ReplicationFactor = s.ReplicationFactor
+	}
+
+	for k, v := range s.configs() {
+		conf.ConfigEntries = append(conf.ConfigEntries, kafka.ConfigEntry{ConfigName: k, ConfigValue: v})
+	}
+	sort.Slice(conf.ConfigEntries, func(i, j int) bool {
+		return conf.ConfigEntries[i].ConfigName < conf.ConfigEntries[j].ConfigName
+	})
+
+	return conf
+}
+
+// diff return the drift of the topic state from the spec
+func (s *TopicSpec) diff(topic string, state topicState, allowAddPartitions bool) []TopicDrift {
+	if !state.exists {
+		return []TopicDrift{{Topic: topic, Field: DriftTopic, Expected: "exists", Actual: "missing", Action: ActionCreateTopic}}
+	}
+
+	var drifts []TopicDrift
+	if s.Partitions > 0 && s.Partitions != state.partitions {
+		d := TopicDrift{
+			Topic:    topic,
+			Field:    DriftPartitions,
+			Expected: strconv.Itoa(s.Partitions),
+			Actual:   strconv.Itoa(state.partitions),
+		}
+		// partitions can't be removed
+		if allowAddPartitions && s.Partitions > state.partitions {
+			d.Action = ActionAddPartitions
+		}
+		drifts = append(drifts, d)
+	}
+
+	if s.ReplicationFactor > 0 && s.ReplicationFactor != state.replicationFactor {
+		drifts = append(drifts, TopicDrift{
+			Topic:    topic,
+			Field:    DriftReplicationFactor,
+			Expected: strconv.Itoa(s.ReplicationFactor),
+			Actual:   strconv.Itoa(state.replicationFactor),
+		})
+	}
+
+	configs := s.configs()
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if actual := state.configs[name]; actual != configs[name] {
+			drifts = append(drifts, TopicDrift{
+				Topic:    topic,
+				Field:    name,
+				Expected: configs[name],
+				Actual:   actual,
+				Action:   ActionAlterConfig,
+			})
+		}
+	}
+
+	return drifts
+}
+
+// describeTopics return the state of the topics, missing topic is not exists
+func describeTopics(ctx context.Context, client *kafka.Client, topics []string) (map[string]topicState, error) {
+	meta, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: topics})
+	if err != nil {
+		return nil, fmt.Errorf("[event/kafka] failed to get topic metadata: %w", err)
+	}
+
+	states := make(map[string]topicState, len(topics))
+	req := &kafka.DescribeConfigsRequest{}
+	for _, t := range meta.Topics {
+		if errors.Is(t.Error, kafka.UnknownTopicOrPartition) {
+			continue
+		}
+		if t.Error != nil {
+			return nil, fmt.Errorf("[event/kafka] failed to get metadata of topic %s: %w", t.Name, t.Error)
+		}
+
+		state := topicState{exists: true, partitions: len(t.Partitions), configs: make(map[string]string)}
+		if len(t.Partitions) > 0 {
+			state.replicationFactor = len(t.Partitions[0].Replicas)
+		}
+		states[t.Name] = state
+
+		req.Resources = append(req.Resources, kafka.DescribeConfigRequestResource{
+			ResourceType: kafka.ResourceTypeTopic,
+			ResourceName: t.Name,
+		})
+	}
+
+	if len(req.Resources) == 0 {
+		return states, nil
+	}
+
+	res, err := client.DescribeConfigs(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("[event/kafka] failed to describe topic configs: %w", err)
+	}
+
+	for _, r := range res.Resources {
+		if r.Error != nil {
+			return nil, fmt.Errorf("[event/kafka] failed to describe config of topic %s: %w", r.ResourceName, r.Error)
+		}
+
+		for _, c := range r.ConfigEntries {
+			states[r.ResourceName].configs[c.ConfigName] = c.ConfigValue
+		}
+	}
+
+	return states, nil
+}
+
+func joinErrors(op string, errs map[string]error) error {
+	var joined []error
+	for name, err := range errs {
+		if err != nil {
+			joined = append(joined, fmt.Errorf("[event/kafka] failed to %s %s: %w", op, name, err))
+		}
+	}
+	return errors.Join(joined...)
+}
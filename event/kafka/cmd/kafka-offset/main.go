@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/diki-haryadi/govega/event/kafka"
+)
+
+func main() {
+	var brokers = flag.String("brokers", "localhost:9092", "comma separated kafka brokers")
+	var topic = flag.String("topic", "", "topic of the offsets")
+	var group = flag.String("group", "", "consumer group")
+	var to = flag.String("to", "", "target offset: earliest, latest, RFC3339 time or absolute offset")
+	var partitions = flag.String("partitions", "", "comma separated partitions, default: all partitions")
+	var dryRun = flag.Bool("dry-run", false, "print the offsets without committing them")
+	var username = flag.String("username", "", "sasl username")
+	var password = flag.String("password", "", "sasl password")
+	var authType = flag.String("auth-type", "", "sasl mechanism: plain or scram")
+	var timeout = flag.Duration("timeout", 30*time.Second, "timeout of the reset")
+	flag.Parse()
+
+	if *topic == "" || *group == "" || *to == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	target, err := kafka.ParseOffsetTarget(*to)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	option := &kafka.ResetOffsetOption{Target: target, DryRun: *dryRun}
+	if *partitions != "" {
+		for _, p := range strings.Split(*partitions, ",") {
+			partition, err := strconv.Atoi(strings.TrimSpace(p))
+			if err != nil {
+				log.Fatalf("invalid partition: %s", p)
+			}
+			option.Partitions = append(option.Partitions, partition)
+		}
+	}
+
+	listener := &kafka.KafkaListener{
+		Brokers:  strings.Split(*brokers, ","),
+		Username: *username,
+		Password: *password,
+		AuthType: *authType,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	offsets, err := listener.ResetOffsets(ctx, *topic, *group, option)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PARTITION\tPREVIOUS\tOFFSET")
+	for _, o := range offsets {
+		fmt.Fprintf(w, "%d\t%d\t%d\n", o.Partition, o.Previous, o.Offset)
+	}
+	w.Flush()
+
+	if *dryRun {
+		fmt.Println("dry run, offsets are not committed")
+	}
+}
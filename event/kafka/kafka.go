@@ -15,6 +15,9 @@ import (
 const (
 	KafkaPartitionKey     = attribute.Key("messaging.kafka.partition")
 	KafkaConsumerGroupKey = attribute.Key("messaging.kafka.consumer_group")
+
+	// CloudEventsHeaderPrefix prefix of cloudevents attribute headers on binary content mode
+	CloudEventsHeaderPrefix = "ce_"
 )
 
 func init() {
@@ -62,3 +65,15 @@ func dial(certFile, keyFile, caCert, username, password, authType string) (*kafk
 
 	return dialer, nil
 }
+
+// newClient create kafka client of the brokers with the dialer tls and sasl config
+func newClient(brokers []string, dialer *kafka.Dialer) *kafka.Client {
+	return &kafka.Client{
+		Addr: kafka.TCP(brokers...),
+		Transport: &kafka.Transport{
+			ClientID: dialer.ClientID,
+			TLS:      dialer.TLS,
+			SASL:     dialer.SASLMechanism,
+		},
+	}
+}
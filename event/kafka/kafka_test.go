@@ -2,10 +2,14 @@ package kafka
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/diki-haryadi/govega/event"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
 )
 
 func TestPublish(t *testing.T) {
@@ -32,3 +36,253 @@ func TestPublish(t *testing.T) {
 
 	require.Nil(t, em.Publish(ctx, "test", "t123", "testdata", nil))
 }
+
+func TestCloudEvents(t *testing.T) {
+	ctx := context.Background()
+
+	for _, mode := range []string{event.CloudEventsStructured, event.CloudEventsBinary} {
+		t.Run(mode, func(t *testing.T) {
+			sender := &KafkaSender{CloudEvents: mode, propagator: otel.GetTextMapPropagator()}
+
+			msg, err := sender.newMessage(ctx, &event.EventMessage{
+				Topic: "order",
+				Key:   "o123",
+				Data:  map[string]interface{}{"id": "o123"},
+				Metadata: map[string]interface{}{
+					event.MetaEvent:   "order_created",
+					event.MetaVersion: 2,
+					event.MetaHash:    "hash123",
+					event.MetaTime:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+					"source":          "checkout",
+					"retry_count":     1,
+				},
+			})
+			require.Nil(t, err)
+
+			carrier := newKafkaMessageCarrier(&msg)
+			if mode == event.CloudEventsBinary {
+				assert.Equal(t, "order_created", carrier.Get("ce_type"))
+				assert.Equal(t, "hash123", carrier.Get("ce_id"))
+				assert.Equal(t, "order", carrier.Get("ce_source"))
+				assert.Equal(t, "o123", carrier.Get("ce_partitionkey"))
+				assert.Equal(t, "2", carrier.Get("ce_version"))
+				assert.Equal(t, event.ContentTypeJSON, carrier.Get("content-type"))
+				assert.JSONEq(t, `{"id":"o123"}`, string(msg.Value))
+			} else {
+				assert.Equal(t, event.ContentTypeCloudEvents, carrier.Get("content-type"))
+				assert.Contains(t, string(msg.Value), `"specversion":"1.0"`)
+			}
+
+			cm := newKafkaConsumeMessage(nil, carrier)
+			em, err := cm.GetEventConsumeMessage(ctx)
+			require.Nil(t, err)
+
+			assert.Equal(t, "order", em.Topic)
+			assert.Equal(t, "o123", em.Key)
+			assert.JSONEq(t, `{"id":"o123"}`, string(em.Data))
+			assert.Equal(t, "order_created", event.GetMetadataEvent(em.Metadata))
+			assert.Equal(t, 2, event.GetMetadataVersion(em.Metadata))
+			assert.Equal(t, "hash123", em.Metadata[event.MetaHash])
+			assert.Equal(t, "2024-01-02T03:04:05Z", em.Metadata[event.MetaTime])
+			assert.Equal(t, "checkout", em.Metadata["source"])
+			assert.EqualValues(t, 1, em.Metadata["retry_count"])
+		})
+	}
+
+	_, err := NewKafkaSender(ctx, map[string]interface{}{"cloud_events": "unknown"})
+	assert.Error(t, err)
+}
+
+func TestOffsetTarget(t *testing.T) {
+	target, err := ParseOffsetTarget("earliest")
+	require.Nil(t, err)
+	assert.Equal(t, int64(10), target.resolve(10, 100, -1))
+
+	target, err = ParseOffsetTarget("latest")
+	require.Nil(t, err)
+	assert.Equal(t, int64(100), target.resolve(10, 100, -1))
+
+	target, err = ParseOffsetTarget("2024-01-02T03:04:05Z")
+	require.Nil(t, err)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), target.Time)
+	assert.Equal(t, int64(50), target.resolve(10, 100, 50))
+	assert.Equal(t, int64(100), target.resolve(10, 100, -1), "latest when no message after the time")
+
+	target, err = ParseOffsetTarget("42")
+	require.Nil(t, err)
+	assert.Equal(t, int64(42), target.resolve(10, 100, -1))
+	assert.Equal(t, int64(50), target.resolve(50, 100, -1), "offset is limited to available offsets")
+	assert.Equal(t, int64(30), target.resolve(10, 30, -1))
+
+	_, err = ParseOffsetTarget("yesterday")
+	assert.Error(t, err)
+
+	_, err = ParseOffsetTarget("-1")
+	assert.Error(t, err)
+}
+
+func TestResetOffsetsAndReplay(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	topic := fmt.Sprintf("test-replay-%d", time.Now().UnixNano())
+
+	event.RegisterSender("kafka", NewKafkaSender)
+	em, err := event.New(ctx, &event.EmitterConfig{
+		Sender: &event.DriverConfig{
+			Type:   "kafka",
+			Config: map[string]interface{}{"brokers": []string{"localhost:9092"}},
+		},
+	})
+	require.Nil(t, err)
+	for i := 0; i < 5; i++ {
+		require.Nil(t, em.Publish(ctx, topic, fmt.Sprint(i), i, nil))
+	}
+
+	listener := &KafkaListener{Brokers: []string{"localhost:9092"}}
+
+	offsets, err := listener.ResetOffsets(ctx, topic, "test-replay", &ResetOffsetOption{
+		Target: OffsetTarget{Offset: 2},
+		DryRun: true,
+	})
+	require.Nil(t, err)
+	require.Len(t, offsets, 1)
+	assert.Equal(t, int64(-1), offsets[0].Previous)
+	assert.Equal(t, int64(2), offsets[0].Offset)
+
+	_, err = listener.ResetOffsets(ctx, topic, "test-replay", &ResetOffsetOption{Target: OffsetTarget{Offset: 2}})
+	require.Nil(t, err)
+
+	offsets, err = listener.ResetOffsets(ctx, topic, "test-replay", &ResetOffsetOption{
+		Target: OffsetTarget{Position: OffsetLatest},
+		DryRun: true,
+	})
+	require.Nil(t, err)
+	assert.Equal(t, int64(2), offsets[0].Previous)
+	assert.Equal(t, int64(5), offsets[0].Offset)
+
+	event.RegisterListener("kafka", NewKafkaListener)
+	consumer, err := event.NewConsumer(ctx, &event.ConsumerConfig{
+		Listener: &event.DriverConfig{
+			Type:   "kafka",
+			Config: map[string]interface{}{"brokers": []string{"localhost:9092"}},
+		},
+	})
+	require.Nil(t, err)
+
+	var keys []string
+	count, err := consumer.Replay(ctx, topic, &event.ReplayOption{FromOffset: 1, ToOffset: 4},
+		func(ctx context.Context, message *event.EventConsumeMessage) error {
+			keys = append(keys, message.Key)
+			return nil
+		})
+	require.Nil(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, []string{"1", "2", "3"}, keys)
+}
+
+func TestTopicSpecDiff(t *testing.T) {
+	spec := &TopicSpec{Partitions: 6, ReplicationFactor: 3, Retention: "168h", Configs: map[string]string{"cleanup.policy": "delete"}}
+
+	drifts := spec.diff("order", topicState{}, false)
+	assert.Equal(t, []TopicDrift{{Topic: "order", Field: DriftTopic, Expected: "exists", Actual: "missing", Action: ActionCreateTopic}}, drifts)
+
+	state := topicState{
+		exists:            true,
+		partitions:        6,
+		replicationFactor: 3,
+		configs:           map[string]string{"retention.ms": "604800000", "cleanup.policy": "delete"},
+	}
+	assert.Empty(t, spec.diff("order", state, false))
+
+	state.partitions = 3
+	state.replicationFactor = 1
+	state.configs["retention.ms"] = "86400000"
+	drifts = spec.diff("order", state, false)
+	assert.Equal(t, []TopicDrift{
+		{Topic: "order", Field: DriftPartitions, Expected: "6", Actual: "3"},
+		{Topic: "order", Field: DriftReplicationFactor, Expected: "3", Actual: "1"},
+		{Topic: "order", Field: "retention.ms", Expected: "604800000", Actual: "86400000", Action: ActionAlterConfig},
+	}, drifts)
+
+	drifts = spec.diff("order", state, true)
+	assert.Equal(t, ActionAddPartitions, drifts[0].Action, "partitions are added when allowed")
+
+	state.partitions = 12
+	drifts = spec.diff("order", state, true)
+	assert.Empty(t, drifts[0].Action, "partitions can't be removed")
+
+	report := &ProvisionReport{Drifts: drifts}
+	assert.Len(t, report.Unresolved(), 2)
+
+	conf := spec.topicConfig("order")
+	assert.Equal(t, 6, conf.NumPartitions)
+	assert.Equal(t, 3, conf.ReplicationFactor)
+	assert.Len(t, conf.ConfigEntries, 2)
+	assert.Equal(t, -1, (&TopicSpec{}).topicConfig("order").NumPartitions, "broker default")
+}
+
+func TestNewTopicAdmin(t *testing.T) {
+	emitterConfig := &event.EventConfig{EventMap: map[string]string{"order_created": "order"}}
+	consumerConfig := &event.EventConfig{EventMap: map[string]string{"payment_paid": "payment"}}
+
+	admin, err := NewTopicAdmin(&AdminConfig{
+		Brokers: []string{"localhost:9092"},
+		Default: &TopicSpec{Partitions: 3},
+		Topics:  map[string]*TopicSpec{"order": {Partitions: 12}, "audit": nil},
+	}, emitterConfig, consumerConfig, nil)
+	require.Nil(t, err)
+
+	assert.Len(t, admin.topics, 3)
+	assert.Equal(t, 12, admin.topics["order"].Partitions, "declared spec override default")
+	assert.Equal(t, 3, admin.topics["payment"].Partitions)
+	assert.Equal(t, 0, admin.topics["audit"].Partitions)
+
+	_, err = NewTopicAdmin(map[string]interface{}{
+		"brokers": []string{"localhost:9092"},
+		"topics":  map[string]interface{}{"order": map[string]interface{}{"retention": "a week"}},
+	})
+	assert.Error(t, err)
+
+	_, err = NewTopicAdmin(map[string]interface{}{})
+	assert.Error(t, err)
+}
+
+func TestTopicAdmin(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	topic := fmt.Sprintf("test-admin-%d", time.Now().UnixNano())
+
+	admin, err := NewTopicAdmin(map[string]interface{}{
+		"brokers": []string{"localhost:9092"},
+		"dry_run": true,
+		"topics": map[string]interface{}{
+			topic: map[string]interface{}{"partitions": 2, "retention": "24h"},
+		},
+	})
+	require.Nil(t, err)
+
+	report, err := admin.Ensure(ctx)
+	require.Nil(t, err)
+	require.Len(t, report.Drifts, 1)
+	assert.Equal(t, ActionCreateTopic, report.Drifts[0].Action)
+
+	admin.config.DryRun = false
+	_, err = admin.Ensure(ctx)
+	require.Nil(t, err)
+
+	report, err = admin.Check(ctx)
+	require.Nil(t, err)
+	assert.Empty(t, report.Drifts)
+
+	admin.topics[topic].Partitions = 3
+	admin.config.FailOnDrift = true
+	report, err = admin.Ensure(ctx)
+	assert.ErrorIs(t, err, ErrTopicDrift)
+	assert.Len(t, report.Unresolved(), 1)
+}
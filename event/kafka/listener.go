@@ -8,6 +8,7 @@ import (
 
 	"github.com/diki-haryadi/govega/event"
 	"github.com/diki-haryadi/govega/log"
+	"github.com/diki-haryadi/govega/monitor"
 	"github.com/mitchellh/mapstructure"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -58,6 +59,7 @@ type (
 
 	KafkaIterator struct {
 		reader     *kafka.Reader
+		group      string
 		tracer     trace.Tracer
 		propagator propagation.TextMapPropagator
 	}
@@ -93,138 +95,149 @@ func newKafkaIterator(kaf *KafkaListener, topic, group string) (iter *KafkaItera
 		}
 	}()
 
-	dialer, err := dial(kaf.CertFile, kaf.KeyFile, kaf.CACertificate, kaf.Username, kaf.Password, kaf.AuthType)
+	conf, err := kaf.readerConfig(topic, group)
 	if err != nil {
 		return nil, err
 	}
 
 	// NOTE: NewReader may panic
+	reader := kafka.NewReader(conf)
+
+	iter = &KafkaIterator{
+		reader:     reader,
+		group:      group,
+		tracer:     otel.Tracer("event/consumer"),
+		propagator: otel.GetTextMapPropagator(),
+	}
+	err = nil
+	return
+}
+
+// readerConfig return reader config of the topic group, reader without group read a single partition
+func (k *KafkaListener) readerConfig(topic, group string) (kafka.ReaderConfig, error) {
+	dialer, err := dial(k.CertFile, k.KeyFile, k.CACertificate, k.Username, k.Password, k.AuthType)
+	if err != nil {
+		return kafka.ReaderConfig{}, err
+	}
+
 	conf := kafka.ReaderConfig{
-		Brokers:               kaf.Brokers,
+		Brokers:               k.Brokers,
 		Topic:                 topic,
 		GroupID:               group,
 		Dialer:                dialer,
-		QueueCapacity:         kaf.QueueCapacity,
-		MaxBytes:              kaf.MaxBytes,
-		MinBytes:              kaf.MinBytes,
-		WatchPartitionChanges: kaf.WatchPartitionChanges,
-		StartOffset:           kaf.StartOffset,
-		Logger:                newKafkaPrintLogger(kaf.PrintLogLevel),
-		ErrorLogger:           newKafkaErrorLogger(kaf.ErrorLogLevel),
+		QueueCapacity:         k.QueueCapacity,
+		MaxBytes:              k.MaxBytes,
+		MinBytes:              k.MinBytes,
+		WatchPartitionChanges: k.WatchPartitionChanges,
+		StartOffset:           k.StartOffset,
+		Logger:                newKafkaPrintLogger(k.PrintLogLevel),
+		ErrorLogger:           newKafkaErrorLogger(k.ErrorLogLevel),
 	}
 
-	if kaf.MaxWait != "" {
-		maxWait, err := time.ParseDuration(kaf.MaxWait)
+	if k.MaxWait != "" {
+		maxWait, err := time.ParseDuration(k.MaxWait)
 		if err != nil {
-			return nil, fmt.Errorf("invalid kafka listener max wait value: %w", err)
+			return conf, fmt.Errorf("invalid kafka listener max wait value: %w", err)
 		}
 
 		conf.MaxWait = maxWait
 	}
 
-	if kaf.ReadlagInterval != "" {
-		interval, err := time.ParseDuration(kaf.ReadlagInterval)
+	if k.ReadlagInterval != "" {
+		interval, err := time.ParseDuration(k.ReadlagInterval)
 		if err != nil {
-			return nil, fmt.Errorf("invalid kafka listener read lag interval value: %w", err)
+			return conf, fmt.Errorf("invalid kafka listener read lag interval value: %w", err)
 		}
 
 		conf.ReadLagInterval = interval
 	}
 
-	if kaf.HeartbeatInterval != "" {
-		interval, err := time.ParseDuration(kaf.HeartbeatInterval)
+	if k.HeartbeatInterval != "" {
+		interval, err := time.ParseDuration(k.HeartbeatInterval)
 		if err != nil {
-			return nil, fmt.Errorf("invalid kafka listener heartbeat interval value: %w", err)
+			return conf, fmt.Errorf("invalid kafka listener heartbeat interval value: %w", err)
 		}
 
 		conf.HeartbeatInterval = interval
 	}
 
-	if kaf.CommitInterval != "" {
-		interval, err := time.ParseDuration(kaf.CommitInterval)
+	if k.CommitInterval != "" {
+		interval, err := time.ParseDuration(k.CommitInterval)
 		if err != nil {
-			return nil, fmt.Errorf("invalid kafka listener commit interval value: %w", err)
+			return conf, fmt.Errorf("invalid kafka listener commit interval value: %w", err)
 		}
 
 		conf.CommitInterval = interval
 	}
 
-	if kaf.PartitionWatchInterval != "" {
-		interval, err := time.ParseDuration(kaf.PartitionWatchInterval)
+	if k.PartitionWatchInterval != "" {
+		interval, err := time.ParseDuration(k.PartitionWatchInterval)
 		if err != nil {
-			return nil, fmt.Errorf("invalid kafka listener partition watch interval value: %w", err)
+			return conf, fmt.Errorf("invalid kafka listener partition watch interval value: %w", err)
 		}
 
 		conf.PartitionWatchInterval = interval
 	}
 
-	if kaf.SessionTimeout != "" {
-		timeout, err := time.ParseDuration(kaf.SessionTimeout)
+	if k.SessionTimeout != "" {
+		timeout, err := time.ParseDuration(k.SessionTimeout)
 		if err != nil {
-			return nil, fmt.Errorf("invalid kafka listener session timeout value: %w", err)
+			return conf, fmt.Errorf("invalid kafka listener session timeout value: %w", err)
 		}
 
 		conf.SessionTimeout = timeout
 	}
 
-	if kaf.RebalanceTimeout != "" {
-		timeout, err := time.ParseDuration(kaf.RebalanceTimeout)
+	if k.RebalanceTimeout != "" {
+		timeout, err := time.ParseDuration(k.RebalanceTimeout)
 		if err != nil {
-			return nil, fmt.Errorf("invalid kafka listener rebalance timeout value: %w", err)
+			return conf, fmt.Errorf("invalid kafka listener rebalance timeout value: %w", err)
 		}
 
 		conf.RebalanceTimeout = timeout
 	}
 
-	if kaf.JoinGroupBackoff != "" {
-		backoff, err := time.ParseDuration(kaf.JoinGroupBackoff)
+	if k.JoinGroupBackoff != "" {
+		backoff, err := time.ParseDuration(k.JoinGroupBackoff)
 		if err != nil {
-			return nil, fmt.Errorf("invalid kafka listener join group backoff value: %w", err)
+			return conf, fmt.Errorf("invalid kafka listener join group backoff value: %w", err)
 		}
 
 		conf.JoinGroupBackoff = backoff
 	}
 
-	if kaf.RetentionTime != "" {
-		retentionTime, err := time.ParseDuration(kaf.RetentionTime)
+	if k.RetentionTime != "" {
+		retentionTime, err := time.ParseDuration(k.RetentionTime)
 		if err != nil {
-			return nil, fmt.Errorf("invalid kafka listener retention time value: %w", err)
+			return conf, fmt.Errorf("invalid kafka listener retention time value: %w", err)
 		}
 
 		conf.RetentionTime = retentionTime
 	}
 
-	if kaf.ReadBackoffMin != "" {
-		min, err := time.ParseDuration(kaf.ReadBackoffMin)
+	if k.ReadBackoffMin != "" {
+		min, err := time.ParseDuration(k.ReadBackoffMin)
 		if err != nil {
-			return nil, fmt.Errorf("invalid kafka listener read backoff min value: %w", err)
+			return conf, fmt.Errorf("invalid kafka listener read backoff min value: %w", err)
 		}
 
 		conf.ReadBackoffMin = min
 	}
 
-	if kaf.ReadBackoffMax != "" {
-		max, err := time.ParseDuration(kaf.ReadBackoffMax)
+	if k.ReadBackoffMax != "" {
+		max, err := time.ParseDuration(k.ReadBackoffMax)
 		if err != nil {
-			return nil, fmt.Errorf("invalid kafka listener read backoff max value: %w", err)
+			return conf, fmt.Errorf("invalid kafka listener read backoff max value: %w", err)
 		}
 
 		conf.ReadBackoffMax = max
 	}
 
-	if kaf.MaxAttempts > 0 {
-		conf.MaxAttempts = kaf.MaxAttempts
+	if k.MaxAttempts > 0 {
+		conf.MaxAttempts = k.MaxAttempts
 	}
 
-	reader := kafka.NewReader(conf)
-
-	iter = &KafkaIterator{
-		reader:     reader,
-		tracer:     otel.Tracer("event/consumer"),
-		propagator: otel.GetTextMapPropagator(),
-	}
-	err = nil
-	return
+	return conf, nil
 }
 
 func (k *KafkaIterator) Next(ctx context.Context) (event.ConsumeMessage, error) {
@@ -232,6 +245,8 @@ func (k *KafkaIterator) Next(ctx context.Context) (event.ConsumeMessage, error)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read message: %w", err)
 	}
+	k.feedLag(msg)
+
 	carrier := newKafkaMessageCarrier(&msg)
 	parentSpanContext := k.propagator.Extract(ctx, carrier)
 
@@ -265,6 +280,40 @@ func (k *KafkaIterator) injectOtel(ctx context.Context, msg kafka.Message,
 	return newctx, span
 }
 
+// feedLag report lag of the message partition, high watermark is the offset of the next message
+// to be written to the partition as reported by the broker on the fetch response.
+// Reader stats is not used since group reader merge the stats of all partitions into a single lag
+func (k *KafkaIterator) feedLag(msg kafka.Message) {
+	if msg.HighWaterMark <= 0 {
+		return
+	}
+
+	lag := msg.HighWaterMark - msg.Offset - 1
+	if lag < 0 {
+		lag = 0
+	}
+
+	monitor.FeedConsumerLagMetrics(msg.Topic, strconv.Itoa(msg.Partition), k.group, lag)
+}
+
 func (k *KafkaIterator) Close() error {
 	return k.reader.Close()
 }
+
+// CommitBatch commit multiple kafka messages with a single request
+func (k *KafkaIterator) CommitBatch(ctx context.Context, messages []event.ConsumeMessage) error {
+	msgs := make([]kafka.Message, 0, len(messages))
+	for _, m := range messages {
+		km, ok := m.(*KafkaConsumeMessage)
+		if !ok {
+			return fmt.Errorf("failed to commit batch: unsupported message type %T", m)
+		}
+		msgs = append(msgs, *km.Message)
+	}
+
+	if err := k.reader.CommitMessages(ctx, msgs...); err != nil {
+		return fmt.Errorf("failed to commit messages: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,437 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/diki-haryadi/govega/event"
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	OffsetEarliest = "earliest"
+	OffsetLatest   = "latest"
+)
+
+var ErrGroupActive = errors.New("[event/kafka] consumer group has active members, stop the consumers before resetting the offsets")
+
+type (
+	// OffsetTarget target offset of each partition: earliest, latest, the first message at or after
+	// the time or the absolute offset. Offset is limited to the offsets available on the partition
+	OffsetTarget struct {
+		Position string
+		Time     time.Time
+		Offset   int64
+	}
+
+	// ResetOffsetOption option of the consumer group offset reset
+	ResetOffsetOption struct {
+		Target OffsetTarget
+		// Partitions reset only the partitions, default: all partitions
+		Partitions []int
+		// DryRun return the offsets without committing them
+		DryRun bool
+	}
+
+	// PartitionOffset committed offset of the partition before and after the reset,
+	// previous is -1 when the group has no committed offset
+	PartitionOffset struct {
+		Partition int
+		Previous  int64
+		Offset    int64
+	}
+
+	// partitionRange offset range of the partition, end is exclusive
+	partitionRange struct {
+		partition int
+		start     int64
+		end       int64
+	}
+
+	// KafkaReplayIterator read the ranges partition by partition without consumer group
+	KafkaReplayIterator struct {
+		listener   *KafkaListener
+		topic      string
+		ranges     []partitionRange
+		current    int
+		next       int64
+		reader     *kafka.Reader
+		tracer     trace.Tracer
+		propagator propagation.TextMapPropagator
+	}
+)
+
+// ParseOffsetTarget parse earliest, latest, RFC3339 time or absolute offset
+func ParseOffsetTarget(s string) (OffsetTarget, error) {
+	switch s {
+	case OffsetEarliest, OffsetLatest:
+		return OffsetTarget{Position: s}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return OffsetTarget{Time: t}, nil
+	}
+
+	offset, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || offset < 0 {
+		return OffsetTarget{}, fmt.Errorf("[event/kafka] invalid offset target: %s", s)
+	}
+
+	return OffsetTarget{Offset: offset}, nil
+}
+
+// resolve return the target offset of the partition from the available offsets,
+// timeOffset is the offset of the first message at or after the target time, -1 if none
+func (o OffsetTarget) resolve(first, last, timeOffset int64) int64 {
+	switch {
+	case o.Position == OffsetEarliest:
+		return first
+	case o.Position == OffsetLatest:
+		return last
+	case !o.Time.IsZero():
+		if timeOffset < 0 {
+			return last
+		}
+		return timeOffset
+	case o.Offset < first:
+		return first
+	case o.Offset > last:
+		return last
+	default:
+		return o.Offset
+	}
+}
+
+func (k *KafkaListener) client() (*kafka.Client, error) {
+	dialer, err := dial(k.CertFile, k.KeyFile, k.CACertificate, k.Username, k.Password, k.AuthType)
+	if err != nil {
+		return nil, err
+	}
+
+	return newClient(k.Brokers, dialer), nil
+}
+
+// ResetOffsets commit the consumer group offsets of the topic to the target, the group must not have active members.
+// Consumers of the group resume from the committed offsets once started
+func (k *KafkaListener) ResetOffsets(ctx context.Context, topic, group string, option *ResetOffsetOption) ([]PartitionOffset, error) {
+	client, err := k.client()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Transport.(*kafka.Transport).CloseIdleConnections()
+
+	groups, err := client.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{GroupIDs: []string{group}})
+	if err != nil {
+		return nil, fmt.Errorf("[event/kafka] failed to describe group %s: %w", group, err)
+	}
+
+	for _, g := range groups.Groups {
+		if g.Error != nil {
+			return nil, fmt.Errorf("[event/kafka] failed to describe group %s: %w", group, g.Error)
+		}
+		if len(g.Members) > 0 {
+			return nil, ErrGroupActive
+		}
+	}
+
+	partitions, err := k.partitions(ctx, client, topic, option.Partitions)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets, err := k.resolveOffsets(ctx, client, topic, partitions, option.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	fetched, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: group,
+		Topics:  map[string][]int{topic: partitions},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("[event/kafka] failed to fetch group offsets: %w", err)
+	}
+
+	if fetched.Error != nil {
+		return nil, fmt.Errorf("[event/kafka] failed to fetch group offsets: %w", fetched.Error)
+	}
+
+	previous := make(map[int]int64)
+	for _, p := range fetched.Topics[topic] {
+		previous[p.Partition] = p.CommittedOffset
+	}
+
+	result := make([]PartitionOffset, 0, len(partitions))
+	commits := make([]kafka.OffsetCommit, 0, len(partitions))
+	for _, p := range partitions {
+		prev, ok := previous[p]
+		if !ok {
+			prev = -1
+		}
+
+		result = append(result, PartitionOffset{Partition: p, Previous: prev, Offset: offsets[p]})
+		commits = append(commits, kafka.OffsetCommit{Partition: p, Offset: offsets[p]})
+	}
+
+	if option.DryRun {
+		return result, nil
+	}
+
+	// commit without generation as the group has no active members
+	res, err := client.OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      group,
+		GenerationID: -1,
+		Topics:       map[string][]kafka.OffsetCommit{topic: commits},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("[event/kafka] failed to commit group offsets: %w", err)
+	}
+
+	for _, p := range res.Topics[topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("[event/kafka] failed to commit offset of partition %d: %w", p.Partition, p.Error)
+		}
+	}
+
+	return result, nil
+}
+
+// Replay read messages of the range partition by partition without consumer group
+func (k *KafkaListener) Replay(ctx context.Context, topic string, option *event.ReplayOption) (event.Iterator, error) {
+	client, err := k.client()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Transport.(*kafka.Transport).CloseIdleConnections()
+
+	partitions, err := k.partitions(ctx, client, topic, option.Partitions)
+	if err != nil {
+		return nil, err
+	}
+
+	from := OffsetTarget{Position: OffsetEarliest}
+	if !option.From.IsZero() {
+		from = OffsetTarget{Time: option.From}
+	} else if option.FromOffset > 0 {
+		from = OffsetTarget{Offset: option.FromOffset}
+	}
+
+	to := OffsetTarget{Position: OffsetLatest}
+	if !option.To.IsZero() {
+		to = OffsetTarget{Time: option.To}
+	} else if option.ToOffset > 0 {
+		to = OffsetTarget{Offset: option.ToOffset}
+	}
+
+	starts, err := k.resolveOffsets(ctx, client, topic, partitions, from)
+	if err != nil {
+		return nil, err
+	}
+
+	ends, err := k.resolveOffsets(ctx, client, topic, partitions, to)
+	if err != nil {
+		return nil, err
+	}
+
+	iter := &KafkaReplayIterator{
+		listener:   k,
+		topic:      topic,
+		tracer:     otel.Tracer("event/consumer"),
+		propagator: otel.GetTextMapPropagator(),
+	}
+
+	for _, p := range partitions {
+		if starts[p] < ends[p] {
+			iter.ranges = append(iter.ranges, partitionRange{partition: p, start: starts[p], end: ends[p]})
+		}
+	}
+
+	return iter, nil
+}
+
+// partitions return the requested partitions or all partitions of the topic
+func (k *KafkaListener) partitions(ctx context.Context, client *kafka.Client, topic string, partitions []int) ([]int, error) {
+	if len(partitions) > 0 {
+		sort.Ints(partitions)
+		return partitions, nil
+	}
+
+	meta, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, fmt.Errorf("[event/kafka] failed to get topic metadata: %w", err)
+	}
+
+	for _, t := range meta.Topics {
+		if t.Name != topic {
+			continue
+		}
+		if t.Error != nil {
+			return nil, fmt.Errorf("[event/kafka] failed to get topic metadata: %w", t.Error)
+		}
+
+		for _, p := range t.Partitions {
+			partitions = append(partitions, p.ID)
+		}
+	}
+
+	if len(partitions) == 0 {
+		return nil, fmt.Errorf("[event/kafka] topic %s not found", topic)
+	}
+
+	sort.Ints(partitions)
+	return partitions, nil
+}
+
+// resolveOffsets return the target offset of each partition
+func (k *KafkaListener) resolveOffsets(ctx context.Context, client *kafka.Client, topic string,
+	partitions []int, target OffsetTarget) (map[int]int64, error) {
+	requests := make([]kafka.OffsetRequest, 0, 2*len(partitions))
+	for _, p := range partitions {
+		requests = append(requests, kafka.FirstOffsetOf(p), kafka.LastOffsetOf(p))
+	}
+
+	bounds, err := listOffsets(ctx, client, topic, requests)
+	if err != nil {
+		return nil, err
+	}
+
+	times := make(map[int]kafka.PartitionOffsets)
+	if !target.Time.IsZero() {
+		requests = requests[:0]
+		for _, p := range partitions {
+			requests = append(requests, kafka.TimeOffsetOf(p, target.Time))
+		}
+
+		// requested separately, broker return -1 timestamp when there is no message after the time
+		if times, err = listOffsets(ctx, client, topic, requests); err != nil {
+			return nil, err
+		}
+	}
+
+	offsets := make(map[int]int64, len(partitions))
+	for _, p := range partitions {
+		b, ok := bounds[p]
+		if !ok {
+			return nil, fmt.Errorf("[event/kafka] partition %d of topic %s not found", p, topic)
+		}
+
+		timeOffset := int64(-1)
+		for offset := range times[p].Offsets {
+			timeOffset = offset
+		}
+
+		offsets[p] = target.resolve(b.FirstOffset, b.LastOffset, timeOffset)
+	}
+
+	return offsets, nil
+}
+
+func listOffsets(ctx context.Context, client *kafka.Client, topic string,
+	requests []kafka.OffsetRequest) (map[int]kafka.PartitionOffsets, error) {
+	res, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("[event/kafka] failed to list offsets: %w", err)
+	}
+
+	offsets := make(map[int]kafka.PartitionOffsets)
+	for _, p := range res.Topics[topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("[event/kafka] failed to list offsets of partition %d: %w", p.Partition, p.Error)
+		}
+		offsets[p.Partition] = p
+	}
+
+	return offsets, nil
+}
+
+// Next return the next message of the range, io.EOF once all of the ranges are read
+func (k *KafkaReplayIterator) Next(ctx context.Context) (event.ConsumeMessage, error) {
+	for {
+		for k.reader == nil || k.next >= k.ranges[k.current].end {
+			if err := k.advance(); err != nil {
+				return nil, err
+			}
+		}
+
+		msg, err := k.reader.FetchMessage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message: %w", err)
+		}
+		k.next = msg.Offset + 1
+
+		// the last offsets of the range may be control records that are never fetched,
+		// so the fetched message can already be past the range end
+		if msg.Offset >= k.ranges[k.current].end {
+			continue
+		}
+
+		carrier := newKafkaMessageCarrier(&msg)
+		parentSpanContext := k.propagator.Extract(ctx, carrier)
+		_, span := k.tracer.Start(parentSpanContext, fmt.Sprintf("kafka.replay.%s", msg.Topic),
+			trace.WithSpanKind(trace.SpanKindConsumer))
+		defer span.End()
+
+		// replayed message is never committed
+		return newKafkaConsumeMessage(nil, carrier), nil
+	}
+}
+
+// advance close the current reader and open the next partition range
+func (k *KafkaReplayIterator) advance() error {
+	if k.reader != nil {
+		if err := k.reader.Close(); err != nil {
+			return fmt.Errorf("failed to close reader: %w", err)
+		}
+		k.reader = nil
+		k.current++
+	}
+
+	if k.current >= len(k.ranges) {
+		return io.EOF
+	}
+
+	return k.open(k.ranges[k.current])
+}
+
+func (k *KafkaReplayIterator) open(r partitionRange) error {
+	conf, err := k.listener.readerConfig(k.topic, "")
+	if err != nil {
+		return err
+	}
+	conf.Partition = r.partition
+	if err := conf.Validate(); err != nil {
+		return fmt.Errorf("invalid reader config: %w", err)
+	}
+
+	reader := kafka.NewReader(conf)
+	if err := reader.SetOffset(r.start); err != nil {
+		reader.Close()
+		return fmt.Errorf("failed to set offset of partition %d: %w", r.partition, err)
+	}
+
+	k.reader = reader
+	k.next = r.start
+	return nil
+}
+
+// Close close the reader of the current partition
+func (k *KafkaReplayIterator) Close() error {
+	if k.reader == nil {
+		return nil
+	}
+
+	err := k.reader.Close()
+	k.reader = nil
+	k.current = len(k.ranges)
+	return err
+}
@@ -42,6 +42,9 @@ type (
 		// discard level will discard any of the log message received
 		// default: error
 		ErrorLogLevel string `json:"error_log_level" mapstructure:"error_log_level"`
+		// CloudEvents encode message as cloudevents v1.0 of the content mode: structured or binary,
+		// default encoding is used when empty
+		CloudEvents string `json:"cloud_events" mapstructure:"cloud_events"`
 
 		writer     *kafka.Writer
 		propagator propagation.TextMapPropagator
@@ -59,6 +62,10 @@ func NewKafkaSender(_ context.Context, config interface{}) (event.Sender, error)
 		kaf.Brokers = bks
 	}
 
+	if err := event.ValidateCloudEventsMode(kaf.CloudEvents); err != nil {
+		return nil, err
+	}
+
 	dialer, err := dial(kaf.CertFile, kaf.KeyFile, kaf.CACertificate, kaf.Username, kaf.Password, kaf.AuthType)
 	if err != nil {
 		return nil, err
@@ -113,24 +120,11 @@ func (k *KafkaSender) Send(ctx context.Context, message *event.EventMessage) err
 	)
 	defer span.End()
 
-	mb, err := message.ToBytes()
-
+	msg, err := k.newMessage(ctx, message)
 	if err != nil {
 		return err
 	}
 
-	msg := kafka.Message{
-		Topic: message.Topic,
-		Value: mb,
-	}
-
-	if message.Key != "" {
-		msg.Key = []byte(message.Key)
-	}
-
-	carrier := newKafkaMessageCarrier(&msg)
-	k.propagator.Inject(ctx, carrier)
-
 	err = k.writer.WriteMessages(ctx, msg)
 	if err != nil {
 
@@ -154,6 +148,37 @@ func (k *KafkaSender) Send(ctx context.Context, message *event.EventMessage) err
 	return nil
 }
 
+func (k *KafkaSender) newMessage(ctx context.Context, message *event.EventMessage) (kafka.Message, error) {
+	msg := kafka.Message{
+		Topic: message.Topic,
+	}
+
+	if message.Key != "" {
+		msg.Key = []byte(message.Key)
+	}
+
+	carrier := newKafkaMessageCarrier(&msg)
+
+	var err error
+	if k.CloudEvents != "" {
+		msg.Value, err = event.EncodeCloudEvents(message, k.CloudEvents, CloudEventsHeaderPrefix, carrier)
+	} else {
+		msg.Value, err = message.ToBytes()
+	}
+
+	if err != nil {
+		return msg, err
+	}
+
+	k.propagator.Inject(ctx, carrier)
+	return msg, nil
+}
+
+// Close flush pending messages and close the kafka writer
+func (k *KafkaSender) Close() error {
+	return k.writer.Close()
+}
+
 func newKafkaConsumeMessage(reader *kafka.Reader, message *KafkaMessageCarrier) *KafkaConsumeMessage {
 	return &KafkaConsumeMessage{
 		KafkaMessageCarrier: message,
@@ -176,6 +201,11 @@ func (k *KafkaConsumeMessage) GetEventConsumeMessage(ctx context.Context) (*even
 }
 
 func (k *KafkaConsumeMessage) Commit(ctx context.Context) error {
+	// replayed message has no reader as it is read without consumer group
+	if k.reader == nil {
+		return nil
+	}
+
 	if err := k.reader.CommitMessages(ctx, *k.Message); err != nil {
 		return fmt.Errorf("failed to commit message: %w", err)
 	}
@@ -183,8 +213,9 @@ func (k *KafkaConsumeMessage) Commit(ctx context.Context) error {
 	return nil
 }
 
+// parseMessage parse the default encoding or cloudevents of structured and binary content mode
 func (k *KafkaConsumeMessage) parseMessage(value []byte) (*event.EventConsumeMessage, error) {
-	return event.NewEventConsumeMessage(value)
+	return event.NewEventConsumeMessageFromHeaders(value, CloudEventsHeaderPrefix, k.KafkaMessageCarrier)
 }
 
 func getBalancer(balancer string, config map[string]interface{}) (kafka.Balancer, error) {
@@ -8,16 +8,21 @@ import (
 	"github.com/diki-haryadi/govega/database"
 	"github.com/diki-haryadi/govega/event"
 	"github.com/diki-haryadi/govega/util"
+	"github.com/google/uuid"
 	"github.com/mitchellh/mapstructure"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MongoSender struct {
 	Collection string      `json:"collection" mapstructure:"collection"`
 	Connection interface{} `json:"connection" mapstructure:"connection"`
-	store      *mongo.Collection
+	// ScheduleCollection collection of scheduled messages, required by emitter PublishAt
+	ScheduleCollection string `json:"schedule_collection" mapstructure:"schedule_collection"`
+	store              *mongo.Collection
+	schedule           *mongo.Collection
 }
 
 type MongoOutbox struct {
@@ -26,6 +31,14 @@ type MongoOutbox struct {
 	Key       string    `bson:"key,omitempty"`
 	Value     string    `bson:"value,omitempty"`
 	CreatedAt time.Time `bson:"created_at,omitempty"`
+	ClaimedBy string    `bson:"claimed_by,omitempty"`
+	ClaimedAt time.Time `bson:"claimed_at,omitempty"`
+}
+
+// MongoScheduled scheduled outbox model
+type MongoScheduled struct {
+	MongoOutbox `bson:",inline"`
+	DueAt       time.Time `bson:"due_at"`
 }
 
 func FromOutbox(out *event.OutboxRecord) *MongoOutbox {
@@ -65,25 +78,28 @@ func NewMongoOutbox(ctx context.Context, config interface{}) (*MongoSender, erro
 		return nil, errors.New("[event/mongo] missing collection param")
 	}
 
+	var db *mongo.Database
 	switch con := ms.Connection.(type) {
 	case *database.Database:
-		ms.store = con.Database.Collection(ms.Collection)
-		return &ms, nil
+		db = con.Database
 	case *database.Client:
-		db := database.MongoConnectClient(con)
-		ms.store = db.Database.Collection(ms.Collection)
-		return &ms, nil
+		db = database.MongoConnectClient(con).Database
 	case map[string]interface{}:
 		var conf database.Client
 		if err := util.DecodeJSON(con, &conf); err != nil {
 			return nil, err
 		}
-		db := database.MongoConnectClient(&conf)
-		ms.store = db.Database.Collection(ms.Collection)
-		return &ms, nil
+		db = database.MongoConnectClient(&conf).Database
 	default:
 		return nil, errors.New("[event/mongo] unsupported connection type")
 	}
+
+	ms.store = db.Collection(ms.Collection)
+	if ms.ScheduleCollection != "" {
+		ms.schedule = db.Collection(ms.ScheduleCollection)
+	}
+
+	return &ms, nil
 }
 
 func (m *MongoSender) Send(ctx context.Context, message *event.EventMessage) error {
@@ -113,3 +129,150 @@ func (m *MongoSender) Delete(ctx context.Context, message *event.EventMessage) e
 	_, err = m.store.DeleteOne(ctx, bson.D{primitive.E{Key: "_id", Value: outbox.ID}})
 	return err
 }
+
+// RelayOutbox claim outbox records by marking them with a claim token,
+// claim older than ClaimTimeout is considered abandoned and can be reclaimed
+func (m *MongoSender) RelayOutbox(ctx context.Context, opt *event.RelayOption, fn event.RelayFunc) (int, error) {
+	match := bson.M{"created_at": bson.M{"$lt": opt.CreatedBefore}}
+	return claim(ctx, m.store, match, "created_at", opt.Limit, opt.ClaimTimeout, fn)
+}
+
+// Schedule insert the outbox record into the schedule collection
+func (m *MongoSender) Schedule(ctx context.Context, record *event.OutboxRecord, dueAt time.Time) error {
+	if m.schedule == nil {
+		return errors.New("[event/mongo] missing schedule_collection param")
+	}
+
+	_, err := m.schedule.InsertOne(ctx, &MongoScheduled{MongoOutbox: *FromOutbox(record), DueAt: dueAt})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// CancelSchedule delete scheduled records of the topic and key which are not claimed by a scheduler
+func (m *MongoSender) CancelSchedule(ctx context.Context, topic, key string) (int, error) {
+	if m.schedule == nil {
+		return 0, errors.New("[event/mongo] missing schedule_collection param")
+	}
+
+	res, err := m.schedule.DeleteMany(ctx, bson.M{
+		"topic":      topic,
+		"key":        key,
+		"claimed_by": bson.M{"$exists": false},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(res.DeletedCount), nil
+}
+
+// ReleaseDue claim due scheduled records by marking them with a claim token, see RelayOutbox
+func (m *MongoSender) ReleaseDue(ctx context.Context, opt *event.ReleaseOption, fn event.RelayFunc) (int, error) {
+	if m.schedule == nil {
+		return 0, errors.New("[event/mongo] missing schedule_collection param")
+	}
+
+	match := bson.M{"due_at": bson.M{"$lte": opt.DueBefore}}
+	return claim(ctx, m.schedule, match, "due_at", opt.Limit, opt.ClaimTimeout, fn)
+}
+
+// claim mark records matching the filter with a claim token and pass them to fn ordered by the sort field,
+// record is deleted when fn succeed and unclaimed otherwise
+func claim(ctx context.Context, store *mongo.Collection, match bson.M, sortField string, limit int,
+	claimTimeout time.Duration, fn event.RelayFunc) (int, error) {
+	now := time.Now()
+	token := uuid.New().String()
+
+	claimable := bson.M{
+		"$or": bson.A{
+			bson.M{"claimed_at": bson.M{"$exists": false}},
+			bson.M{"claimed_at": bson.M{"$lt": now.Add(-claimTimeout)}},
+		},
+	}
+	for k, v := range match {
+		claimable[k] = v
+	}
+
+	sort := bson.D{primitive.E{Key: sortField, Value: 1}}
+
+	findOpt := options.Find().
+		SetSort(sort).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"_id": 1})
+
+	cur, err := store.Find(ctx, claimable, findOpt)
+	if err != nil {
+		return 0, err
+	}
+
+	var candidates []MongoOutbox
+	if err := cur.All(ctx, &candidates); err != nil {
+		return 0, err
+	}
+
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+
+	ids := make(bson.A, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.ID
+	}
+
+	// re-check claimable condition so record claimed by another relay in between is skipped
+	claimable["_id"] = bson.M{"$in": ids}
+	_, err = store.UpdateMany(ctx, claimable, bson.M{
+		"$set": bson.M{"claimed_by": token, "claimed_at": now},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	cur, err = store.Find(ctx, bson.M{"claimed_by": token}, options.Find().SetSort(sort))
+	if err != nil {
+		return 0, err
+	}
+
+	var claimed []MongoOutbox
+	if err := cur.All(ctx, &claimed); err != nil {
+		return 0, err
+	}
+
+	relayed := 0
+	for _, o := range claimed {
+		record := &event.OutboxRecord{
+			ID:        o.ID,
+			Topic:     o.Topic,
+			Key:       o.Key,
+			Value:     o.Value,
+			CreatedAt: o.CreatedAt,
+		}
+
+		filter := bson.D{
+			primitive.E{Key: "_id", Value: o.ID},
+			primitive.E{Key: "claimed_by", Value: token},
+		}
+
+		if err := fn(ctx, record); err != nil {
+			if _, err := store.UpdateOne(ctx, filter, bson.M{
+				"$unset": bson.M{"claimed_by": "", "claimed_at": ""},
+			}); err != nil {
+				return relayed, err
+			}
+			continue
+		}
+
+		if _, err := store.DeleteOne(ctx, filter); err != nil {
+			return relayed, err
+		}
+		relayed++
+	}
+
+	return relayed, nil
+}
@@ -112,3 +112,96 @@ func TestMongoWriter(t *testing.T) {
 	assert.Equal(t, err, mongo.ErrNoDocuments)
 	db.Database.Collection("outbox").DeleteMany(ctx, bson.D{})
 }
+
+func TestMongoRelayOutbox(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	mconf := database.Client{
+		URI:     "mongodb://localhost:27017",
+		DB:      "test",
+		AppName: "event",
+	}
+	db := database.MongoConnectClient(&mconf)
+
+	ctx := context.Background()
+	writer, err := NewMongoOutbox(ctx, map[string]interface{}{
+		"collection": "outbox",
+		"connection": db,
+	})
+	require.Nil(t, err)
+
+	key := fmt.Sprintf("%v", time.Now().Unix())
+	require.Nil(t, writer.Send(ctx, &event.EventMessage{Topic: "test", Key: key, Data: "testdata"}))
+
+	var buf bytes.Buffer
+	logrus.SetOutput(&buf)
+
+	sender, err := event.NewEventLogger(ctx, nil)
+	require.Nil(t, err)
+
+	relay, err := event.NewOutboxRelay(writer, sender, &event.OutboxRelayConfig{MinAge: "0s"})
+	require.Nil(t, err)
+
+	n, err := relay.RelayOnce(ctx)
+	require.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Contains(t, buf.String(), "key="+key)
+
+	err = db.Database.Collection("outbox").FindOne(ctx, bson.M{"key": key}).Err()
+	assert.Equal(t, mongo.ErrNoDocuments, err)
+	db.Database.Collection("outbox").DeleteMany(ctx, bson.D{})
+}
+
+func TestMongoSchedule(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	mconf := database.Client{
+		URI:     "mongodb://localhost:27017",
+		DB:      "test",
+		AppName: "event",
+	}
+	db := database.MongoConnectClient(&mconf)
+
+	ctx := context.Background()
+	writer, err := NewMongoOutbox(ctx, map[string]interface{}{
+		"collection":          "outbox",
+		"schedule_collection": "outbox_scheduled",
+		"connection":          db,
+	})
+	require.Nil(t, err)
+	defer db.Database.Collection("outbox_scheduled").DeleteMany(ctx, bson.D{})
+
+	now := time.Now()
+	for _, key := range []string{"due", "later", "canceled"} {
+		rec, err := event.OutboxFromMessage(&event.EventMessage{Topic: "test", Key: key, Data: "testdata"})
+		require.Nil(t, err)
+
+		dueAt := now.Add(-time.Minute)
+		if key != "due" {
+			dueAt = now.Add(time.Hour)
+		}
+		require.Nil(t, writer.Schedule(ctx, rec, dueAt))
+	}
+
+	n, err := writer.CancelSchedule(ctx, "test", "canceled")
+	require.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	var released []string
+	n, err = writer.ReleaseDue(ctx, &event.ReleaseOption{DueBefore: now, Limit: 10, ClaimTimeout: time.Minute},
+		func(ctx context.Context, record *event.OutboxRecord) error {
+			released = append(released, record.Key)
+			return nil
+		})
+	require.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"due"}, released)
+
+	count, err := db.Database.Collection("outbox_scheduled").CountDocuments(ctx, bson.M{"key": "later"})
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+}
@@ -3,6 +3,7 @@ package event
 import (
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -42,3 +43,25 @@ func OutboxFromMessage(msg *EventMessage) (*OutboxRecord, error) {
 		Value: string(mb),
 	}).GenerateID(), nil
 }
+
+// ToMessage convert outbox record back into event message
+// Data is kept as raw json so it will be sent exactly as it was written
+func (o *OutboxRecord) ToMessage() (*EventMessage, error) {
+	var readmsg eventConsumeMessageRead
+	if err := json.Unmarshal([]byte(o.Value), &readmsg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal outbox value: %w", err)
+	}
+
+	msg := &EventMessage{
+		Topic:    o.Topic,
+		Key:      o.Key,
+		Metadata: readmsg.Metadata,
+		RawData:  readmsg.Data,
+	}
+
+	if len(readmsg.Data) > 0 {
+		msg.Data = readmsg.Data
+	}
+
+	return msg, nil
+}
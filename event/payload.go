@@ -0,0 +1,264 @@
+package event
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/diki-haryadi/govega/file"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// MetaCompression compression algorithm applied to the payload
+	MetaCompression = "compression"
+	// MetaEncrypted flag of encrypted payload
+	MetaEncrypted = "encrypted"
+	// MetaKeyID id of the key used to encrypt the payload
+	MetaKeyID = "key_id"
+
+	CompressionGzip   = "gzip"
+	CompressionZstd   = "zstd"
+	CompressionSnappy = "snappy"
+
+	// DefaultEncryptionChunkSize chunk size of the AES encryptor created from the config keys,
+	// message encrypted with different chunk size can't be decrypted
+	DefaultEncryptionChunkSize = 32 * 1024
+)
+
+// ErrUnknownPayloadKey returned when the consumer doesn't have the key of the encrypted payload,
+// it is retryable since missing key is a config error rather than a poison message
+var ErrUnknownPayloadKey = errors.New("[event/payload] unknown key")
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+type (
+	PayloadConfig struct {
+		// Compression algorithm of the published payload: gzip, zstd or snappy, default: no compression
+		Compression string `json:"compression" mapstructure:"compression"`
+		// MinSize compress only payload larger than this size in bytes, default: 0 always compress
+		MinSize int `json:"min_size" mapstructure:"min_size"`
+		// Encryption encrypt the published payload and decrypt the consumed payload
+		Encryption *EncryptionConfig `json:"encryption" mapstructure:"encryption"`
+	}
+
+	EncryptionConfig struct {
+		// KeyID id of the key used to encrypt the published payload, required by emitter
+		KeyID string `json:"key_id" mapstructure:"key_id"`
+		// Keys secret of the AES encryptor by key id, keep the rotated keys
+		// to decrypt messages published before the rotation
+		Keys map[string]string `json:"keys" mapstructure:"keys"`
+	}
+
+	// PayloadTransformer compress and encrypt published payload, consumed payload is decrypted
+	// and decompressed based on the metadata flags
+	PayloadTransformer struct {
+		compression string
+		minSize     int
+		keyID       string
+		encryptors  map[string]file.Encryptor
+	}
+)
+
+// NewPayloadTransformer create payload transformer from the config,
+// transformer without config only decompress consumed payload
+func NewPayloadTransformer(config *PayloadConfig) (*PayloadTransformer, error) {
+	p := &PayloadTransformer{encryptors: make(map[string]file.Encryptor)}
+	if config == nil {
+		return p, nil
+	}
+
+	switch config.Compression {
+	case "", CompressionGzip, CompressionZstd, CompressionSnappy:
+		p.compression = config.Compression
+	default:
+		return nil, fmt.Errorf("[event/payload] unsupported compression: %s", config.Compression)
+	}
+	p.minSize = config.MinSize
+
+	if config.Encryption != nil {
+		for id, secret := range config.Encryption.Keys {
+			p.encryptors[id] = &file.AESEncryptor{Secret: secret, ChunkSize: DefaultEncryptionChunkSize}
+		}
+
+		if config.Encryption.KeyID != "" {
+			if _, ok := p.encryptors[config.Encryption.KeyID]; !ok {
+				return nil, fmt.Errorf("[event/payload] missing key: %s", config.Encryption.KeyID)
+			}
+			p.keyID = config.Encryption.KeyID
+		}
+	}
+
+	return p, nil
+}
+
+// WithEncryptor add encryptor of the key id, published payload is encrypted with the key
+// when active is true
+func (p *PayloadTransformer) WithEncryptor(keyID string, encryptor file.Encryptor, active bool) {
+	p.encryptors[keyID] = encryptor
+	if active {
+		p.keyID = keyID
+	}
+}
+
+// Encode compress then encrypt the message data and set the metadata flags,
+// transformed data is sent as base64 string
+func (p *PayloadTransformer) Encode(message *EventMessage) error {
+	if p.compression == "" && p.keyID == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(message.Data)
+	if err != nil {
+		return err
+	}
+
+	compression := ""
+	if p.compression != "" && len(data) > p.minSize {
+		if data, err = compress(p.compression, data); err != nil {
+			return fmt.Errorf("[event/payload] failed to compress payload: %w", err)
+		}
+		compression = p.compression
+	}
+
+	if p.keyID != "" {
+		var buf bytes.Buffer
+		if err := p.encryptors[p.keyID].Encrypt(bytes.NewReader(data), &buf); err != nil {
+			return fmt.Errorf("[event/payload] failed to encrypt payload: %w", err)
+		}
+		data = buf.Bytes()
+	}
+
+	if compression == "" && p.keyID == "" {
+		return nil
+	}
+
+	if message.Metadata == nil {
+		message.Metadata = make(map[string]interface{})
+	}
+
+	if compression != "" {
+		message.Metadata[MetaCompression] = compression
+	}
+
+	if p.keyID != "" {
+		message.Metadata[MetaEncrypted] = true
+		message.Metadata[MetaKeyID] = p.keyID
+	}
+
+	message.Data = data
+	return nil
+}
+
+// Decode decrypt then decompress the message data based on the metadata flags, the flags are
+// removed once decoded. Failure is non retryable except ErrUnknownPayloadKey
+func (p *PayloadTransformer) Decode(message *EventConsumeMessage) error {
+	compression, _ := message.Metadata[MetaCompression].(string)
+	// flag is string on cloudevents binary mode
+	encrypted := fmt.Sprint(message.Metadata[MetaEncrypted]) == "true"
+	if compression == "" && !encrypted {
+		return nil
+	}
+
+	var data []byte
+	if err := json.Unmarshal(message.Data, &data); err != nil {
+		return NonRetryable(fmt.Errorf("[event/payload] invalid transformed payload: %w", err))
+	}
+
+	if encrypted {
+		keyID, _ := message.Metadata[MetaKeyID].(string)
+		encryptor, ok := p.encryptors[keyID]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownPayloadKey, keyID)
+		}
+
+		var buf bytes.Buffer
+		if err := encryptor.Decrypt(bytes.NewReader(data), &buf); err != nil {
+			return NonRetryable(fmt.Errorf("[event/payload] failed to decrypt payload: %w", err))
+		}
+		data = buf.Bytes()
+	}
+
+	if compression != "" {
+		var err error
+		if data, err = decompress(compression, data); err != nil {
+			return NonRetryable(fmt.Errorf("[event/payload] failed to decompress payload: %w", err))
+		}
+	}
+
+	delete(message.Metadata, MetaCompression)
+	delete(message.Metadata, MetaEncrypted)
+	delete(message.Metadata, MetaKeyID)
+
+	message.Data = data
+	return nil
+}
+
+// Middleware decode the message before passing it to the handler
+func (p *PayloadTransformer) Middleware(next EventHandler) EventHandler {
+	return func(ctx context.Context, message *EventConsumeMessage) error {
+		if err := p.Decode(message); err != nil {
+			return err
+		}
+		return next(ctx, message)
+	}
+}
+
+// BatchMiddleware decode all messages of the batch before passing them to the handler
+func (p *PayloadTransformer) BatchMiddleware(next BatchEventHandler) BatchEventHandler {
+	return func(ctx context.Context, messages []*EventConsumeMessage) error {
+		for _, m := range messages {
+			if err := p.Decode(m); err != nil {
+				return err
+			}
+		}
+		return next(ctx, messages)
+	}
+}
+
+func compress(algorithm string, data []byte) ([]byte, error) {
+	switch algorithm {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CompressionZstd:
+		return zstdEncoder.EncodeAll(data, nil), nil
+	case CompressionSnappy:
+		return snappy.Encode(nil, data), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", algorithm)
+	}
+}
+
+func decompress(algorithm string, data []byte) ([]byte, error) {
+	switch algorithm {
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case CompressionZstd:
+		return zstdDecoder.DecodeAll(data, nil)
+	case CompressionSnappy:
+		return snappy.Decode(nil, data)
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", algorithm)
+	}
+}
@@ -0,0 +1,196 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/diki-haryadi/govega/file"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// consume convert the published message into consumed message
+func consume(t *testing.T, message *EventMessage) *EventConsumeMessage {
+	b, err := message.ToBytes()
+	require.NoError(t, err)
+
+	em, err := NewEventConsumeMessage(b)
+	require.NoError(t, err)
+	return em
+}
+
+// payloadTestListener test listener which stop iterating once it is closed
+type payloadTestListener struct {
+	*testListener
+}
+
+func (l *payloadTestListener) factory(ctx context.Context, config interface{}) (Listener, error) {
+	return l, nil
+}
+
+func (l *payloadTestListener) Listen(ctx context.Context, topic, group string) (Iterator, error) {
+	return l, nil
+}
+
+func (l *payloadTestListener) Next(ctx context.Context) (ConsumeMessage, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case msg, ok := <-l.ch:
+		if !ok {
+			return nil, errors.New("listener closed")
+		}
+		return msg, nil
+	}
+}
+
+func TestPayloadTransformer(t *testing.T) {
+	order := testOrder{ID: strings.Repeat("o", 100), Amount: 10}
+
+	cases := []struct {
+		name   string
+		config *PayloadConfig
+	}{
+		{"gzip", &PayloadConfig{Compression: CompressionGzip}},
+		{"zstd", &PayloadConfig{Compression: CompressionZstd}},
+		{"snappy", &PayloadConfig{Compression: CompressionSnappy}},
+		{"encrypt", &PayloadConfig{Encryption: &EncryptionConfig{KeyID: "k1", Keys: map[string]string{"k1": "secret"}}}},
+		{"compress and encrypt", &PayloadConfig{
+			Compression: CompressionZstd,
+			Encryption:  &EncryptionConfig{KeyID: "k1", Keys: map[string]string{"k1": "secret"}},
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p, err := NewPayloadTransformer(c.config)
+			require.NoError(t, err)
+
+			msg := &EventMessage{Topic: "order", Data: order, Metadata: map[string]interface{}{MetaEvent: "order"}}
+			require.NoError(t, p.Encode(msg))
+
+			b, err := msg.ToBytes()
+			require.NoError(t, err)
+			assert.NotContains(t, string(b), order.ID, "payload is transformed")
+
+			assert.Equal(t, c.config.Compression != "", msg.Metadata[MetaCompression] == c.config.Compression)
+			if c.config.Encryption != nil {
+				assert.Equal(t, true, msg.Metadata[MetaEncrypted])
+				assert.Equal(t, "k1", msg.Metadata[MetaKeyID])
+			}
+
+			em := consume(t, msg)
+			require.NoError(t, p.Decode(em))
+
+			var received testOrder
+			require.NoError(t, decodePayload(JSONCodec{}, em.Data, &received))
+			assert.Equal(t, order, received)
+			assert.NotContains(t, em.Metadata, MetaCompression, "flags are removed once decoded")
+			assert.NotContains(t, em.Metadata, MetaEncrypted)
+		})
+	}
+}
+
+func TestPayloadKeyRotation(t *testing.T) {
+	old, err := NewPayloadTransformer(&PayloadConfig{
+		Encryption: &EncryptionConfig{KeyID: "k1", Keys: map[string]string{"k1": "secret1"}},
+	})
+	require.NoError(t, err)
+
+	rotated, err := NewPayloadTransformer(&PayloadConfig{
+		Encryption: &EncryptionConfig{KeyID: "k2", Keys: map[string]string{"k1": "secret1", "k2": "secret2"}},
+	})
+	require.NoError(t, err)
+
+	msg := &EventMessage{Data: "testdata"}
+	require.NoError(t, old.Encode(msg))
+
+	em := consume(t, msg)
+	require.NoError(t, rotated.Decode(em), "message encrypted with the rotated key is decrypted")
+	assert.JSONEq(t, `"testdata"`, string(em.Data))
+
+	msg = &EventMessage{Data: "testdata"}
+	require.NoError(t, rotated.Encode(msg))
+	assert.Equal(t, "k2", msg.Metadata[MetaKeyID])
+
+	err = old.Decode(consume(t, msg))
+	assert.ErrorIs(t, err, ErrUnknownPayloadKey)
+	assert.False(t, IsNonRetryable(err), "unknown key is retried until the key is configured")
+
+	custom, err := NewPayloadTransformer(nil)
+	require.NoError(t, err)
+	custom.WithEncryptor("k2", &file.AESEncryptor{Secret: "secret2", ChunkSize: DefaultEncryptionChunkSize}, false)
+
+	em = consume(t, msg)
+	require.NoError(t, custom.Decode(em))
+	assert.JSONEq(t, `"testdata"`, string(em.Data))
+}
+
+func TestPayloadMinSize(t *testing.T) {
+	p, err := NewPayloadTransformer(&PayloadConfig{Compression: CompressionGzip, MinSize: 100})
+	require.NoError(t, err)
+
+	msg := &EventMessage{Data: "small"}
+	require.NoError(t, p.Encode(msg))
+	assert.Equal(t, "small", msg.Data)
+	assert.Empty(t, msg.Metadata)
+
+	_, err = NewPayloadTransformer(&PayloadConfig{Compression: "lz4"})
+	assert.Error(t, err)
+
+	_, err = NewPayloadTransformer(&PayloadConfig{Encryption: &EncryptionConfig{KeyID: "missing"}})
+	assert.Error(t, err)
+}
+
+func TestPayloadEmitterConsumer(t *testing.T) {
+	sender := &testSender{}
+	RegisterSender("payload-test", func(ctx context.Context, config interface{}) (Sender, error) {
+		return sender, nil
+	})
+
+	payload := &PayloadConfig{
+		Compression: CompressionSnappy,
+		Encryption:  &EncryptionConfig{KeyID: "k1", Keys: map[string]string{"k1": "secret"}},
+	}
+
+	ctx := context.Background()
+	em, err := New(ctx, &EmitterConfig{Sender: &DriverConfig{Type: "payload-test"}, Payload: payload})
+	require.NoError(t, err)
+	require.NoError(t, em.Publish(ctx, "test", "k1", "testdata", nil))
+	require.Equal(t, 1, sender.count())
+
+	plain, err := hash("testdata")
+	require.NoError(t, err)
+	encrypted, err := hash(sender.sent[0].Data)
+	require.NoError(t, err)
+	assert.NotEqual(t, plain, sender.sent[0].Metadata[MetaHash], "plain payload hash is not published")
+	assert.Equal(t, encrypted, sender.sent[0].Metadata[MetaHash])
+
+	listener := &payloadTestListener{newTestListener()}
+	RegisterListener("payload-test", listener.factory)
+
+	consumer, err := NewConsumer(ctx, &ConsumerConfig{Listener: &DriverConfig{Type: "payload-test"}, Payload: payload})
+	require.NoError(t, err)
+
+	received := make(chan *EventConsumeMessage, 1)
+	consumer.Use(func(next EventHandler) EventHandler {
+		return func(ctx context.Context, message *EventConsumeMessage) error {
+			assert.JSONEq(t, `"testdata"`, string(message.Data), "middleware receive decoded payload")
+			return next(ctx, message)
+		}
+	})
+	require.NoError(t, consumer.Subscribe(ctx, "test", "group", func(ctx context.Context, message *EventConsumeMessage) error {
+		received <- message
+		return nil
+	}))
+	require.NoError(t, consumer.Start())
+	defer consumer.Stop()
+
+	listener.sendMessage(newTestConsumeMessage(consume(t, sender.sent[0])))
+
+	message := <-received
+	assert.JSONEq(t, `"testdata"`, string(message.Data))
+	assert.Equal(t, "test", GetMetadataEvent(message.Metadata))
+}
@@ -0,0 +1,207 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/diki-haryadi/govega/event"
+	"github.com/diki-haryadi/govega/log"
+	"github.com/mitchellh/mapstructure"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	"gocloud.dev/pubsub"
+	"gocloud.dev/pubsub/kafkapubsub"
+)
+
+type (
+	PubsubListener struct {
+		Schema       string `json:"schema" mapstructure:"schema"`
+		KafkaBrokers string `json:"kafka_brokers" mapstructure:"kafka_brokers"`
+		// Subscription url of the consumer group subscription, {topic} and {group} are replaced
+		// with the topic and group name, e.g. gcppubsub://projects/myproject/subscriptions/{group}.
+		// Default to schema + topic
+		Subscription string `json:"subscription" mapstructure:"subscription"`
+
+		lock          sync.Mutex
+		subscriptions map[string]*sharedSubscription
+	}
+
+	// sharedSubscription subscription shared by members of the same group,
+	// shutdown when all of the members are closed
+	sharedSubscription struct {
+		*pubsub.Subscription
+		members int
+	}
+
+	PubsubIterator struct {
+		listener     *PubsubListener
+		subscription *pubsub.Subscription
+		key          string
+		topic        string
+		tracer       trace.Tracer
+		propagator   propagation.TextMapPropagator
+		closeOnce    sync.Once
+	}
+
+	PubsubConsumeMessage struct {
+		*PubsubMessageCarrier
+		topic  string
+		prefix string
+		once   sync.Once
+	}
+)
+
+func NewPubsubListener(_ context.Context, config interface{}) (event.Listener, error) {
+	var listener PubsubListener
+	if err := mapstructure.Decode(config, &listener); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	listener.subscriptions = make(map[string]*sharedSubscription)
+	return &listener, nil
+}
+
+func (p *PubsubListener) Listen(ctx context.Context, topic, group string) (event.Iterator, error) {
+	key := topic + "/" + group
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	sub, ok := p.subscriptions[key]
+	if !ok {
+		s, err := p.openSubscription(ctx, topic, group)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open subscription: %w", err)
+		}
+		sub = &sharedSubscription{Subscription: s}
+		p.subscriptions[key] = sub
+	}
+	sub.members++
+
+	return &PubsubIterator{
+		listener:     p,
+		subscription: sub.Subscription,
+		key:          key,
+		topic:        topic,
+		tracer:       otel.Tracer("event/consumer"),
+		propagator:   otel.GetTextMapPropagator(),
+	}, nil
+}
+
+func (p *PubsubListener) openSubscription(ctx context.Context, topic, group string) (*pubsub.Subscription, error) {
+	if p.Schema == "kafka://" {
+		config := kafkapubsub.MinimalConfig()
+		return kafkapubsub.OpenSubscription(strings.Split(p.KafkaBrokers, ","), config, group,
+			[]string{topic}, &kafkapubsub.SubscriptionOptions{KeyName: "key"})
+	}
+
+	url := p.Schema + topic
+	if p.Subscription != "" {
+		url = strings.NewReplacer("{topic}", topic, "{group}", group).Replace(p.Subscription)
+	}
+
+	return pubsub.OpenSubscription(ctx, url)
+}
+
+func (p *PubsubListener) release(key string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	sub, ok := p.subscriptions[key]
+	if !ok {
+		return nil
+	}
+
+	sub.members--
+	if sub.members > 0 {
+		return nil
+	}
+
+	delete(p.subscriptions, key)
+	return sub.Shutdown(context.Background())
+}
+
+func (p *PubsubIterator) Next(ctx context.Context) (event.ConsumeMessage, error) {
+	msg, err := p.subscription.Receive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive message: %w", err)
+	}
+
+	// metadata may be shared with the other subscriptions of the topic, copy it before injecting the span
+	md := make(map[string]string, len(msg.Metadata))
+	for k, v := range msg.Metadata {
+		md[k] = v
+	}
+	msg.Metadata = md
+
+	carrier := newPubsubMessageCarrier(msg)
+	parentSpanContext := p.propagator.Extract(ctx, carrier)
+
+	consumeMessage := &PubsubConsumeMessage{
+		PubsubMessageCarrier: carrier,
+		topic:                p.topic,
+		prefix:               cloudEventsPrefix(p.listener.Schema),
+	}
+
+	attrs := []attribute.KeyValue{
+		semconv.MessagingSystemKey.String("pubsub"),
+		semconv.MessagingDestinationKindTopic,
+		semconv.MessagingDestinationKey.String(p.topic),
+		semconv.MessagingOperationReceive,
+		semconv.MessagingMessageIDKey.String(msg.LoggableID),
+		semconv.MessagingConversationIDKey.String(carrier.Get("key")),
+	}
+
+	newctx, span := p.tracer.Start(parentSpanContext, "pubsub.consume."+p.topic,
+		trace.WithAttributes(attrs...), trace.WithSpanKind(trace.SpanKindConsumer))
+	p.propagator.Inject(newctx, consumeMessage)
+	span.End()
+
+	return consumeMessage, nil
+}
+
+// Close release the subscription, the subscription is shutdown once all of the group members are closed
+func (p *PubsubIterator) Close() error {
+	var err error
+	p.closeOnce.Do(func() {
+		err = p.listener.release(p.key)
+	})
+	return err
+}
+
+func (p *PubsubConsumeMessage) GetEventConsumeMessage(ctx context.Context) (*event.EventConsumeMessage, error) {
+	em, err := event.NewEventConsumeMessageFromHeaders(p.Body, p.prefix, p.PubsubMessageCarrier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode message: %w", err)
+	}
+
+	em.Topic = p.topic
+	if key := p.Get("key"); key != "" {
+		em.Key = key
+	}
+
+	return em, nil
+}
+
+// Commit ack the message
+func (p *PubsubConsumeMessage) Commit(ctx context.Context) error {
+	p.once.Do(p.Ack)
+	return nil
+}
+
+// Nack the message so it is redelivered, ignored if the message is already acked or
+// the driver doesn't support nack, unacked message is redelivered after the ack deadline
+func (p *PubsubConsumeMessage) Nack(ctx context.Context) error {
+	if !p.Nackable() {
+		log.WithContext(ctx).Debugln("[event/pubsub] nack is not supported by the driver")
+		return nil
+	}
+
+	p.once.Do(p.Message.Nack)
+	return nil
+}
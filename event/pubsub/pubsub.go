@@ -2,6 +2,8 @@ package pubsub
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"strings"
 
@@ -15,11 +17,21 @@ import (
 	"gocloud.dev/pubsub/kafkapubsub"
 )
 
+const (
+	// CloudEventsHeaderPrefix prefix of cloudevents attribute metadata on binary content mode
+	CloudEventsHeaderPrefix = "ce-"
+	// KafkaCloudEventsHeaderPrefix prefix of cloudevents attribute headers on kafka schema
+	KafkaCloudEventsHeaderPrefix = "ce_"
+)
+
 type PubsubSender struct {
 	topics       map[string]*pubsub.Topic
 	Schema       string `json:"schema" mapstructure:"schema"`
 	KafkaBrokers string `json:"kafka_brokers" mapstructure:"kafka_brokers"`
-	propagator   propagation.TextMapPropagator
+	// CloudEvents encode message as cloudevents v1.0 of the content mode: structured or binary,
+	// default encoding is used when empty
+	CloudEvents string `json:"cloud_events" mapstructure:"cloud_events"`
+	propagator  propagation.TextMapPropagator
 }
 
 type PubsubMessageCarrier struct {
@@ -28,6 +40,7 @@ type PubsubMessageCarrier struct {
 
 func init() {
 	event.RegisterSender("pubsub", NewPubsubSender)
+	event.RegisterListener("pubsub", NewPubsubListener)
 }
 
 func NewPubsubSender(ctx context.Context, config interface{}) (event.Sender, error) {
@@ -36,6 +49,10 @@ func NewPubsubSender(ctx context.Context, config interface{}) (event.Sender, err
 		return nil, err
 	}
 
+	if err := event.ValidateCloudEventsMode(pub.CloudEvents); err != nil {
+		return nil, err
+	}
+
 	if pub.Schema == "kafka://" {
 		os.Setenv("KAFKA_BROKERS", pub.KafkaBrokers)
 	}
@@ -83,17 +100,22 @@ func (p *PubsubSender) Send(ctx context.Context, message *event.EventMessage) er
 		span.RecordError(err)
 		return err
 	}
-	mb, err := message.ToBytes()
+	msg := &pubsub.Message{}
+	if message.Key != "" {
+		msg.Metadata = map[string]string{"key": message.Key}
+	}
+	carrier := newPubsubMessageCarrier(msg)
+
+	if p.CloudEvents != "" {
+		msg.Body, err = event.EncodeCloudEvents(message, p.CloudEvents, cloudEventsPrefix(p.Schema), carrier)
+	} else {
+		msg.Body, err = message.ToBytes()
+	}
 
 	if err != nil {
 		span.RecordError(err)
 		return err
 	}
-	msg := &pubsub.Message{Body: mb}
-	if message.Key != "" {
-		msg.Metadata = map[string]string{"key": message.Key}
-	}
-	carrier := newPubsubMessageCarrier(msg)
 	p.propagator.Inject(ctx, carrier)
 
 	if err := topic.Send(ctx, msg); err != nil {
@@ -105,6 +127,26 @@ func (p *PubsubSender) Send(ctx context.Context, message *event.EventMessage) er
 
 }
 
+// Close shutdown opened topics
+func (p *PubsubSender) Close() error {
+	var errs []error
+	for name, topic := range p.topics {
+		if err := topic.Shutdown(context.Background()); err != nil {
+			errs = append(errs, fmt.Errorf("failed to shutdown topic %s: %w", name, err))
+		}
+		delete(p.topics, name)
+	}
+	return errors.Join(errs...)
+}
+
+// cloudEventsPrefix return cloudevents attribute prefix of the schema, kafka use the kafka protocol binding prefix
+func cloudEventsPrefix(schema string) string {
+	if schema == "kafka://" {
+		return KafkaCloudEventsHeaderPrefix
+	}
+	return CloudEventsHeaderPrefix
+}
+
 func newPubsubMessageCarrier(msg *pubsub.Message) *PubsubMessageCarrier {
 	return &PubsubMessageCarrier{
 		Message: msg,
@@ -118,16 +160,18 @@ func (k PubsubMessageCarrier) Get(key string) string {
 
 // Set sets a header.
 func (k PubsubMessageCarrier) Set(key, val string) {
+	if k.Metadata == nil {
+		k.Metadata = make(map[string]string)
+	}
 	// Ensure uniqueness of keys
 	k.Metadata[key] = val
 }
 
 // Keys returns a slice of all key identifiers in the carrier.
 func (k PubsubMessageCarrier) Keys() []string {
-	out := make([]string, len(k.Metadata))
-	i := 0
-	for _, h := range k.Metadata {
-		out[i] = h
+	out := make([]string, 0, len(k.Metadata))
+	for key := range k.Metadata {
+		out = append(out, key)
 	}
 	return out
 }
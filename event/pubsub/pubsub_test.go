@@ -0,0 +1,200 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/diki-haryadi/govega/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"gocloud.dev/pubsub"
+	_ "gocloud.dev/pubsub/mempubsub"
+)
+
+func openTopic(t *testing.T, name string) {
+	// mem subscription requires the topic to exist, the topic is kept by the mem driver
+	_, err := pubsub.OpenTopic(context.Background(), "mem://"+name)
+	require.NoError(t, err)
+}
+
+func TestPubsubEmitterConsumer(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	ctx := context.Background()
+	openTopic(t, "pubsub-test")
+
+	em, err := event.New(ctx, &event.EmitterConfig{
+		Sender: &event.DriverConfig{
+			Type:   "pubsub",
+			Config: map[string]interface{}{"schema": "mem://"},
+		},
+	})
+	require.NoError(t, err)
+
+	consumer, err := event.NewConsumer(ctx, &event.ConsumerConfig{
+		Listener: &event.DriverConfig{
+			Type:   "pubsub",
+			Config: map[string]interface{}{"schema": "mem://"},
+		},
+		ConsumeStrategy: &event.DriverConfig{Type: "commit_on_success"},
+	})
+	require.NoError(t, err)
+
+	traceID := trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+
+	var lock sync.Mutex
+	received := map[string][]string{}
+	attempts := map[string]int{}
+	traces := map[string]trace.TraceID{}
+
+	handler := func(group string) event.EventHandler {
+		return func(ctx context.Context, message *event.EventConsumeMessage) error {
+			lock.Lock()
+			defer lock.Unlock()
+
+			attempts[group+message.Key]++
+			if group == "group2" && message.Key == "b" && attempts[group+message.Key] == 1 {
+				return errors.New("failed")
+			}
+
+			received[group] = append(received[group], message.Key)
+			traces[group+message.Key] = trace.SpanContextFromContext(ctx).TraceID()
+			assert.Equal(t, "pubsub-test", message.Topic)
+			assert.JSONEq(t, `"testdata"`, string(message.Data))
+			return nil
+		}
+	}
+
+	require.NoError(t, consumer.Subscribe(ctx, "pubsub-test", "group1", handler("group1")))
+	require.NoError(t, consumer.Subscribe(ctx, "pubsub-test", "group2", handler("group2")))
+	require.NoError(t, consumer.Start())
+
+	pctx := trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, em.Publish(pctx, "pubsub-test", key, "testdata", nil))
+	}
+
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(received["group1"]) == 3 && len(received["group2"]) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, consumer.Stop())
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	for _, group := range []string{"group1", "group2"} {
+		sort.Strings(received[group])
+		assert.Equal(t, []string{"a", "b", "c"}, received[group], group)
+	}
+	assert.Equal(t, 2, attempts["group2b"], "nacked message is redelivered")
+	assert.Equal(t, 1, attempts["group1b"])
+	assert.Equal(t, traceID, traces["group1a"])
+}
+
+func TestPubsubGroupMembers(t *testing.T) {
+	ctx := context.Background()
+	openTopic(t, "pubsub-members")
+
+	listener, err := NewPubsubListener(ctx, map[string]interface{}{
+		"schema":       "mem://",
+		"subscription": "mem://{topic}",
+	})
+	require.NoError(t, err)
+
+	it1, err := listener.Listen(ctx, "pubsub-members", "group")
+	require.NoError(t, err)
+	it2, err := listener.Listen(ctx, "pubsub-members", "group")
+	require.NoError(t, err)
+
+	assert.Same(t, it1.(*PubsubIterator).subscription, it2.(*PubsubIterator).subscription,
+		"members of the same group share the subscription")
+
+	require.NoError(t, it1.(event.Closer).Close())
+	require.NoError(t, it1.(event.Closer).Close())
+	assert.Len(t, listener.(*PubsubListener).subscriptions, 1)
+
+	require.NoError(t, it2.(event.Closer).Close())
+	assert.Empty(t, listener.(*PubsubListener).subscriptions)
+}
+
+func TestPubsubMessageCarrier(t *testing.T) {
+	carrier := newPubsubMessageCarrier(&pubsub.Message{})
+	carrier.Set("key", "value")
+	carrier.Set("traceparent", "parent")
+
+	assert.Equal(t, "value", carrier.Get("key"))
+
+	keys := carrier.Keys()
+	sort.Strings(keys)
+	assert.Equal(t, []string{"key", "traceparent"}, keys)
+}
+
+func TestPubsubCloudEvents(t *testing.T) {
+	ctx := context.Background()
+
+	for _, mode := range []string{event.CloudEventsStructured, event.CloudEventsBinary} {
+		t.Run(mode, func(t *testing.T) {
+			topic := "pubsub-cloudevents-" + mode
+			openTopic(t, topic)
+
+			listener, err := NewPubsubListener(ctx, map[string]interface{}{"schema": "mem://"})
+			require.NoError(t, err)
+			it, err := listener.Listen(ctx, topic, "group")
+			require.NoError(t, err)
+			defer it.(event.Closer).Close()
+
+			sender, err := NewPubsubSender(ctx, map[string]interface{}{"schema": "mem://", "cloud_events": mode})
+			require.NoError(t, err)
+
+			require.NoError(t, sender.Send(ctx, &event.EventMessage{
+				Topic: topic,
+				Key:   "o123",
+				Data:  "testdata",
+				Metadata: map[string]interface{}{
+					event.MetaEvent:   "order_created",
+					event.MetaVersion: 1,
+					event.MetaHash:    "hash123",
+				},
+			}))
+
+			cm, err := it.Next(ctx)
+			require.NoError(t, err)
+
+			pm := cm.(*PubsubConsumeMessage)
+			if mode == event.CloudEventsBinary {
+				assert.Equal(t, "order_created", pm.Get("ce-type"))
+				assert.Equal(t, `"testdata"`, string(pm.Body))
+			} else {
+				assert.Equal(t, event.ContentTypeCloudEvents, pm.Get("content-type"))
+			}
+
+			em, err := pm.GetEventConsumeMessage(ctx)
+			require.NoError(t, err)
+			require.NoError(t, pm.Commit(ctx))
+
+			assert.Equal(t, topic, em.Topic)
+			assert.Equal(t, "o123", em.Key)
+			assert.JSONEq(t, `"testdata"`, string(em.Data))
+			assert.Equal(t, "order_created", event.GetMetadataEvent(em.Metadata))
+			assert.Equal(t, 1, event.GetMetadataVersion(em.Metadata))
+			assert.Equal(t, "hash123", em.Metadata[event.MetaHash])
+		})
+	}
+
+	_, err := NewPubsubSender(ctx, map[string]interface{}{"cloud_events": "unknown"})
+	assert.Error(t, err)
+}
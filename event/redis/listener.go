@@ -0,0 +1,337 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diki-haryadi/govega/event"
+	redis "github.com/go-redis/redis/v8"
+	"github.com/mitchellh/mapstructure"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	DefaultCount     = 10
+	DefaultBlock     = time.Second
+	DefaultClaimIdle = time.Minute
+	DefaultStartID   = "0"
+
+	claimStart = "0-0"
+)
+
+type (
+	RedisListener struct {
+		// URL redis connection url, e.g. redis://:password@localhost:6379/0
+		URL string `json:"url" mapstructure:"url"`
+		// StreamPrefix prefix of the stream key, stream key is prefix + topic
+		StreamPrefix string `json:"stream_prefix" mapstructure:"stream_prefix"`
+		// Consumer name of the consumer within the group, default to hostname.
+		// Pending messages of the consumer are redelivered when it is restarted with the same name
+		Consumer string `json:"consumer" mapstructure:"consumer"`
+		// Count maximum number of messages read at once, default 10
+		Count int64 `json:"count" mapstructure:"count"`
+		// Block how long to wait for new messages on each read, default 1s
+		Block string `json:"block" mapstructure:"block"`
+		// ClaimIdle pending messages of other consumers idle longer than claim idle are reclaimed,
+		// e.g. messages of crashed consumer, default 1m
+		ClaimIdle string `json:"claim_idle" mapstructure:"claim_idle"`
+		// StartID where the new group start reading the stream, 0 from the beginning or $ for new messages only, default 0
+		StartID string `json:"start_id" mapstructure:"start_id"`
+
+		client    *redis.Client
+		block     time.Duration
+		claimIdle time.Duration
+	}
+
+	RedisIterator struct {
+		client     *redis.Client
+		stream     string
+		topic      string
+		group      string
+		consumer   string
+		count      int64
+		block      time.Duration
+		claimIdle  time.Duration
+		tracer     trace.Tracer
+		propagator propagation.TextMapPropagator
+
+		lock sync.Mutex
+		// pendingID last id of own pending messages read after start, empty when all are read
+		pendingID  string
+		claimStart string
+		nextClaim  time.Time
+		buffer     []redis.XMessage
+	}
+
+	RedisConsumeMessage struct {
+		*StreamMessageCarrier
+		ID       string
+		iterator *RedisIterator
+	}
+)
+
+func NewRedisListener(_ context.Context, config interface{}) (event.Listener, error) {
+	var listener RedisListener
+	if err := mapstructure.Decode(config, &listener); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	client, err := newClient(listener.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if listener.Consumer == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get consumer name: %w", err)
+		}
+		listener.Consumer = host
+	}
+
+	if listener.Count <= 0 {
+		listener.Count = DefaultCount
+	}
+
+	if listener.StartID == "" {
+		listener.StartID = DefaultStartID
+	}
+
+	listener.block = DefaultBlock
+	if listener.Block != "" {
+		block, err := time.ParseDuration(listener.Block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis listener block value: %w", err)
+		}
+		listener.block = block
+	}
+
+	listener.claimIdle = DefaultClaimIdle
+	if listener.ClaimIdle != "" {
+		idle, err := time.ParseDuration(listener.ClaimIdle)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis listener claim idle value: %w", err)
+		}
+		listener.claimIdle = idle
+	}
+
+	listener.client = client
+	return &listener, nil
+}
+
+// Listen create the consumer group of the topic stream if not exist
+func (r *RedisListener) Listen(ctx context.Context, topic, group string) (event.Iterator, error) {
+	stream := r.StreamPrefix + topic
+
+	err := r.client.XGroupCreateMkStream(ctx, stream, group, r.StartID).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil, fmt.Errorf("failed to create consumer group: %w", err)
+	}
+
+	return &RedisIterator{
+		client:     r.client,
+		stream:     stream,
+		topic:      topic,
+		group:      group,
+		consumer:   r.Consumer,
+		count:      r.Count,
+		block:      r.block,
+		claimIdle:  r.claimIdle,
+		tracer:     otel.Tracer("event/consumer"),
+		propagator: otel.GetTextMapPropagator(),
+		pendingID:  "0",
+		claimStart: claimStart,
+	}, nil
+}
+
+// Close close the redis client
+func (r *RedisListener) Close() error {
+	return r.client.Close()
+}
+
+// Next return the next message of the group, pending messages of the consumer are read first
+// followed by reclaimed idle messages of other consumers and new messages
+func (it *RedisIterator) Next(ctx context.Context) (event.ConsumeMessage, error) {
+	it.lock.Lock()
+	defer it.lock.Unlock()
+
+	for len(it.buffer) == 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if err := it.fetch(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	msg := it.buffer[0]
+	it.buffer = it.buffer[1:]
+
+	carrier := newStreamMessageCarrier(msg.Values)
+	parentSpanContext := it.propagator.Extract(ctx, carrier)
+
+	consumeMessage := &RedisConsumeMessage{
+		StreamMessageCarrier: carrier,
+		ID:                   msg.ID,
+		iterator:             it,
+	}
+
+	attrs := []attribute.KeyValue{
+		semconv.MessagingSystemKey.String("redis"),
+		semconv.MessagingDestinationKindTopic,
+		semconv.MessagingDestinationKey.String(it.topic),
+		semconv.MessagingOperationReceive,
+		semconv.MessagingMessageIDKey.String(msg.ID),
+		semconv.MessagingConversationIDKey.String(carrier.Get(FieldKey)),
+	}
+
+	newctx, span := it.tracer.Start(parentSpanContext, "redis.consume."+it.topic,
+		trace.WithAttributes(attrs...), trace.WithSpanKind(trace.SpanKindConsumer))
+	it.propagator.Inject(newctx, consumeMessage)
+	span.End()
+
+	return consumeMessage, nil
+}
+
+func (it *RedisIterator) fetch(ctx context.Context) error {
+	if it.pendingID != "" {
+		msgs, err := it.read(ctx, it.pendingID, -1)
+		if err != nil {
+			return err
+		}
+
+		if len(msgs) == 0 {
+			it.pendingID = ""
+			return nil
+		}
+
+		it.pendingID = msgs[len(msgs)-1].ID
+		it.buffer = msgs
+		return nil
+	}
+
+	if it.claimIdle > 0 && !time.Now().Before(it.nextClaim) {
+		next, msgs, err := it.claim(ctx)
+		if err != nil {
+			return err
+		}
+
+		it.claimStart = next
+		if next == claimStart {
+			it.nextClaim = time.Now().Add(it.claimIdle)
+		}
+
+		if len(msgs) > 0 {
+			it.buffer = msgs
+			return nil
+		}
+	}
+
+	msgs, err := it.read(ctx, ">", it.block)
+	if err != nil {
+		return err
+	}
+
+	it.buffer = msgs
+	return nil
+}
+
+func (it *RedisIterator) read(ctx context.Context, id string, block time.Duration) ([]redis.XMessage, error) {
+	streams, err := it.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    it.group,
+		Consumer: it.consumer,
+		Streams:  []string{it.stream, id},
+		Count:    it.count,
+		Block:    block,
+	}).Result()
+
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	var msgs []redis.XMessage
+	for _, s := range streams {
+		msgs = append(msgs, s.Messages...)
+	}
+
+	return msgs, nil
+}
+
+// claim transfer idle pending messages of the group to the consumer. XAUTOCLAIM reply is parsed here
+// as the client only accept the two elements reply while redis 7 also reply with the deleted ids
+func (it *RedisIterator) claim(ctx context.Context) (string, []redis.XMessage, error) {
+	res, err := it.client.Do(ctx, "XAUTOCLAIM", it.stream, it.group, it.consumer,
+		it.claimIdle.Milliseconds(), it.claimStart, "COUNT", it.count).Slice()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to claim pending messages: %w", err)
+	}
+
+	if len(res) < 2 {
+		return "", nil, fmt.Errorf("failed to claim pending messages: unexpected reply length %d", len(res))
+	}
+
+	next, _ := res[0].(string)
+	if next == "" {
+		next = claimStart
+	}
+
+	entries, _ := res[1].([]interface{})
+	msgs := make([]redis.XMessage, 0, len(entries))
+	for _, e := range entries {
+		// entry deleted from the stream is returned as nil by redis 6.2
+		entry, ok := e.([]interface{})
+		if !ok || len(entry) != 2 {
+			continue
+		}
+
+		id, _ := entry[0].(string)
+		fields, _ := entry[1].([]interface{})
+		values := make(map[string]interface{}, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			if k, ok := fields[i].(string); ok {
+				values[k] = fields[i+1]
+			}
+		}
+
+		msgs = append(msgs, redis.XMessage{ID: id, Values: values})
+	}
+
+	return next, msgs, nil
+}
+
+func (m *RedisConsumeMessage) GetEventConsumeMessage(ctx context.Context) (*event.EventConsumeMessage, error) {
+	em, err := event.NewEventConsumeMessage([]byte(m.Get(FieldData)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode message: %w", err)
+	}
+
+	em.Topic = m.iterator.topic
+	if key := m.Get(FieldKey); key != "" {
+		em.Key = key
+	}
+
+	return em, nil
+}
+
+// Commit acknowledge the message so it is removed from the group pending entries
+func (m *RedisConsumeMessage) Commit(ctx context.Context) error {
+	if err := m.iterator.client.XAck(ctx, m.iterator.stream, m.iterator.group, m.ID).Err(); err != nil {
+		return fmt.Errorf("failed to ack message: %w", err)
+	}
+
+	return nil
+}
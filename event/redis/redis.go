@@ -0,0 +1,79 @@
+package redis
+
+import (
+	"fmt"
+
+	"github.com/diki-haryadi/govega/event"
+	"github.com/go-redis/redis/extra/redisotel"
+	redis "github.com/go-redis/redis/v8"
+)
+
+const (
+	// FieldData stream entry field containing the event message
+	FieldData = "data"
+	// FieldKey stream entry field containing the message key
+	FieldKey = "key"
+)
+
+// StreamMessageCarrier text map carrier of stream entry fields,
+// trace context is stored as additional fields next to the message data
+type StreamMessageCarrier struct {
+	Values map[string]interface{}
+}
+
+func init() {
+	event.RegisterSender("redis", NewRedisSender)
+	event.RegisterListener("redis", NewRedisListener)
+}
+
+func newClient(url string) (*redis.Client, error) {
+	if url == "" {
+		return nil, fmt.Errorf("[event/redis] missing redis url")
+	}
+
+	opt, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("[event/redis] invalid redis url: %w", err)
+	}
+
+	client := redis.NewClient(opt)
+	client.AddHook(redisotel.TracingHook{})
+	return client, nil
+}
+
+func newStreamMessageCarrier(values map[string]interface{}) *StreamMessageCarrier {
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+
+	return &StreamMessageCarrier{Values: values}
+}
+
+// Get retrieves a single value for a given key.
+func (c *StreamMessageCarrier) Get(key string) string {
+	switch v := c.Values[key].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return ""
+	}
+}
+
+// Set sets a header.
+func (c *StreamMessageCarrier) Set(key, val string) {
+	c.Values[key] = val
+}
+
+// Keys returns a slice of all key identifiers in the carrier.
+func (c *StreamMessageCarrier) Keys() []string {
+	out := make([]string, 0, len(c.Values))
+	for k := range c.Values {
+		if k == FieldData || k == FieldKey {
+			continue
+		}
+		out = append(out, k)
+	}
+	return out
+}
@@ -0,0 +1,190 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/diki-haryadi/govega/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRedisEmitterConsumer(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	s := miniredis.RunT(t)
+	ctx := context.Background()
+
+	em, err := event.New(ctx, &event.EmitterConfig{
+		Sender: &event.DriverConfig{
+			Type:   "redis",
+			Config: map[string]interface{}{"url": "redis://" + s.Addr(), "stream_prefix": "event:"},
+		},
+	})
+	require.NoError(t, err)
+
+	consumer, err := event.NewConsumer(ctx, &event.ConsumerConfig{
+		Listener: &event.DriverConfig{
+			Type: "redis",
+			Config: map[string]interface{}{
+				"url":           "redis://" + s.Addr(),
+				"stream_prefix": "event:",
+				"block":         "20ms",
+				"claim_idle":    "100ms",
+			},
+		},
+		ConsumeStrategy: &event.DriverConfig{Type: "commit_on_success"},
+	})
+	require.NoError(t, err)
+
+	traceID := trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+
+	var lock sync.Mutex
+	received := map[string][]string{}
+	attempts := map[string]int{}
+	traces := map[string]trace.TraceID{}
+
+	handler := func(group string) event.EventHandler {
+		return func(ctx context.Context, message *event.EventConsumeMessage) error {
+			lock.Lock()
+			defer lock.Unlock()
+
+			attempts[group+message.Key]++
+			if group == "group2" && message.Key == "b" && attempts[group+message.Key] == 1 {
+				return errors.New("failed")
+			}
+
+			received[group] = append(received[group], message.Key)
+			traces[group+message.Key] = trace.SpanContextFromContext(ctx).TraceID()
+			assert.Equal(t, "redis-test", message.Topic)
+			assert.JSONEq(t, `"testdata"`, string(message.Data))
+			return nil
+		}
+	}
+
+	require.NoError(t, consumer.Subscribe(ctx, "redis-test", "group1", handler("group1")))
+	require.NoError(t, consumer.Subscribe(ctx, "redis-test", "group2", handler("group2")))
+	require.NoError(t, consumer.Start())
+
+	pctx := trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, em.Publish(pctx, "redis-test", key, "testdata", nil))
+	}
+
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(received["group1"]) == 3 && len(received["group2"]) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, consumer.Stop())
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	for _, group := range []string{"group1", "group2"} {
+		sort.Strings(received[group])
+		assert.Equal(t, []string{"a", "b", "c"}, received[group], group)
+	}
+	assert.Equal(t, 2, attempts["group2b"], "failed message is reclaimed")
+	assert.Equal(t, 1, attempts["group1b"])
+	assert.Equal(t, traceID, traces["group1a"])
+}
+
+func TestRedisSenderMaxLen(t *testing.T) {
+	s := miniredis.RunT(t)
+	ctx := context.Background()
+
+	sender, err := NewRedisSender(ctx, map[string]interface{}{
+		"url":           "redis://" + s.Addr(),
+		"max_len":       2,
+		"exact_max_len": true,
+	})
+	require.NoError(t, err)
+	defer sender.(*RedisSender).Close()
+
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, sender.Send(ctx, &event.EventMessage{Topic: "trim", Key: key, Data: "data"}))
+	}
+
+	n, err := sender.(*RedisSender).client.XLen(ctx, "trim").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
+
+func newTestIterator(t *testing.T, s *miniredis.Miniredis, consumer string) event.Iterator {
+	listener, err := NewRedisListener(context.Background(), map[string]interface{}{
+		"url":        "redis://" + s.Addr(),
+		"consumer":   consumer,
+		"block":      "10ms",
+		"claim_idle": "50ms",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.(*RedisListener).Close() })
+
+	it, err := listener.Listen(context.Background(), "claim", "group")
+	require.NoError(t, err)
+	return it
+}
+
+func TestRedisIteratorPending(t *testing.T) {
+	s := miniredis.RunT(t)
+	ctx := context.Background()
+
+	sender, err := NewRedisSender(ctx, map[string]interface{}{"url": "redis://" + s.Addr()})
+	require.NoError(t, err)
+	defer sender.(*RedisSender).Close()
+
+	crashed := newTestIterator(t, s, "crashed")
+	require.NoError(t, sender.Send(ctx, &event.EventMessage{Topic: "claim", Key: "a", Data: "data"}))
+	require.NoError(t, sender.Send(ctx, &event.EventMessage{Topic: "claim", Key: "b", Data: "data"}))
+
+	// consumer crashed before committing the messages
+	for i := 0; i < 2; i++ {
+		_, err := crashed.Next(ctx)
+		require.NoError(t, err)
+	}
+
+	restarted := newTestIterator(t, s, "crashed")
+	msg, err := restarted.Next(ctx)
+	require.NoError(t, err)
+	em, err := msg.GetEventConsumeMessage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "a", em.Key, "pending message is redelivered to the restarted consumer")
+	require.NoError(t, msg.Commit(ctx))
+
+	other := newTestIterator(t, s, "other")
+	time.Sleep(60 * time.Millisecond)
+
+	msg, err = other.Next(ctx)
+	require.NoError(t, err)
+	em, err = msg.GetEventConsumeMessage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "b", em.Key, "idle message is reclaimed by other consumer")
+	require.NoError(t, msg.Commit(ctx))
+
+	pending, err := sender.(*RedisSender).client.XPending(ctx, "claim", "group").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), pending.Count)
+}
+
+func TestStreamMessageCarrier(t *testing.T) {
+	carrier := newStreamMessageCarrier(nil)
+	carrier.Set(FieldData, "data")
+	carrier.Set("traceparent", "parent")
+
+	assert.Equal(t, "parent", carrier.Get("traceparent"))
+	assert.Equal(t, []string{"traceparent"}, carrier.Keys())
+}
@@ -0,0 +1,92 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/diki-haryadi/govega/event"
+	redis "github.com/go-redis/redis/v8"
+	"github.com/mitchellh/mapstructure"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type RedisSender struct {
+	// URL redis connection url, e.g. redis://:password@localhost:6379/0
+	URL string `json:"url" mapstructure:"url"`
+	// StreamPrefix prefix of the stream key, stream key is prefix + topic
+	StreamPrefix string `json:"stream_prefix" mapstructure:"stream_prefix"`
+	// MaxLen trim the stream to approximately max len entries on every send, 0 disable trimming
+	MaxLen int64 `json:"max_len" mapstructure:"max_len"`
+	// ExactMaxLen trim the stream to exactly max len entries instead of the more efficient approximate trimming
+	ExactMaxLen bool `json:"exact_max_len" mapstructure:"exact_max_len"`
+
+	client     *redis.Client
+	propagator propagation.TextMapPropagator
+}
+
+func NewRedisSender(_ context.Context, config interface{}) (event.Sender, error) {
+	var sender RedisSender
+	if err := mapstructure.Decode(config, &sender); err != nil {
+		return nil, err
+	}
+
+	client, err := newClient(sender.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	sender.client = client
+	sender.propagator = otel.GetTextMapPropagator()
+	return &sender, nil
+}
+
+func (r *RedisSender) Send(ctx context.Context, message *event.EventMessage) error {
+	tr := otel.Tracer("event/emitter")
+	ctx, span := tr.Start(ctx, "SEND "+message.Topic,
+		trace.WithAttributes(semconv.MessagingOperationProcess),
+		trace.WithAttributes(semconv.MessagingDestinationKindTopic),
+		trace.WithAttributes(semconv.MessagingDestinationKey.String(message.Topic)),
+		trace.WithAttributes(semconv.MessagingSystemKey.String("redis")),
+		trace.WithAttributes(semconv.MessagingConversationIDKey.String(message.Key)),
+		trace.WithSpanKind(trace.SpanKindProducer),
+	)
+	defer span.End()
+
+	mb, err := message.ToBytes()
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	carrier := newStreamMessageCarrier(map[string]interface{}{FieldData: mb})
+	if message.Key != "" {
+		carrier.Values[FieldKey] = message.Key
+	}
+	r.propagator.Inject(ctx, carrier)
+
+	args := &redis.XAddArgs{
+		Stream: r.StreamPrefix + message.Topic,
+		Values: carrier.Values,
+	}
+
+	if r.MaxLen > 0 {
+		args.MaxLen = r.MaxLen
+		args.Approx = !r.ExactMaxLen
+	}
+
+	if err := r.client.XAdd(ctx, args).Err(); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to add stream entry: %w", err)
+	}
+
+	return nil
+}
+
+// Close close the redis client
+func (r *RedisSender) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,257 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/diki-haryadi/govega/log"
+	"github.com/diki-haryadi/govega/monitor"
+)
+
+const (
+	DefaultRelayInterval     = time.Minute
+	DefaultRelayMinAge       = time.Minute
+	DefaultRelayBatchSize    = 100
+	DefaultRelayClaimTimeout = 5 * time.Minute
+)
+
+var (
+	ErrRelayStarted = errors.New("Outbox relay already started")
+
+	errRelaySkipped = errors.New("previous record with the same key failed to relay")
+)
+
+type (
+	// OutboxRelayer is implemented by writer which able to relay stale outbox records
+	OutboxRelayer interface {
+		// RelayOutbox claim outbox records matching the option and pass them to fn
+		// ordered by creation time. Record will be deleted when fn succeed and released
+		// otherwise, it return number of records successfully relayed
+		RelayOutbox(ctx context.Context, opt *RelayOption, fn RelayFunc) (int, error)
+	}
+
+	RelayOption struct {
+		// CreatedBefore only claim record created before this time
+		CreatedBefore time.Time
+		// Limit maximum number of record claimed
+		Limit int
+		// ClaimTimeout duration before a claimed record can be claimed by another relay
+		// drivers which hold a lock during relay (e.g. sql) may ignore this
+		ClaimTimeout time.Duration
+	}
+
+	RelayFunc func(ctx context.Context, record *OutboxRecord) error
+
+	OutboxRelayConfig struct {
+		// Interval between outbox scan, default: 1m
+		Interval string `json:"interval" mapstructure:"interval"`
+		// MinAge minimum age of outbox record to be relayed, default: 1m
+		// this should be longer than the time needed by hybrid emitter to send the message
+		MinAge string `json:"min_age" mapstructure:"min_age"`
+		// BatchSize maximum number of record relayed on each scan, default: 100
+		BatchSize int `json:"batch_size" mapstructure:"batch_size"`
+		// ClaimTimeout duration before a claimed record can be reclaimed, default: 5m
+		ClaimTimeout string `json:"claim_timeout" mapstructure:"claim_timeout"`
+	}
+
+	// OutboxRelay periodically send outbox records left by the hybrid emitter
+	OutboxRelay struct {
+		relayer      OutboxRelayer
+		sender       Sender
+		interval     time.Duration
+		minAge       time.Duration
+		claimTimeout time.Duration
+		batchSize    int
+		running      uint32
+		lock         sync.Mutex
+		stopch       chan bool
+		shutdown     chan bool
+	}
+)
+
+// NewOutboxRelay create new instance of outbox relay, writer should implement OutboxRelayer
+func NewOutboxRelay(writer Writer, sender Sender, config *OutboxRelayConfig) (*OutboxRelay, error) {
+	relayer, ok := writer.(OutboxRelayer)
+	if !ok {
+		return nil, errors.New("[event/relay] writer doesn't support outbox relay")
+	}
+
+	if sender == nil {
+		return nil, errors.New("[event/relay] missing sender")
+	}
+
+	relay := &OutboxRelay{
+		relayer:      relayer,
+		sender:       sender,
+		interval:     DefaultRelayInterval,
+		minAge:       DefaultRelayMinAge,
+		claimTimeout: DefaultRelayClaimTimeout,
+		batchSize:    DefaultRelayBatchSize,
+		running:      stop,
+	}
+
+	if config == nil {
+		return relay, nil
+	}
+
+	if config.Interval != "" {
+		interval, err := time.ParseDuration(config.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("[event/relay] invalid interval value: %w", err)
+		}
+
+		relay.interval = interval
+	}
+
+	if config.MinAge != "" {
+		minAge, err := time.ParseDuration(config.MinAge)
+		if err != nil {
+			return nil, fmt.Errorf("[event/relay] invalid min age value: %w", err)
+		}
+
+		relay.minAge = minAge
+	}
+
+	if config.ClaimTimeout != "" {
+		timeout, err := time.ParseDuration(config.ClaimTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("[event/relay] invalid claim timeout value: %w", err)
+		}
+
+		relay.claimTimeout = timeout
+	}
+
+	if config.BatchSize > 0 {
+		relay.batchSize = config.BatchSize
+	}
+
+	return relay, nil
+}
+
+// Start run the relay periodically in background
+func (r *OutboxRelay) Start() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.isRunning() {
+		return ErrRelayStarted
+	}
+
+	r.stopch = make(chan bool)
+	r.shutdown = make(chan bool)
+
+	go r.run(r.stopch, r.shutdown)
+
+	atomic.StoreUint32(&r.running, start)
+
+	return nil
+}
+
+// Stop stop the relay waiting for the running scan to complete
+func (r *OutboxRelay) Stop() error {
+	return r.StopContext(context.Background())
+}
+
+// StopContext stop the relay or until the context timeout
+func (r *OutboxRelay) StopContext(ctx context.Context) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !r.isRunning() {
+		return nil
+	}
+
+	close(r.stopch)
+
+	var err error
+	select {
+	case <-ctx.Done():
+		log.Errorln("[event/relay] timeout waiting relay to stop")
+		err = ctx.Err()
+	case <-r.shutdown:
+	}
+
+	r.stopch = nil
+	r.shutdown = nil
+	atomic.StoreUint32(&r.running, stop)
+
+	return err
+}
+
+// RelayOnce claim a batch of stale outbox records and send them through the sender
+// records with the same topic and key are skipped after the first failure to keep the order
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	failed := make(map[string]bool)
+
+	opt := &RelayOption{
+		CreatedBefore: time.Now().Add(-r.minAge),
+		Limit:         r.batchSize,
+		ClaimTimeout:  r.claimTimeout,
+	}
+
+	return r.relayer.RelayOutbox(ctx, opt, func(ctx context.Context, record *OutboxRecord) error {
+		orderKey := ""
+		if record.Key != "" {
+			orderKey = record.Topic + "/" + record.Key
+			if failed[orderKey] {
+				return errRelaySkipped
+			}
+		}
+
+		start := time.Now()
+		err := r.send(ctx, record)
+		monitor.FeedOutboxRelayMetrics(record.Topic, getMetricStatusFromError(err), time.Since(start))
+
+		if err != nil {
+			if orderKey != "" {
+				failed[orderKey] = true
+			}
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (r *OutboxRelay) send(ctx context.Context, record *OutboxRecord) error {
+	msg, err := record.ToMessage()
+	if err != nil {
+		return err
+	}
+
+	return r.sender.Send(ctx, msg)
+}
+
+func (r *OutboxRelay) run(stop <-chan bool, shutdown chan<- bool) {
+	defer close(shutdown)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			// don't use cancelable context, let the running batch complete
+			// so claimed records are released properly
+			n, err := r.RelayOnce(context.Background())
+			if err != nil {
+				log.WithError(err).Errorln("[event/relay] failed to relay outbox")
+				continue
+			}
+
+			if n > 0 {
+				log.WithFields(log.Fields{"relayed": n}).Infoln("[event/relay] outbox relayed")
+			}
+		}
+	}
+}
+
+func (r *OutboxRelay) isRunning() bool {
+	return atomic.LoadUint32(&r.running) == start
+}
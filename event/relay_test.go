@@ -0,0 +1,195 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	testRelayWriter struct {
+		*EventLogger
+		lock    sync.Mutex
+		records []*OutboxRecord
+	}
+
+	testRelaySender struct {
+		lock   sync.Mutex
+		sent   []*EventMessage
+		failFn func(message *EventMessage) bool
+	}
+)
+
+func (w *testRelayWriter) add(t *testing.T, msg *EventMessage, createdAt time.Time) {
+	rec, err := OutboxFromMessage(msg)
+	require.NoError(t, err)
+	rec.CreatedAt = createdAt
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.records = append(w.records, rec)
+}
+
+func (w *testRelayWriter) RelayOutbox(ctx context.Context, opt *RelayOption, fn RelayFunc) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	sort.Slice(w.records, func(i, j int) bool {
+		return w.records[i].CreatedAt.Before(w.records[j].CreatedAt)
+	})
+
+	remaining := make([]*OutboxRecord, 0)
+	relayed := 0
+	for _, rec := range w.records {
+		if relayed >= opt.Limit || !rec.CreatedAt.Before(opt.CreatedBefore) {
+			remaining = append(remaining, rec)
+			continue
+		}
+
+		if err := fn(ctx, rec); err != nil {
+			remaining = append(remaining, rec)
+			continue
+		}
+		relayed++
+	}
+	w.records = remaining
+
+	return relayed, nil
+}
+
+func (w *testRelayWriter) count() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return len(w.records)
+}
+
+func (s *testRelaySender) Send(ctx context.Context, message *EventMessage) error {
+	if s.failFn != nil && s.failFn(message) {
+		return errors.New("failed")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.sent = append(s.sent, message)
+	return nil
+}
+
+func (s *testRelaySender) count() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.sent)
+}
+
+func TestOutboxRecordToMessage(t *testing.T) {
+	msg := &EventMessage{
+		Topic:    "test",
+		Key:      "t123",
+		Data:     map[string]interface{}{"foo": "bar"},
+		Metadata: map[string]interface{}{MetaEvent: "test"},
+	}
+
+	rec, err := OutboxFromMessage(msg)
+	require.NoError(t, err)
+
+	out, err := rec.ToMessage()
+	require.NoError(t, err)
+
+	assert.Equal(t, "test", out.Topic)
+	assert.Equal(t, "t123", out.Key)
+	assert.Equal(t, "test", out.Metadata[MetaEvent])
+	assert.JSONEq(t, `{"foo":"bar"}`, string(out.RawData))
+
+	ob, err := out.ToBytes()
+	require.NoError(t, err)
+	assert.Equal(t, rec.Value, string(ob))
+}
+
+func TestOutboxRelayOnce(t *testing.T) {
+	writer := &testRelayWriter{}
+	sender := &testRelaySender{
+		failFn: func(message *EventMessage) bool {
+			return message.Key == "fail"
+		},
+	}
+
+	old := time.Now().Add(-time.Hour)
+	writer.add(t, &EventMessage{Topic: "test", Key: "fail", Data: 1}, old)
+	writer.add(t, &EventMessage{Topic: "test", Key: "fail", Data: 2}, old.Add(time.Second))
+	writer.add(t, &EventMessage{Topic: "test", Key: "ok", Data: 3}, old.Add(2*time.Second))
+	writer.add(t, &EventMessage{Topic: "test", Key: "new", Data: 4}, time.Now())
+
+	relay, err := NewOutboxRelay(writer, sender, &OutboxRelayConfig{MinAge: "1m"})
+	require.NoError(t, err)
+
+	n, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 1, sender.count())
+	assert.Equal(t, "ok", sender.sent[0].Key)
+	assert.Equal(t, 3, writer.count())
+}
+
+func TestOutboxRelayStart(t *testing.T) {
+	writer := &testRelayWriter{}
+	sender := &testRelaySender{}
+
+	writer.add(t, &EventMessage{Topic: "test", Key: "t123", Data: "testdata"}, time.Now().Add(-time.Hour))
+
+	relay, err := NewOutboxRelay(writer, sender, &OutboxRelayConfig{Interval: "10ms"})
+	require.NoError(t, err)
+
+	require.NoError(t, relay.Start())
+	assert.ErrorIs(t, relay.Start(), ErrRelayStarted)
+
+	assert.Eventually(t, func() bool {
+		return sender.count() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, relay.Stop())
+	assert.Equal(t, 0, writer.count())
+}
+
+func TestOutboxRelayUnsupportedWriter(t *testing.T) {
+	writer, err := NewEventLogger(context.Background(), nil)
+	require.NoError(t, err)
+
+	_, err = NewOutboxRelay(writer, writer, nil)
+	assert.Error(t, err)
+}
+
+// countingRelayWriter relay writer which count the outbox scans
+type countingRelayWriter struct {
+	testRelayWriter
+	scans int32
+}
+
+func (w *countingRelayWriter) RelayOutbox(ctx context.Context, opt *RelayOption, fn RelayFunc) (int, error) {
+	atomic.AddInt32(&w.scans, 1)
+	return w.testRelayWriter.RelayOutbox(ctx, opt, fn)
+}
+
+func TestEmitterRelayCleanup(t *testing.T) {
+	writer := &countingRelayWriter{}
+	RegisterWriter("relay-cleanup", func(ctx context.Context, config interface{}) (Writer, error) {
+		return writer, nil
+	})
+
+	_, err := NewWithSender(context.Background(), &testRelaySender{}, &EmitterConfig{
+		Writer:    &DriverConfig{Type: "relay-cleanup"},
+		Relay:     &OutboxRelayConfig{Interval: "10ms"},
+		Scheduler: &SchedulerConfig{Lock: "unknown://"},
+	})
+	require.Error(t, err)
+
+	scans := atomic.LoadInt32(&writer.scans)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, scans, atomic.LoadInt32(&writer.scans), "relay is stopped when emitter fails to be created")
+}
@@ -0,0 +1,120 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/diki-haryadi/govega/log"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrReplayUnsupported = errors.New("[event/replay] listener doesn't support replay")
+
+type (
+	// Replayer listener which can read a bounded range of the topic without consumer group,
+	// the iterator return io.EOF once all messages of the range are read
+	Replayer interface {
+		Replay(ctx context.Context, topic string, option *ReplayOption) (Iterator, error)
+	}
+
+	// ReplayOption range of the replayed messages, offsets are applied to each partition
+	ReplayOption struct {
+		// From replay messages published at or after the time, default: earliest message
+		From time.Time
+		// To replay messages published before the time, default: latest message when the replay started
+		To time.Time
+		// FromOffset replay messages from the offset, inclusive
+		FromOffset int64
+		// ToOffset replay messages before the offset, exclusive, default: latest offset when the replay started
+		ToOffset int64
+		// Partitions replay only messages of the partitions, default: all partitions
+		Partitions []int
+	}
+)
+
+// Validate check the range of the option
+func (o *ReplayOption) Validate() error {
+	if !o.From.IsZero() && o.FromOffset > 0 {
+		return errors.New("[event/replay] from time and from offset can't be used together")
+	}
+
+	if !o.To.IsZero() && o.ToOffset > 0 {
+		return errors.New("[event/replay] to time and to offset can't be used together")
+	}
+
+	if !o.From.IsZero() && !o.To.IsZero() && !o.From.Before(o.To) {
+		return errors.New("[event/replay] from time should be before to time")
+	}
+
+	if o.FromOffset < 0 || o.ToOffset < 0 || (o.ToOffset > 0 && o.FromOffset >= o.ToOffset) {
+		return errors.New("[event/replay] invalid offset range")
+	}
+
+	return nil
+}
+
+// Replay read messages of the range into the handler without committing to any consumer group,
+// it can be used while the live consumer group is running. Consumer middlewares are applied to the handler.
+// Replay stops on the first handler error and return the number of handled messages
+func (c *Consumer) Replay(ctx context.Context, topic string, option *ReplayOption, handler EventHandler) (int, error) {
+	replayer, ok := c.listener.(Replayer)
+	if !ok {
+		return 0, ErrReplayUnsupported
+	}
+
+	if option == nil {
+		option = &ReplayOption{}
+	}
+
+	if err := option.Validate(); err != nil {
+		return 0, err
+	}
+
+	topic = c.eventConfig.getTopic(topic)
+
+	iterator, err := replayer.Replay(ctx, topic, option)
+	if err != nil {
+		return 0, fmt.Errorf("[event/replay] failed to replay topic %s: %w", topic, err)
+	}
+
+	if closer, ok := iterator.(Closer); ok {
+		defer closer.Close()
+	}
+
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		handler = c.middlewares[i](handler)
+	}
+	handler = c.payload.Middleware(handler)
+
+	count := 0
+	for {
+		message, err := iterator.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return count, fmt.Errorf("[event/replay] failed to read message: %w", err)
+		}
+
+		em, err := message.GetEventConsumeMessage(ctx)
+		if err != nil {
+			return count, fmt.Errorf("[event/replay] failed to decode message: %w", err)
+		}
+
+		if err := handler(ctx, em); err != nil {
+			return count, fmt.Errorf("[event/replay] failed to handle message: %w", err)
+		}
+		count++
+	}
+
+	log.WithContext(ctx).WithFields(logrus.Fields{
+		"topic": topic,
+		"count": count,
+	}).Println("[event/replay] replay completed")
+
+	return count, nil
+}
@@ -0,0 +1,94 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	// testReplayer replay the messages of the offset range
+	testReplayer struct {
+		testListener
+		messages []*EventConsumeMessage
+	}
+
+	testReplayIterator struct {
+		messages []*EventConsumeMessage
+	}
+)
+
+func (r *testReplayer) Replay(ctx context.Context, topic string, option *ReplayOption) (Iterator, error) {
+	end := int64(len(r.messages))
+	if option.ToOffset > 0 && option.ToOffset < end {
+		end = option.ToOffset
+	}
+
+	return &testReplayIterator{messages: r.messages[option.FromOffset:end]}, nil
+}
+
+func (r *testReplayIterator) Next(ctx context.Context) (ConsumeMessage, error) {
+	if len(r.messages) == 0 {
+		return nil, io.EOF
+	}
+
+	m := r.messages[0]
+	r.messages = r.messages[1:]
+	return newTestConsumeMessage(m), nil
+}
+
+func TestConsumerReplay(t *testing.T) {
+	replayer := &testReplayer{}
+	for _, key := range []string{"k0", "k1", "k2", "k3"} {
+		replayer.messages = append(replayer.messages, &EventConsumeMessage{Topic: "test", Key: key, Metadata: map[string]interface{}{}})
+	}
+
+	RegisterListener("TestConsumerReplay", func(ctx context.Context, config interface{}) (Listener, error) {
+		return replayer, nil
+	})
+
+	ctx := context.Background()
+	consumer, err := NewConsumer(ctx, &ConsumerConfig{Listener: &DriverConfig{Type: "TestConsumerReplay"}})
+	require.NoError(t, err)
+
+	consumer.Use(func(next EventHandler) EventHandler {
+		return func(ctx context.Context, message *EventConsumeMessage) error {
+			message.Metadata["replayed"] = true
+			return next(ctx, message)
+		}
+	})
+
+	var keys []string
+	count, err := consumer.Replay(ctx, "test", &ReplayOption{FromOffset: 1, ToOffset: 3},
+		func(ctx context.Context, message *EventConsumeMessage) error {
+			assert.Equal(t, true, message.Metadata["replayed"], "middlewares are applied")
+			keys = append(keys, message.Key)
+			return nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []string{"k1", "k2"}, keys)
+
+	count, err = consumer.Replay(ctx, "test", nil, func(ctx context.Context, message *EventConsumeMessage) error {
+		if message.Key == "k2" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 2, count, "replay stop on handler error")
+
+	_, err = consumer.Replay(ctx, "test", &ReplayOption{FromOffset: 3, ToOffset: 1}, nil)
+	assert.Error(t, err)
+
+	RegisterListener("TestConsumerReplayUnsupported", newTestListener().factory)
+	consumer, err = NewConsumer(ctx, &ConsumerConfig{Listener: &DriverConfig{Type: "TestConsumerReplayUnsupported"}})
+	require.NoError(t, err)
+
+	_, err = consumer.Replay(ctx, "test", nil, nil)
+	assert.ErrorIs(t, err, ErrReplayUnsupported)
+}
@@ -0,0 +1,123 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/diki-haryadi/govega/log"
+	"github.com/diki-haryadi/govega/monitor"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrUnknownEvent returned by ErrorUnknownEvent fallback when there is no route for the message
+var ErrUnknownEvent = errors.New("unknown event")
+
+type (
+	// Router dispatch messages of a topic to the handler registered for the message event and version,
+	// router Handle can be used as the subscriber handler. Routes should be registered before subscribe
+	Router struct {
+		routes   map[string]*route
+		fallback EventHandler
+	}
+
+	route struct {
+		handler  EventHandler
+		versions VersionHandler
+	}
+)
+
+// NewRouter create router, message without route is returned as ErrUnknownEvent by default
+func NewRouter() *Router {
+	return &Router{
+		routes:   make(map[string]*route),
+		fallback: ErrorUnknownEvent,
+	}
+}
+
+func (r *Router) getRoute(event string) *route {
+	rt, ok := r.routes[event]
+	if !ok {
+		rt = &route{versions: make(VersionHandler)}
+		r.routes[event] = rt
+	}
+	return rt
+}
+
+// On register handler for every version of the event without specific version handler,
+// middlewares are only applied to this route
+func (r *Router) On(event string, handler EventHandler, middlewares ...EventMiddleware) *Router {
+	r.getRoute(event).handler = chain(handler, middlewares)
+	return r
+}
+
+// OnVersion register handler for specific version of the event,
+// middlewares are only applied to this route
+func (r *Router) OnVersion(event string, version int, handler EventHandler, middlewares ...EventMiddleware) *Router {
+	r.getRoute(event).versions[version] = chain(handler, middlewares)
+	return r
+}
+
+// Fallback set handler for message without route, e.g. SkipUnknownEvent, ErrorUnknownEvent or DeadLetterUnknownEvent
+func (r *Router) Fallback(handler EventHandler) *Router {
+	r.fallback = handler
+	return r
+}
+
+// Handle call handler registered for the message event and version
+func (r *Router) Handle(ctx context.Context, message *EventConsumeMessage) error {
+	if handler := r.lookup(message); handler != nil {
+		return handler(ctx, message)
+	}
+
+	return r.fallback(ctx, message)
+}
+
+func (r *Router) lookup(message *EventConsumeMessage) EventHandler {
+	rt, ok := r.routes[GetMetadataEvent(message.Metadata)]
+	if !ok {
+		return nil
+	}
+
+	if handler, ok := rt.versions[GetMetadataVersion(message.Metadata)]; ok {
+		return handler
+	}
+
+	return rt.handler
+}
+
+func chain(handler EventHandler, middlewares []EventMiddleware) EventHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// SkipUnknownEvent router fallback which ignore message without route
+func SkipUnknownEvent(ctx context.Context, message *EventConsumeMessage) error {
+	log.WithContext(ctx).WithFields(logrus.Fields{
+		"topic":   message.Topic,
+		"event":   GetMetadataEvent(message.Metadata),
+		"version": GetMetadataVersion(message.Metadata),
+	}).Debugln("[event/router] skip unknown event")
+	return nil
+}
+
+// ErrorUnknownEvent router fallback which return ErrUnknownEvent for message without route
+func ErrorUnknownEvent(_ context.Context, message *EventConsumeMessage) error {
+	return fmt.Errorf("[event/router] %w: %s v%d", ErrUnknownEvent,
+		GetMetadataEvent(message.Metadata), GetMetadataVersion(message.Metadata))
+}
+
+// DeadLetterUnknownEvent router fallback which send message without route to dead letter topic,
+// default to the original topic with .dlq suffix
+func DeadLetterUnknownEvent(sender Sender, topic string) EventHandler {
+	return func(ctx context.Context, message *EventConsumeMessage) error {
+		if err := sendDeadLetter(ctx, sender, topic, message, ErrorUnknownEvent(ctx, message), 0); err != nil {
+			return fmt.Errorf("[event/router] failed to send dead letter: %w", err)
+		}
+
+		monitor.FeedConsumerDeadLetterMetrics(message.Topic, GetConsumerGroupFromContext(ctx))
+		return nil
+	}
+}
@@ -0,0 +1,104 @@
+package event
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter(t *testing.T) {
+	var called []string
+	handler := func(name string) EventHandler {
+		return func(ctx context.Context, message *EventConsumeMessage) error {
+			called = append(called, name)
+			return nil
+		}
+	}
+
+	middleware := func(next EventHandler) EventHandler {
+		return func(ctx context.Context, message *EventConsumeMessage) error {
+			called = append(called, "middleware")
+			return next(ctx, message)
+		}
+	}
+
+	router := NewRouter().
+		On("order_created", handler("created")).
+		OnVersion("order_created", 2, handler("created.v2"), middleware).
+		On("order_paid", handler("paid"), middleware)
+
+	msg := func(event string, version interface{}) *EventConsumeMessage {
+		return &EventConsumeMessage{
+			Topic:    "order",
+			Metadata: map[string]interface{}{MetaEvent: event, MetaVersion: version},
+		}
+	}
+
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		message  *EventConsumeMessage
+		expected []string
+	}{
+		{"event handler", msg("order_created", 1), []string{"created"}},
+		{"version handler", msg("order_created", "v2"), []string{"middleware", "created.v2"}},
+		{"other version", msg("order_created", 3), []string{"created"}},
+		{"route middleware", msg("order_paid", 1), []string{"middleware", "paid"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called = nil
+			require.NoError(t, router.Handle(ctx, tt.message))
+			assert.Equal(t, tt.expected, called)
+		})
+	}
+
+	called = nil
+	err := router.Handle(ctx, msg("order_cancelled", 1))
+	assert.ErrorIs(t, err, ErrUnknownEvent)
+	assert.Empty(t, called)
+
+	router.Fallback(SkipUnknownEvent)
+	assert.NoError(t, router.Handle(ctx, msg("order_cancelled", 1)))
+	assert.NoError(t, router.Handle(ctx, &EventConsumeMessage{}), "message without event metadata")
+}
+
+func TestRouterVersionWithoutEventHandler(t *testing.T) {
+	router := NewRouter().OnVersion("order_created", 2, func(ctx context.Context, message *EventConsumeMessage) error {
+		return nil
+	})
+
+	err := router.Handle(context.Background(), &EventConsumeMessage{
+		Metadata: map[string]interface{}{MetaEvent: "order_created", MetaVersion: 1},
+	})
+	assert.ErrorIs(t, err, ErrUnknownEvent)
+	assert.EqualError(t, err, "[event/router] unknown event: order_created v1")
+}
+
+func TestRouterDeadLetterFallback(t *testing.T) {
+	sender := &testSender{}
+	router := NewRouter().Fallback(DeadLetterUnknownEvent(sender, ""))
+
+	ctx := context.WithValue(context.Background(), consumerGroupKey, "group")
+	require.NoError(t, router.Handle(ctx, &EventConsumeMessage{
+		Topic:    "order",
+		Key:      "o123",
+		Data:     []byte(`{"id":"o123"}`),
+		Metadata: map[string]interface{}{MetaEvent: "order_cancelled"},
+	}))
+
+	require.Equal(t, 1, sender.count())
+	msg := sender.sent[0]
+	assert.Equal(t, "order"+DefaultDLQTopicSuffix, msg.Topic)
+	assert.Equal(t, "o123", msg.Key)
+	assert.Equal(t, "order", msg.Metadata[MetaDLQOriginalTopic])
+	assert.Equal(t, "group", msg.Metadata[MetaDLQGroup])
+	assert.Equal(t, "[event/router] unknown event: order_cancelled v1", msg.Metadata[MetaDLQError])
+
+	router = NewRouter().Fallback(DeadLetterUnknownEvent(sender, "unknown"))
+	require.NoError(t, router.Handle(ctx, &EventConsumeMessage{Topic: "order"}))
+	assert.Equal(t, "unknown", sender.sent[1].Topic)
+}
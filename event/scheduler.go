@@ -0,0 +1,297 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/diki-haryadi/govega/lock"
+	"github.com/diki-haryadi/govega/log"
+	"github.com/diki-haryadi/govega/monitor"
+)
+
+const (
+	MetaDueAt = "due_at"
+
+	DefaultSchedulerInterval     = time.Second
+	DefaultSchedulerBatchSize    = 100
+	DefaultSchedulerClaimTimeout = 5 * time.Minute
+	DefaultSchedulerLockKey      = "event-scheduler"
+	DefaultSchedulerLockTTL      = 30 * time.Second
+)
+
+var (
+	ErrSchedulerStarted = errors.New("Scheduler already started")
+	// ErrScheduleUnsupported returned when the writer doesn't support scheduled message
+	ErrScheduleUnsupported = errors.New("[event/scheduler] writer doesn't support scheduled message")
+)
+
+type (
+	// ScheduleWriter is implemented by writer which able to store scheduled message
+	ScheduleWriter interface {
+		// Schedule store the outbox record to be released at the due time
+		Schedule(ctx context.Context, record *OutboxRecord, dueAt time.Time) error
+		// CancelSchedule delete unreleased scheduled records of the topic and key,
+		// it return number of records deleted
+		CancelSchedule(ctx context.Context, topic, key string) (int, error)
+		// ReleaseDue claim scheduled records due before opt.DueBefore and pass them to fn
+		// ordered by due time. Record will be deleted when fn succeed and released
+		// otherwise, it return number of records successfully released
+		ReleaseDue(ctx context.Context, opt *ReleaseOption, fn RelayFunc) (int, error)
+	}
+
+	ReleaseOption struct {
+		// DueBefore only claim record due before this time
+		DueBefore time.Time
+		// Limit maximum number of record claimed
+		Limit int
+		// ClaimTimeout duration before a claimed record can be claimed by another scheduler
+		// drivers which hold a lock during release (e.g. sql) may ignore this
+		ClaimTimeout time.Duration
+	}
+
+	SchedulerConfig struct {
+		// Interval between scheduled records scan, default: 1s
+		Interval string `json:"interval" mapstructure:"interval"`
+		// BatchSize maximum number of record released on each scan, default: 100
+		BatchSize int `json:"batch_size" mapstructure:"batch_size"`
+		// ClaimTimeout duration before a claimed record can be reclaimed, default: 5m
+		ClaimTimeout string `json:"claim_timeout" mapstructure:"claim_timeout"`
+		// Lock url of the distributed lock used to coordinate schedulers across instances, e.g. redis://localhost:6379
+		// the lock driver should be imported, default: local
+		Lock string `json:"lock" mapstructure:"lock"`
+		// LockKey key of the distributed lock, default: event-scheduler
+		LockKey string `json:"lock_key" mapstructure:"lock_key"`
+		// LockTTL duration the lock is held when the scheduler crash, default: 30s
+		LockTTL string `json:"lock_ttl" mapstructure:"lock_ttl"`
+	}
+
+	// Scheduler periodically send scheduled records which are due,
+	// only one instance holding the lock release the records on each scan
+	Scheduler struct {
+		writer       ScheduleWriter
+		sender       Sender
+		locker       lock.DLocker
+		interval     time.Duration
+		claimTimeout time.Duration
+		batchSize    int
+		lockKey      string
+		lockTTL      int
+		running      uint32
+		lock         sync.Mutex
+		stopch       chan bool
+		shutdown     chan bool
+	}
+)
+
+// NewScheduler create new instance of scheduler, writer should implement ScheduleWriter
+func NewScheduler(writer Writer, sender Sender, locker lock.DLocker, config *SchedulerConfig) (*Scheduler, error) {
+	sw, ok := writer.(ScheduleWriter)
+	if !ok {
+		return nil, ErrScheduleUnsupported
+	}
+
+	if sender == nil {
+		return nil, errors.New("[event/scheduler] missing sender")
+	}
+
+	if locker == nil {
+		return nil, errors.New("[event/scheduler] missing locker")
+	}
+
+	s := &Scheduler{
+		writer:       sw,
+		sender:       sender,
+		locker:       locker,
+		interval:     DefaultSchedulerInterval,
+		claimTimeout: DefaultSchedulerClaimTimeout,
+		batchSize:    DefaultSchedulerBatchSize,
+		lockKey:      DefaultSchedulerLockKey,
+		lockTTL:      int(DefaultSchedulerLockTTL.Seconds()),
+		running:      stop,
+	}
+
+	if config == nil {
+		return s, nil
+	}
+
+	if config.Interval != "" {
+		interval, err := time.ParseDuration(config.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("[event/scheduler] invalid interval value: %w", err)
+		}
+
+		s.interval = interval
+	}
+
+	if config.ClaimTimeout != "" {
+		timeout, err := time.ParseDuration(config.ClaimTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("[event/scheduler] invalid claim timeout value: %w", err)
+		}
+
+		s.claimTimeout = timeout
+	}
+
+	if config.LockTTL != "" {
+		ttl, err := time.ParseDuration(config.LockTTL)
+		if err != nil {
+			return nil, fmt.Errorf("[event/scheduler] invalid lock ttl value: %w", err)
+		}
+
+		// lock ttl is in seconds
+		s.lockTTL = int(math.Max(1, math.Ceil(ttl.Seconds())))
+	}
+
+	if config.LockKey != "" {
+		s.lockKey = config.LockKey
+	}
+
+	if config.BatchSize > 0 {
+		s.batchSize = config.BatchSize
+	}
+
+	return s, nil
+}
+
+// Start run the scheduler periodically in background
+func (s *Scheduler) Start() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.isRunning() {
+		return ErrSchedulerStarted
+	}
+
+	s.stopch = make(chan bool)
+	s.shutdown = make(chan bool)
+
+	go s.run(s.stopch, s.shutdown)
+
+	atomic.StoreUint32(&s.running, start)
+
+	return nil
+}
+
+// Stop stop the scheduler waiting for the running scan to complete
+func (s *Scheduler) Stop() error {
+	return s.StopContext(context.Background())
+}
+
+// StopContext stop the scheduler or until the context timeout
+func (s *Scheduler) StopContext(ctx context.Context) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.isRunning() {
+		return nil
+	}
+
+	close(s.stopch)
+
+	var err error
+	select {
+	case <-ctx.Done():
+		log.Errorln("[event/scheduler] timeout waiting scheduler to stop")
+		err = ctx.Err()
+	case <-s.shutdown:
+	}
+
+	s.stopch = nil
+	s.shutdown = nil
+	atomic.StoreUint32(&s.running, stop)
+
+	return err
+}
+
+// ReleaseOnce send a batch of due scheduled records through the sender when the lock is acquired,
+// records with the same topic and key are skipped after the first failure to keep the order
+func (s *Scheduler) ReleaseOnce(ctx context.Context) (int, error) {
+	if err := s.locker.TryLock(ctx, s.lockKey, s.lockTTL); err != nil {
+		if errors.Is(err, lock.ErrResourceLocked) {
+			// released by another instance
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	defer func() {
+		if err := s.locker.Unlock(ctx, s.lockKey); err != nil {
+			log.WithError(err).Errorln("[event/scheduler] failed to unlock")
+		}
+	}()
+
+	failed := make(map[string]bool)
+
+	opt := &ReleaseOption{
+		DueBefore:    time.Now(),
+		Limit:        s.batchSize,
+		ClaimTimeout: s.claimTimeout,
+	}
+
+	return s.writer.ReleaseDue(ctx, opt, func(ctx context.Context, record *OutboxRecord) error {
+		orderKey := ""
+		if record.Key != "" {
+			orderKey = record.Topic + "/" + record.Key
+			if failed[orderKey] {
+				return errRelaySkipped
+			}
+		}
+
+		start := time.Now()
+		err := s.send(ctx, record)
+		monitor.FeedOutboxRelayMetrics(record.Topic, getMetricStatusFromError(err), time.Since(start))
+
+		if err != nil {
+			if orderKey != "" {
+				failed[orderKey] = true
+			}
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (s *Scheduler) send(ctx context.Context, record *OutboxRecord) error {
+	msg, err := record.ToMessage()
+	if err != nil {
+		return err
+	}
+
+	return s.sender.Send(ctx, msg)
+}
+
+func (s *Scheduler) run(stop <-chan bool, shutdown chan<- bool) {
+	defer close(shutdown)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			// don't use cancelable context, let the running batch complete
+			// so claimed records are released properly
+			n, err := s.ReleaseOnce(context.Background())
+			if err != nil {
+				log.WithError(err).Errorln("[event/scheduler] failed to release scheduled records")
+				continue
+			}
+
+			if n > 0 {
+				log.WithFields(log.Fields{"released": n}).Infoln("[event/scheduler] scheduled records released")
+			}
+		}
+	}
+}
+
+func (s *Scheduler) isRunning() bool {
+	return atomic.LoadUint32(&s.running) == start
+}
@@ -0,0 +1,242 @@
+package event
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/diki-haryadi/govega/lock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	testScheduleWriter struct {
+		*EventLogger
+		lock      sync.Mutex
+		scheduled []*testScheduled
+	}
+
+	testScheduled struct {
+		record *OutboxRecord
+		dueAt  time.Time
+	}
+)
+
+func (w *testScheduleWriter) Schedule(ctx context.Context, record *OutboxRecord, dueAt time.Time) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.scheduled = append(w.scheduled, &testScheduled{record: record, dueAt: dueAt})
+	return nil
+}
+
+func (w *testScheduleWriter) CancelSchedule(ctx context.Context, topic, key string) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	remaining := make([]*testScheduled, 0)
+	for _, s := range w.scheduled {
+		if s.record.Topic != topic || s.record.Key != key {
+			remaining = append(remaining, s)
+		}
+	}
+
+	canceled := len(w.scheduled) - len(remaining)
+	w.scheduled = remaining
+	return canceled, nil
+}
+
+func (w *testScheduleWriter) ReleaseDue(ctx context.Context, opt *ReleaseOption, fn RelayFunc) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	sort.SliceStable(w.scheduled, func(i, j int) bool {
+		return w.scheduled[i].dueAt.Before(w.scheduled[j].dueAt)
+	})
+
+	remaining := make([]*testScheduled, 0)
+	released := 0
+	for _, s := range w.scheduled {
+		if released >= opt.Limit || s.dueAt.After(opt.DueBefore) {
+			remaining = append(remaining, s)
+			continue
+		}
+
+		if err := fn(ctx, s.record); err != nil {
+			remaining = append(remaining, s)
+			continue
+		}
+		released++
+	}
+	w.scheduled = remaining
+
+	return released, nil
+}
+
+func (w *testScheduleWriter) count() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return len(w.scheduled)
+}
+
+func newTestScheduler(t *testing.T, writer Writer, sender Sender, locker lock.DLocker) *Scheduler {
+	if locker == nil {
+		locker, _ = lock.Local()
+	}
+
+	s, err := NewScheduler(writer, sender, locker, &SchedulerConfig{BatchSize: 10})
+	require.NoError(t, err)
+	return s
+}
+
+func TestSchedulerReleaseOnce(t *testing.T) {
+	writer := &testScheduleWriter{}
+	sender := &testSender{}
+	ctx := context.Background()
+
+	now := time.Now()
+	for i, due := range []time.Duration{time.Hour, -time.Minute, -time.Hour} {
+		rec, err := OutboxFromMessage(&EventMessage{Topic: "test", Key: string(rune('a' + i)), Data: i})
+		require.NoError(t, err)
+		require.NoError(t, writer.Schedule(ctx, rec, now.Add(due)))
+	}
+
+	s := newTestScheduler(t, writer, sender, nil)
+	n, err := s.ReleaseOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 1, writer.count(), "record not yet due is kept")
+
+	require.Equal(t, 2, sender.count())
+	assert.Equal(t, "c", sender.sent[0].Key, "released by due time")
+	assert.Equal(t, "b", sender.sent[1].Key)
+}
+
+func TestSchedulerKeepOrder(t *testing.T) {
+	writer := &testScheduleWriter{}
+	sender := &testSender{failFn: func(message *EventMessage) bool {
+		return string(message.RawData) == "1"
+	}}
+	ctx := context.Background()
+
+	now := time.Now()
+	for i := 1; i <= 3; i++ {
+		rec, err := OutboxFromMessage(&EventMessage{Topic: "test", Key: "k1", Data: i})
+		require.NoError(t, err)
+		require.NoError(t, writer.Schedule(ctx, rec, now.Add(time.Duration(i-10)*time.Second)))
+	}
+
+	s := newTestScheduler(t, writer, sender, nil)
+	n, err := s.ReleaseOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "records after the failed one are skipped")
+	assert.Equal(t, 3, writer.count())
+}
+
+func TestSchedulerLocked(t *testing.T) {
+	writer := &testScheduleWriter{}
+	sender := &testSender{}
+	ctx := context.Background()
+
+	rec, err := OutboxFromMessage(&EventMessage{Topic: "test", Key: "k1", Data: "data"})
+	require.NoError(t, err)
+	require.NoError(t, writer.Schedule(ctx, rec, time.Now().Add(-time.Second)))
+
+	locker, _ := lock.Local()
+	require.NoError(t, locker.TryLock(ctx, DefaultSchedulerLockKey, 30))
+
+	s := newTestScheduler(t, writer, sender, locker)
+	n, err := s.ReleaseOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "released by instance holding the lock")
+	assert.Equal(t, 1, writer.count())
+
+	require.NoError(t, locker.Unlock(ctx, DefaultSchedulerLockKey))
+	n, err = s.ReleaseOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestNewSchedulerInvalid(t *testing.T) {
+	locker, _ := lock.Local()
+
+	_, err := NewScheduler(&testWriter{}, &testSender{}, locker, nil)
+	assert.ErrorIs(t, err, ErrScheduleUnsupported)
+
+	_, err = NewScheduler(&testScheduleWriter{}, nil, locker, nil)
+	assert.Error(t, err)
+
+	_, err = NewScheduler(&testScheduleWriter{}, &testSender{}, nil, nil)
+	assert.Error(t, err)
+
+	_, err = NewScheduler(&testScheduleWriter{}, &testSender{}, locker, &SchedulerConfig{LockTTL: "invalid"})
+	assert.Error(t, err)
+
+	s, err := NewScheduler(&testScheduleWriter{}, &testSender{}, locker, &SchedulerConfig{LockTTL: "1500ms"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.lockTTL)
+}
+
+func TestEmitterPublishAt(t *testing.T) {
+	writer := &testScheduleWriter{}
+	sender := &testSender{}
+	RegisterSender("scheduler-test", func(ctx context.Context, config interface{}) (Sender, error) {
+		return sender, nil
+	})
+	RegisterWriter("scheduler-test", func(ctx context.Context, config interface{}) (Writer, error) {
+		return writer, nil
+	})
+
+	ctx := context.Background()
+	em, err := New(ctx, &EmitterConfig{
+		Sender:    &DriverConfig{Type: "scheduler-test"},
+		Writer:    &DriverConfig{Type: "scheduler-test"},
+		Scheduler: &SchedulerConfig{Interval: "10ms"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, em.PublishAfter(ctx, 50*time.Millisecond, "payment_timeout", "p1", "data", nil))
+	require.NoError(t, em.PublishAfter(ctx, time.Hour, "payment_timeout", "p2", "data", nil))
+	require.NoError(t, em.PublishAt(ctx, time.Now().Add(time.Hour), "payment_timeout", "p3", "data", nil))
+	assert.Equal(t, 3, writer.count())
+	assert.Equal(t, 0, sender.count(), "scheduled message is not sent right away")
+
+	n, err := em.CancelScheduled(ctx, "payment_timeout", "p2")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	_, err = em.CancelScheduled(ctx, "payment_timeout", "")
+	assert.Error(t, err)
+
+	assert.Eventually(t, func() bool {
+		return sender.count() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	msg := sender.sent[0]
+	assert.Equal(t, "p1", msg.Key)
+	assert.Equal(t, "payment_timeout", msg.Metadata[MetaEvent])
+	assert.Contains(t, msg.Metadata, MetaDueAt)
+	assert.Equal(t, 1, writer.count())
+
+	require.NoError(t, em.Close(ctx))
+	assert.ErrorIs(t, em.PublishAfter(ctx, time.Minute, "payment_timeout", "p4", "data", nil), ErrEmitterClosed)
+}
+
+func TestEmitterScheduleUnsupported(t *testing.T) {
+	ctx := context.Background()
+
+	em, err := New(ctx, &EmitterConfig{Writer: &DriverConfig{Type: "logger"}})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, em.PublishAfter(ctx, time.Minute, "test", "k1", "data", nil), ErrScheduleUnsupported)
+	_, err = em.CancelScheduled(ctx, "test", "k1")
+	assert.ErrorIs(t, err, ErrScheduleUnsupported)
+
+	_, err = New(ctx, &EmitterConfig{Writer: &DriverConfig{Type: "logger"}, Scheduler: &SchedulerConfig{}})
+	assert.ErrorIs(t, err, ErrScheduleUnsupported)
+
+	_, err = New(ctx, &EmitterConfig{Scheduler: &SchedulerConfig{}})
+	assert.Error(t, err, "scheduler requires writer")
+}
@@ -0,0 +1,333 @@
+package event
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const DefaultEventVersion = 1
+
+var (
+	ErrSchemaNotFound     = errors.New("schema not found")
+	ErrInvalidPayload     = errors.New("payload doesn't match schema")
+	ErrUnsupportedVersion = errors.New("unsupported event version")
+
+	schemaFormats = map[string]SchemaFactory{
+		"json": NewJSONSchema,
+	}
+	schemaRegistries = map[string]SchemaRegistryFactory{
+		"file": NewFileSchemaRegistry,
+	}
+)
+
+type (
+	// Schema validate json encoded event payload
+	Schema interface {
+		Validate(data []byte) error
+	}
+
+	// SchemaRegistry provide schema for specific event version
+	SchemaRegistry interface {
+		GetSchema(ctx context.Context, event string, version int) (Schema, error)
+	}
+
+	SchemaConfig struct {
+		// Registry schema registry driver
+		Registry *DriverConfig `json:"registry" mapstructure:"registry"`
+		// Strict reject event without registered schema
+		Strict bool `json:"strict" mapstructure:"strict"`
+	}
+
+	// FileSchemaRegistry load schema from local directory,
+	// schema file should be named as <event>.v<version>.<format>, e.g. order_created.v1.json.
+	// Loaded and missing schemas are cached, call Reload to pick up changed files
+	FileSchemaRegistry struct {
+		Path    string `json:"path" mapstructure:"path"`
+		schemas map[string]Schema
+		lock    sync.RWMutex
+	}
+
+	// UpcastFunc convert event payload from a version into the next version
+	UpcastFunc func(ctx context.Context, data []byte) ([]byte, error)
+
+	// Upcaster convert older event payload into the latest version before handled
+	Upcaster struct {
+		upcasters map[string]map[int]UpcastFunc
+	}
+
+	// VersionHandler route message to the handler registered for the message version
+	VersionHandler map[int]EventHandler
+
+	SchemaFactory         func(definition []byte) (Schema, error)
+	SchemaRegistryFactory func(ctx context.Context, config interface{}) (SchemaRegistry, error)
+
+	jsonSchema struct {
+		schema *jsonschema.Schema
+	}
+)
+
+// RegisterSchemaFormat register schema format, name is used as schema file extension by file registry
+func RegisterSchemaFormat(name string, fn SchemaFactory) {
+	schemaFormats[name] = fn
+}
+
+func RegisterSchemaRegistry(name string, fn SchemaRegistryFactory) {
+	schemaRegistries[name] = fn
+}
+
+// NewSchemaRegistry create schema registry from driver config
+func NewSchemaRegistry(ctx context.Context, config *DriverConfig) (SchemaRegistry, error) {
+	if config == nil {
+		return nil, errors.New("[event/schema] missing registry driver config")
+	}
+
+	factory, ok := schemaRegistries[config.Type]
+	if !ok {
+		return nil, fmt.Errorf("[event/schema] unsupported registry driver: %s", config.Type)
+	}
+
+	return factory(ctx, config.Config)
+}
+
+// NewJSONSchema compile json schema definition
+func NewJSONSchema(definition []byte) (Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("mem:///schema.json", bytes.NewReader(definition)); err != nil {
+		return nil, err
+	}
+
+	schema, err := compiler.Compile("mem:///schema.json")
+	if err != nil {
+		return nil, err
+	}
+
+	return &jsonSchema{schema: schema}, nil
+}
+
+func (s *jsonSchema) Validate(data []byte) error {
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return err
+	}
+
+	return s.schema.Validate(v)
+}
+
+func NewFileSchemaRegistry(_ context.Context, config interface{}) (SchemaRegistry, error) {
+	var registry FileSchemaRegistry
+	if err := mapstructure.Decode(config, &registry); err != nil {
+		return nil, err
+	}
+
+	if registry.Path == "" {
+		return nil, errors.New("[event/schema] missing path param")
+	}
+
+	registry.schemas = make(map[string]Schema)
+	return &registry, nil
+}
+
+func (f *FileSchemaRegistry) GetSchema(_ context.Context, event string, version int) (Schema, error) {
+	name := fmt.Sprintf("%s.v%d", event, version)
+
+	f.lock.RLock()
+	schema, ok := f.schemas[name]
+	f.lock.RUnlock()
+
+	if ok {
+		// nil schema cache the missing schema, avoid scanning the directory on each publish
+		if schema == nil {
+			return nil, ErrSchemaNotFound
+		}
+		return schema, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(f.Path, name+".*"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		factory, ok := schemaFormats[strings.TrimPrefix(filepath.Ext(file), ".")]
+		if !ok {
+			continue
+		}
+
+		definition, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		schema, err := factory(definition)
+		if err != nil {
+			return nil, fmt.Errorf("[event/schema] invalid schema %s: %w", file, err)
+		}
+
+		f.lock.Lock()
+		f.schemas[name] = schema
+		f.lock.Unlock()
+
+		return schema, nil
+	}
+
+	f.lock.Lock()
+	f.schemas[name] = nil
+	f.lock.Unlock()
+
+	return nil, ErrSchemaNotFound
+}
+
+// Reload clear the cached schemas so they are loaded again from the directory
+func (f *FileSchemaRegistry) Reload() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.schemas = make(map[string]Schema)
+}
+
+// GetMetadataVersion return event version from metadata,
+// DefaultEventVersion is returned when version is not available
+func GetMetadataVersion(metadata map[string]interface{}) int {
+	switch v := metadata[MetaVersion].(type) {
+	case int:
+		return v
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return int(i)
+		}
+	case string:
+		if i, err := strconv.Atoi(strings.TrimPrefix(v, "v")); err == nil {
+			return i
+		}
+	}
+
+	return DefaultEventVersion
+}
+
+// GetMetadataEvent return event name from metadata
+func GetMetadataEvent(metadata map[string]interface{}) string {
+	if v, ok := metadata[MetaEvent].(string); ok {
+		return v
+	}
+	return ""
+}
+
+func (e *Emitter) validate(ctx context.Context, event string, message interface{},
+	metadata map[string]interface{}) error {
+	version := GetMetadataVersion(metadata)
+
+	schema, err := e.schemaRegistry.GetSchema(ctx, event, version)
+	if err != nil {
+		if errors.Is(err, ErrSchemaNotFound) && !e.strictSchema {
+			return nil
+		}
+
+		return fmt.Errorf("[event/emitter] failed to get schema of %s v%d: %w", event, version, err)
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	if err := schema.Validate(data); err != nil {
+		return fmt.Errorf("[event/emitter] %w of %s v%d: %s", ErrInvalidPayload, event, version, err)
+	}
+
+	return nil
+}
+
+func NewUpcaster() *Upcaster {
+	return &Upcaster{
+		upcasters: make(map[string]map[int]UpcastFunc),
+	}
+}
+
+// Register register function to convert event payload from version into version + 1
+func (u *Upcaster) Register(event string, version int, fn UpcastFunc) *Upcaster {
+	if _, ok := u.upcasters[event]; !ok {
+		u.upcasters[event] = make(map[int]UpcastFunc)
+	}
+
+	u.upcasters[event][version] = fn
+	return u
+}
+
+// Upcast convert message payload into the latest registered version and update the metadata version
+func (u *Upcaster) Upcast(ctx context.Context, message *EventConsumeMessage) error {
+	fns, ok := u.upcasters[GetMetadataEvent(message.Metadata)]
+	if !ok {
+		return nil
+	}
+
+	version := GetMetadataVersion(message.Metadata)
+	data := message.Data
+	upcasted := false
+
+	for fn, ok := fns[version]; ok; fn, ok = fns[version] {
+		var err error
+		data, err = fn(ctx, data)
+		if err != nil {
+			return fmt.Errorf("[event/upcaster] failed to upcast version %d: %w", version, err)
+		}
+
+		version++
+		upcasted = true
+	}
+
+	if !upcasted {
+		return nil
+	}
+
+	metadata := make(map[string]interface{}, len(message.Metadata))
+	for k, v := range message.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetaVersion] = version
+
+	message.Data = data
+	message.Metadata = metadata
+	return nil
+}
+
+// Middleware return consumer middleware which upcast message before calling the handler
+func (u *Upcaster) Middleware() EventMiddleware {
+	return func(next EventHandler) EventHandler {
+		return func(ctx context.Context, message *EventConsumeMessage) error {
+			if err := u.Upcast(ctx, message); err != nil {
+				return err
+			}
+			return next(ctx, message)
+		}
+	}
+}
+
+// Handle call handler registered for the message version
+func (v VersionHandler) Handle(ctx context.Context, message *EventConsumeMessage) error {
+	version := GetMetadataVersion(message.Metadata)
+
+	handler, ok := v[version]
+	if !ok {
+		return fmt.Errorf("[event/versionHandler] %w: %d", ErrUnsupportedVersion, version)
+	}
+
+	return handler(ctx, message)
+}
@@ -0,0 +1,184 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOrderSchema = `{
+	"type": "object",
+	"required": ["id", "amount"],
+	"properties": {
+		"id": {"type": "string"},
+		"amount": {"type": "number"}
+	}
+}`
+
+func newTestSchemaDir(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "order_created.v1.json"), []byte(testOrderSchema), 0644))
+	return dir
+}
+
+func TestFileSchemaRegistry(t *testing.T) {
+	ctx := context.Background()
+
+	registry, err := NewSchemaRegistry(ctx, &DriverConfig{
+		Type:   "file",
+		Config: map[string]interface{}{"path": newTestSchemaDir(t)},
+	})
+	require.NoError(t, err)
+
+	schema, err := registry.GetSchema(ctx, "order_created", 1)
+	require.NoError(t, err)
+
+	assert.NoError(t, schema.Validate([]byte(`{"id":"o1","amount":10}`)))
+	assert.Error(t, schema.Validate([]byte(`{"id":"o1"}`)))
+	assert.Error(t, schema.Validate([]byte(`{"id":1,"amount":10}`)))
+
+	_, err = registry.GetSchema(ctx, "order_created", 2)
+	assert.ErrorIs(t, err, ErrSchemaNotFound)
+}
+
+func TestFileSchemaRegistryReload(t *testing.T) {
+	ctx := context.Background()
+	dir := newTestSchemaDir(t)
+
+	registry, err := NewFileSchemaRegistry(ctx, map[string]interface{}{"path": dir})
+	require.NoError(t, err)
+
+	_, err = registry.GetSchema(ctx, "order_created", 2)
+	assert.ErrorIs(t, err, ErrSchemaNotFound)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "order_created.v2.json"), []byte(testOrderSchema), 0644))
+
+	_, err = registry.GetSchema(ctx, "order_created", 2)
+	assert.ErrorIs(t, err, ErrSchemaNotFound, "missing schema is cached")
+
+	registry.(*FileSchemaRegistry).Reload()
+
+	schema, err := registry.GetSchema(ctx, "order_created", 2)
+	require.NoError(t, err)
+	assert.NoError(t, schema.Validate([]byte(`{"id":"o1","amount":10}`)))
+}
+
+func TestEmitterSchemaValidation(t *testing.T) {
+	ctx := context.Background()
+
+	conf := &EmitterConfig{
+		Sender: &DriverConfig{Type: "logger"},
+		Schema: &SchemaConfig{
+			Registry: &DriverConfig{
+				Type:   "file",
+				Config: map[string]interface{}{"path": newTestSchemaDir(t)},
+			},
+		},
+	}
+
+	em, err := New(ctx, conf)
+	require.NoError(t, err)
+
+	order := map[string]interface{}{"id": "o1", "amount": 10}
+	assert.NoError(t, em.Publish(ctx, "order_created", "o1", order, nil))
+
+	err = em.Publish(ctx, "order_created", "o1", map[string]interface{}{"id": "o1"}, nil)
+	assert.ErrorIs(t, err, ErrInvalidPayload)
+
+	// no schema registered for unknown event
+	assert.NoError(t, em.Publish(ctx, "unknown", "o1", "testdata", nil))
+
+	em.WithSchemaRegistry(em.schemaRegistry, true)
+	err = em.Publish(ctx, "unknown", "o1", "testdata", nil)
+	assert.ErrorIs(t, err, ErrSchemaNotFound)
+}
+
+func TestGetMetadataVersion(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected int
+	}{
+		{nil, DefaultEventVersion},
+		{2, 2},
+		{int64(3), 3},
+		{float64(4), 4},
+		{json.Number("5"), 5},
+		{"6", 6},
+		{"v7", 7},
+		{"invalid", DefaultEventVersion},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, GetMetadataVersion(map[string]interface{}{MetaVersion: tt.value}))
+	}
+}
+
+func TestUpcaster(t *testing.T) {
+	upcaster := NewUpcaster().
+		Register("order_created", 1, func(ctx context.Context, data []byte) ([]byte, error) {
+			return []byte(`{"v":2}`), nil
+		}).
+		Register("order_created", 2, func(ctx context.Context, data []byte) ([]byte, error) {
+			return []byte(`{"v":3}`), nil
+		})
+
+	var handled *EventConsumeMessage
+	handler := upcaster.Middleware()(func(ctx context.Context, message *EventConsumeMessage) error {
+		handled = message
+		return nil
+	})
+
+	msg := &EventConsumeMessage{
+		Data:     []byte(`{"v":1}`),
+		Metadata: map[string]interface{}{MetaEvent: "order_created", MetaVersion: float64(1)},
+	}
+	require.NoError(t, handler(context.Background(), msg))
+	assert.Equal(t, `{"v":3}`, string(handled.Data))
+	assert.Equal(t, 3, handled.Metadata[MetaVersion])
+
+	msg = &EventConsumeMessage{
+		Data:     []byte(`{"v":3}`),
+		Metadata: map[string]interface{}{MetaEvent: "order_created", MetaVersion: 3},
+	}
+	require.NoError(t, handler(context.Background(), msg))
+	assert.Equal(t, `{"v":3}`, string(handled.Data))
+
+	failed := NewUpcaster().Register("order_created", 1, func(ctx context.Context, data []byte) ([]byte, error) {
+		return nil, errors.New("failed")
+	})
+	msg = &EventConsumeMessage{Metadata: map[string]interface{}{MetaEvent: "order_created"}}
+	assert.Error(t, failed.Upcast(context.Background(), msg))
+}
+
+func TestVersionHandler(t *testing.T) {
+	called := 0
+	handler := VersionHandler{
+		1: func(ctx context.Context, message *EventConsumeMessage) error {
+			called = 1
+			return nil
+		},
+		2: func(ctx context.Context, message *EventConsumeMessage) error {
+			called = 2
+			return nil
+		},
+	}
+
+	require.NoError(t, handler.Handle(context.Background(), &EventConsumeMessage{
+		Metadata: map[string]interface{}{MetaVersion: float64(2)},
+	}))
+	assert.Equal(t, 2, called)
+
+	require.NoError(t, handler.Handle(context.Background(), &EventConsumeMessage{}))
+	assert.Equal(t, 1, called)
+
+	err := handler.Handle(context.Background(), &EventConsumeMessage{
+		Metadata: map[string]interface{}{MetaVersion: 3},
+	})
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+}
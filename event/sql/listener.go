@@ -0,0 +1,238 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/diki-haryadi/govega/event"
+	"github.com/jmoiron/sqlx"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	DefaultVisibilityTimeout = 30 * time.Second
+	DefaultPollInterval      = time.Second
+)
+
+// ErrClaimLost returned on commit when the row is claimed again by other consumer after the visibility timeout
+var ErrClaimLost = errors.New("[event/sql] message is claimed again by other consumer")
+
+type (
+	// SQLListener consume table as a work queue, rows are claimed using SELECT ... FOR UPDATE SKIP LOCKED
+	// and hidden from the other consumers until the visibility timeout. The table has the outbox columns
+	// so it can be written by sql sender, with additional attempts and visible_at columns
+	// (and done_at when MarkDone is enabled).
+	// Every consumer of the topic compete for the rows regardless of the group.
+	// Requires database supporting SKIP LOCKED (MySQL 8, PostgreSQL 9.5)
+	SQLListener struct {
+		Driver     string      `json:"driver" mapstructure:"driver"`
+		Connection interface{} `json:"connection" mapstructure:"connection"`
+		Table      string      `json:"table" mapstructure:"table"`
+		// VisibilityTimeout claimed row is redelivered when it is not committed within the timeout, default 30s
+		VisibilityTimeout string `json:"visibility_timeout" mapstructure:"visibility_timeout"`
+		// PollInterval how long to wait before querying the table again when there is no row available, default 1s
+		PollInterval string `json:"poll_interval" mapstructure:"poll_interval"`
+		// MaxAttempts row which has been claimed max attempts times is not claimed again, default 0 unlimited
+		MaxAttempts int `json:"max_attempts" mapstructure:"max_attempts"`
+		// MarkDone set done_at of the committed row instead of deleting it
+		MarkDone bool `json:"mark_done" mapstructure:"mark_done"`
+
+		db                *sqlx.DB
+		visibilityTimeout time.Duration
+		pollInterval      time.Duration
+	}
+
+	SQLIterator struct {
+		listener *SQLListener
+		topic    string
+	}
+
+	// SQLQueueRecord row claimed from the queue table
+	SQLQueueRecord struct {
+		SQLOutbox
+		Attempts int `db:"attempts"`
+	}
+
+	SQLConsumeMessage struct {
+		*SQLQueueRecord
+		listener *SQLListener
+	}
+)
+
+func NewSQLListener(_ context.Context, config interface{}) (event.Listener, error) {
+	var listener SQLListener
+	if err := mapstructure.Decode(config, &listener); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	if listener.Connection == nil {
+		return nil, errors.New("[event/sql] missing connection param")
+	}
+
+	if listener.Table == "" {
+		return nil, errors.New("[event/sql] missing table param")
+	}
+
+	listener.visibilityTimeout = DefaultVisibilityTimeout
+	if listener.VisibilityTimeout != "" {
+		timeout, err := time.ParseDuration(listener.VisibilityTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sql listener visibility timeout value: %w", err)
+		}
+		listener.visibilityTimeout = timeout
+	}
+
+	listener.pollInterval = DefaultPollInterval
+	if listener.PollInterval != "" {
+		interval, err := time.ParseDuration(listener.PollInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sql listener poll interval value: %w", err)
+		}
+		listener.pollInterval = interval
+	}
+
+	db, err := connect(listener.Driver, listener.Connection)
+	if err != nil {
+		return nil, err
+	}
+
+	listener.db = db
+	return &listener, nil
+}
+
+func (l *SQLListener) Listen(_ context.Context, topic, _ string) (event.Iterator, error) {
+	return &SQLIterator{
+		listener: l,
+		topic:    topic,
+	}, nil
+}
+
+// Next claim the oldest visible row of the topic, waiting for poll interval when there is none
+func (it *SQLIterator) Next(ctx context.Context) (event.ConsumeMessage, error) {
+	for {
+		record, err := it.listener.claim(ctx, it.topic)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim message: %w", err)
+		}
+
+		if record != nil {
+			return &SQLConsumeMessage{
+				SQLQueueRecord: record,
+				listener:       it.listener,
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(it.listener.pollInterval):
+		}
+	}
+}
+
+// claim lock the row only while increasing its attempts and visibility,
+// the row is then hidden from the other consumers by visible_at
+func (l *SQLListener) claim(ctx context.Context, topic string) (*SQLQueueRecord, error) {
+	tx, err := l.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	stmt := fmt.Sprintf("SELECT id, topic, message_key, message_value, created_at, attempts FROM %s "+
+		"WHERE topic = ? AND (visible_at IS NULL OR visible_at <= ?)", l.Table)
+	args := []interface{}{topic, now}
+
+	if l.MarkDone {
+		stmt += " AND done_at IS NULL"
+	}
+
+	if l.MaxAttempts > 0 {
+		stmt += " AND attempts < ?"
+		args = append(args, l.MaxAttempts)
+	}
+
+	stmt += " ORDER BY created_at LIMIT 1 FOR UPDATE SKIP LOCKED"
+
+	var record SQLQueueRecord
+	if err := tx.GetContext(ctx, &record, tx.Rebind(stmt), args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	upStmt := fmt.Sprintf("UPDATE %s SET attempts = attempts + 1, visible_at = ? WHERE id = ?", l.Table)
+	if _, err := tx.ExecContext(ctx, tx.Rebind(upStmt), now.Add(l.visibilityTimeout), record.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	record.Attempts++
+	return &record, nil
+}
+
+func (m *SQLConsumeMessage) GetEventConsumeMessage(ctx context.Context) (*event.EventConsumeMessage, error) {
+	em, err := event.NewEventConsumeMessage([]byte(m.Value))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode message: %w", err)
+	}
+
+	em.Topic = m.Topic
+	if m.Key != "" {
+		em.Key = m.Key
+	}
+
+	return em, nil
+}
+
+// Commit delete the row, or set its done_at when mark done is enabled.
+// ErrClaimLost is returned when the row has been claimed again by other consumer
+func (m *SQLConsumeMessage) Commit(ctx context.Context) error {
+	l := m.listener
+
+	var (
+		res sql.Result
+		err error
+	)
+	if l.MarkDone {
+		stmt := fmt.Sprintf("UPDATE %s SET done_at = ? WHERE id = ? AND attempts = ?", l.Table)
+		if res, err = l.db.ExecContext(ctx, l.db.Rebind(stmt), time.Now(), m.ID, m.Attempts); err != nil {
+			return fmt.Errorf("failed to mark message done: %w", err)
+		}
+	} else {
+		stmt := fmt.Sprintf("DELETE FROM %s WHERE id = ? AND attempts = ?", l.Table)
+		if res, err = l.db.ExecContext(ctx, l.db.Rebind(stmt), m.ID, m.Attempts); err != nil {
+			return fmt.Errorf("failed to delete message: %w", err)
+		}
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrClaimLost
+	}
+
+	return nil
+}
+
+// Nack make the row visible again so it is redelivered without waiting for the visibility timeout,
+// ignored when the row has been claimed again by other consumer
+func (m *SQLConsumeMessage) Nack(ctx context.Context) error {
+	l := m.listener
+	stmt := fmt.Sprintf("UPDATE %s SET visible_at = ? WHERE id = ? AND attempts = ?", l.Table)
+	if _, err := l.db.ExecContext(ctx, l.db.Rebind(stmt), time.Now(), m.ID, m.Attempts); err != nil {
+		return fmt.Errorf("failed to nack message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package sql
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/diki-haryadi/govega/event"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func initQueueTable() *sqlx.DB {
+	db := initTable()
+
+	var schema = `
+	CREATE TABLE IF NOT EXISTS queue (
+		id VARCHAR(255) NOT NULL PRIMARY KEY,
+		topic VARCHAR(255) NOT NULL,
+		message_key VARCHAR(255),
+		message_value TEXT,
+		created_at TIMESTAMP(6),
+		attempts INT NOT NULL DEFAULT 0,
+		visible_at TIMESTAMP(6) NULL,
+		done_at TIMESTAMP(6) NULL
+	)  ENGINE=INNODB;`
+
+	db.MustExec("DROP TABLE IF EXISTS queue;")
+	db.MustExec(schema)
+	return db
+}
+
+func newTestQueue(t *testing.T, config map[string]interface{}) (*SQLSender, event.Iterator) {
+	db := initQueueTable()
+
+	sender, err := NewSQLOutbox(context.Background(), map[string]interface{}{
+		"driver":     "mysql",
+		"table":      "queue",
+		"connection": db,
+	})
+	require.Nil(t, err)
+
+	config["driver"] = "mysql"
+	config["table"] = "queue"
+	config["connection"] = db
+
+	listener, err := NewSQLListener(context.Background(), config)
+	require.Nil(t, err)
+
+	it, err := listener.Listen(context.Background(), "test", "group")
+	require.Nil(t, err)
+	return sender, it
+}
+
+func TestSQLListener(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	sender, it := newTestQueue(t, map[string]interface{}{
+		"visibility_timeout": "1s",
+		"poll_interval":      "10ms",
+		"max_attempts":       3,
+	})
+
+	ctx := context.Background()
+	require.Nil(t, sender.Send(ctx, &event.EventMessage{Topic: "test", Key: "a", Data: "testdata"}))
+	time.Sleep(10 * time.Millisecond)
+	require.Nil(t, sender.Send(ctx, &event.EventMessage{Topic: "test", Key: "b", Data: "testdata"}))
+
+	first, err := it.Next(ctx)
+	require.Nil(t, err)
+	em, err := first.GetEventConsumeMessage(ctx)
+	require.Nil(t, err)
+	assert.Equal(t, "a", em.Key)
+	assert.Equal(t, "test", em.Topic)
+	assert.Equal(t, 1, first.(*SQLConsumeMessage).Attempts)
+
+	second, err := it.Next(ctx)
+	require.Nil(t, err)
+	em, err = second.GetEventConsumeMessage(ctx)
+	require.Nil(t, err)
+	assert.Equal(t, "b", em.Key, "claimed message is hidden")
+	require.Nil(t, second.Commit(ctx))
+
+	require.Nil(t, first.(event.Nacker).Nack(ctx))
+	again, err := it.Next(ctx)
+	require.Nil(t, err)
+	assert.Equal(t, first.(*SQLConsumeMessage).ID, again.(*SQLConsumeMessage).ID, "nacked message is redelivered")
+	assert.Equal(t, 2, again.(*SQLConsumeMessage).Attempts)
+
+	// not committed within visibility timeout
+	expired, err := it.Next(ctx)
+	require.Nil(t, err)
+	assert.Equal(t, first.(*SQLConsumeMessage).ID, expired.(*SQLConsumeMessage).ID)
+	assert.Equal(t, 3, expired.(*SQLConsumeMessage).Attempts)
+	assert.ErrorIs(t, again.Commit(ctx), ErrClaimLost, "late commit doesn't delete the claimed row")
+
+	require.Nil(t, expired.(event.Nacker).Nack(ctx))
+	tctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = it.Next(tctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "max attempts exceeded")
+}
+
+func TestSQLListenerConcurrent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	sender, it := newTestQueue(t, map[string]interface{}{
+		"poll_interval": "10ms",
+		"mark_done":     true,
+	})
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		require.Nil(t, sender.Send(ctx, &event.EventMessage{Topic: "test", Data: i}))
+	}
+
+	var lock sync.Mutex
+	claimed := map[string]bool{}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 2; j++ {
+				msg, err := it.Next(ctx)
+				if !assert.Nil(t, err) {
+					return
+				}
+				assert.Nil(t, msg.Commit(ctx))
+
+				lock.Lock()
+				assert.False(t, claimed[msg.(*SQLConsumeMessage).ID], "message is claimed once")
+				claimed[msg.(*SQLConsumeMessage).ID] = true
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, claimed, 10)
+
+	var done int
+	require.Nil(t, sender.db.Get(&done, "SELECT COUNT(*) FROM queue WHERE done_at IS NOT NULL"))
+	assert.Equal(t, 10, done)
+}
+
+func TestSQLListenerConfig(t *testing.T) {
+	_, err := NewSQLListener(context.Background(), map[string]interface{}{"table": "queue"})
+	assert.EqualError(t, err, "[event/sql] missing connection param")
+
+	_, err = NewSQLListener(context.Background(), map[string]interface{}{"connection": map[string]interface{}{}})
+	assert.EqualError(t, err, "[event/sql] missing table param")
+
+	_, err = NewSQLListener(context.Background(), map[string]interface{}{
+		"connection":         map[string]interface{}{},
+		"table":              "queue",
+		"visibility_timeout": "invalid",
+	})
+	assert.Error(t, err)
+}
@@ -19,7 +19,9 @@ type SQLSender struct {
 	Driver     string      `json:"driver" mapstructure:"driver"`
 	Connection interface{} `json:"connection" mapstructure:"connection"`
 	Table      string      `json:"table" mapstructure:"table"`
-	db         *sqlx.DB
+	// ScheduleTable table of scheduled messages, required by emitter PublishAt
+	ScheduleTable string `json:"schedule_table" mapstructure:"schedule_table"`
+	db            *sqlx.DB
 }
 
 type SQLOutbox struct {
@@ -33,6 +35,7 @@ type SQLOutbox struct {
 func init() {
 	event.RegisterSender("sql", NewSQLSender)
 	event.RegisterWriter("sql", NewSQLWriter)
+	event.RegisterListener("sql", NewSQLListener)
 }
 
 func NewSQLSender(ctx context.Context, config interface{}) (event.Sender, error) {
@@ -57,45 +60,36 @@ func NewSQLOutbox(ctx context.Context, config interface{}) (*SQLSender, error) {
 		return nil, errors.New("[event/sql] missing table param")
 	}
 
-	switch con := ss.Connection.(type) {
+	db, err := connect(ss.Driver, ss.Connection)
+	if err != nil {
+		return nil, err
+	}
+
+	ss.db = db
+	return &ss, nil
+}
+
+// connect return database connection from *sqlx.DB or database.DBConfig connection param
+func connect(driver string, connection interface{}) (*sqlx.DB, error) {
+	switch con := connection.(type) {
 	case *sqlx.DB:
-		ss.db = con
-		return &ss, nil
+		return con, nil
 	case *database.DBConfig:
-		db := database.New(*con, ss.Driver)
-		ss.db = db.Master
-		return &ss, nil
+		return database.New(*con, driver).Master, nil
 	case database.DBConfig:
-		db := database.New(con, ss.Driver)
-		ss.db = db.Master
-		return &ss, nil
+		return database.New(con, driver).Master, nil
 	case map[string]interface{}:
 		var conf database.DBConfig
 		if err := util.DecodeJSON(con, &conf); err != nil {
 			return nil, err
 		}
-		db := database.New(conf, ss.Driver)
-		ss.db = db.Master
-		return &ss, nil
+		return database.New(conf, driver).Master, nil
 	default:
 		return nil, errors.New("[event/sql] unsupported connection type")
 	}
 }
 
 func (s *SQLSender) Send(ctx context.Context, message *event.EventMessage) error {
-	var tx *sql.Tx
-	ownTx := false
-	//ck := NewSQLTxContext(s.ContextKey)
-	tx, ok := ctx.Value(constant.TxKey).(*sql.Tx)
-	if !ok {
-		t, err := s.db.Begin()
-		if err != nil {
-			return err
-		}
-		tx = t
-		ownTx = true
-	}
-
 	outbox, err := event.OutboxFromMessage(message)
 	if err != nil {
 		return err
@@ -103,15 +97,29 @@ func (s *SQLSender) Send(ctx context.Context, message *event.EventMessage) error
 
 	stmt := fmt.Sprintf("INSERT INTO %s (id, topic, message_key, message_value, created_at) VALUES (?, ?, ?, ?, ?)", s.Table)
 
-	_, err = tx.Exec(stmt, outbox.ID, outbox.Topic, outbox.Key, outbox.Value, outbox.CreatedAt)
+	return s.exec(ctx, stmt, outbox.ID, outbox.Topic, outbox.Key, outbox.Value, outbox.CreatedAt)
+}
+
+// exec execute the statement within transaction from the context or its own transaction
+func (s *SQLSender) exec(ctx context.Context, stmt string, args ...interface{}) error {
+	//ck := NewSQLTxContext(s.ContextKey)
+	tx, ok := ctx.Value(constant.TxKey).(*sql.Tx)
+	if ok {
+		_, err := tx.Exec(stmt, args...)
+		return err
+	}
+
+	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
 
-	if ownTx {
-		return tx.Commit()
+	if _, err := tx.Exec(stmt, args...); err != nil {
+		tx.Rollback()
+		return err
 	}
-	return nil
+
+	return tx.Commit()
 }
 
 func (s *SQLSender) Delete(ctx context.Context, message *event.EventMessage) error {
@@ -128,3 +136,127 @@ func (s *SQLSender) Delete(ctx context.Context, message *event.EventMessage) err
 	}
 	return err
 }
+
+// RelayOutbox claim outbox records using SELECT ... FOR UPDATE SKIP LOCKED
+// the records stay locked until all of them are relayed, so ClaimTimeout is not used.
+// Requires database supporting SKIP LOCKED (MySQL 8, PostgreSQL 9.5)
+func (s *SQLSender) RelayOutbox(ctx context.Context, opt *event.RelayOption, fn event.RelayFunc) (int, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt := fmt.Sprintf("SELECT id, topic, message_key, message_value, created_at FROM %s WHERE created_at < ? ORDER BY created_at LIMIT ? FOR UPDATE SKIP LOCKED", s.Table)
+
+	var out []SQLOutbox
+	if err := tx.SelectContext(ctx, &out, tx.Rebind(stmt), opt.CreatedBefore, opt.Limit); err != nil {
+		return 0, err
+	}
+
+	delStmt := tx.Rebind(fmt.Sprintf("DELETE FROM %s WHERE id = ?", s.Table))
+
+	relayed := 0
+	for _, o := range out {
+		record := &event.OutboxRecord{
+			ID:        o.ID,
+			Topic:     o.Topic,
+			Key:       o.Key,
+			Value:     o.Value,
+			CreatedAt: o.CreatedAt,
+		}
+
+		if err := fn(ctx, record); err != nil {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, delStmt, o.ID); err != nil {
+			return 0, err
+		}
+		relayed++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return relayed, nil
+}
+
+// Schedule insert the outbox record into the schedule table, within transaction from the context if any
+func (s *SQLSender) Schedule(ctx context.Context, record *event.OutboxRecord, dueAt time.Time) error {
+	if s.ScheduleTable == "" {
+		return errors.New("[event/sql] missing schedule_table param")
+	}
+
+	stmt := fmt.Sprintf("INSERT INTO %s (id, topic, message_key, message_value, created_at, due_at) VALUES (?, ?, ?, ?, ?, ?)", s.ScheduleTable)
+
+	return s.exec(ctx, s.db.Rebind(stmt), record.ID, record.Topic, record.Key, record.Value, record.CreatedAt, dueAt)
+}
+
+// CancelSchedule delete scheduled records of the topic and key,
+// records being released are deleted by the scheduler and not counted
+func (s *SQLSender) CancelSchedule(ctx context.Context, topic, key string) (int, error) {
+	if s.ScheduleTable == "" {
+		return 0, errors.New("[event/sql] missing schedule_table param")
+	}
+
+	stmt := fmt.Sprintf("DELETE FROM %s WHERE topic = ? AND message_key = ?", s.ScheduleTable)
+
+	res, err := s.db.ExecContext(ctx, s.db.Rebind(stmt), topic, key)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// ReleaseDue claim due scheduled records using SELECT ... FOR UPDATE SKIP LOCKED
+// the records stay locked until all of them are released, so ClaimTimeout is not used
+func (s *SQLSender) ReleaseDue(ctx context.Context, opt *event.ReleaseOption, fn event.RelayFunc) (int, error) {
+	if s.ScheduleTable == "" {
+		return 0, errors.New("[event/sql] missing schedule_table param")
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt := fmt.Sprintf("SELECT id, topic, message_key, message_value, created_at FROM %s WHERE due_at <= ? ORDER BY due_at LIMIT ? FOR UPDATE SKIP LOCKED", s.ScheduleTable)
+
+	var out []SQLOutbox
+	if err := tx.SelectContext(ctx, &out, tx.Rebind(stmt), opt.DueBefore, opt.Limit); err != nil {
+		return 0, err
+	}
+
+	delStmt := tx.Rebind(fmt.Sprintf("DELETE FROM %s WHERE id = ?", s.ScheduleTable))
+
+	released := 0
+	for _, o := range out {
+		record := &event.OutboxRecord{
+			ID:        o.ID,
+			Topic:     o.Topic,
+			Key:       o.Key,
+			Value:     o.Value,
+			CreatedAt: o.CreatedAt,
+		}
+
+		if err := fn(ctx, record); err != nil {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, delStmt, o.ID); err != nil {
+			return 0, err
+		}
+		released++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return released, nil
+}
@@ -37,6 +37,18 @@ func initTable() *sqlx.DB {
 
 	db.Master.MustExec("DROP TABLE IF EXISTS outbox;")
 	db.Master.MustExec(schema)
+
+	db.Master.MustExec("DROP TABLE IF EXISTS outbox_scheduled;")
+	db.Master.MustExec(`
+	CREATE TABLE IF NOT EXISTS outbox_scheduled (
+		id VARCHAR(255) NOT NULL,
+		topic VARCHAR(255) NOT NULL,
+		message_key VARCHAR(255),
+		message_value TEXT,
+		created_at TIMESTAMP,
+		due_at TIMESTAMP,
+		INDEX (due_at)
+	)  ENGINE=INNODB;`)
 	return db.Master
 }
 
@@ -174,3 +186,93 @@ func TestSQLTx(t *testing.T) {
 	assert.Equal(t, 1, len(out))
 
 }
+
+func TestSQLRelayOutbox(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	db := initTable()
+	require.NotNil(t, db)
+
+	writer, err := NewSQLOutbox(context.Background(), map[string]interface{}{
+		"driver":     "mysql",
+		"table":      "outbox",
+		"connection": db,
+	})
+	require.Nil(t, err)
+
+	ctx := context.Background()
+	key := fmt.Sprintf("%v", time.Now().Unix())
+	require.Nil(t, writer.Send(ctx, &event.EventMessage{Topic: "test", Key: key, Data: "testdata"}))
+
+	var buf bytes.Buffer
+	logrus.SetOutput(&buf)
+
+	sender, err := event.NewEventLogger(ctx, nil)
+	require.Nil(t, err)
+
+	relay, err := event.NewOutboxRelay(writer, sender, &event.OutboxRelayConfig{MinAge: "0s"})
+	require.Nil(t, err)
+
+	time.Sleep(1 * time.Second)
+	n, err := relay.RelayOnce(ctx)
+	require.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Contains(t, buf.String(), "key="+key)
+
+	var out []SQLOutbox
+	err = db.Select(&out, "SELECT * FROM outbox WHERE message_key = ?", key)
+	require.Nil(t, err)
+	assert.Equal(t, 0, len(out))
+}
+
+func TestSQLSchedule(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	db := initTable()
+	require.NotNil(t, db)
+
+	writer, err := NewSQLOutbox(context.Background(), map[string]interface{}{
+		"driver":         "mysql",
+		"table":          "outbox",
+		"schedule_table": "outbox_scheduled",
+		"connection":     db,
+	})
+	require.Nil(t, err)
+
+	ctx := context.Background()
+	now := time.Now()
+	for _, key := range []string{"due", "later", "canceled"} {
+		rec, err := event.OutboxFromMessage(&event.EventMessage{Topic: "test", Key: key, Data: "testdata"})
+		require.Nil(t, err)
+
+		dueAt := now.Add(-time.Minute)
+		if key != "due" {
+			dueAt = now.Add(time.Hour)
+		}
+		require.Nil(t, writer.Schedule(ctx, rec, dueAt))
+	}
+
+	n, err := writer.CancelSchedule(ctx, "test", "canceled")
+	require.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	var released []string
+	n, err = writer.ReleaseDue(ctx, &event.ReleaseOption{DueBefore: now, Limit: 10},
+		func(ctx context.Context, record *event.OutboxRecord) error {
+			released = append(released, record.Key)
+			return nil
+		})
+	require.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"due"}, released)
+
+	var out []SQLOutbox
+	err = db.Select(&out, "SELECT id, topic, message_key, message_value, created_at FROM outbox_scheduled")
+	require.Nil(t, err)
+	require.Equal(t, 1, len(out))
+	assert.Equal(t, "later", out[0].Key)
+}
@@ -2,13 +2,34 @@ package event
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
+
+	"github.com/diki-haryadi/govega/log"
+	"github.com/diki-haryadi/govega/monitor"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	MetaDLQError         = "dlq_error"
+	MetaDLQAttempts      = "dlq_attempts"
+	MetaDLQOriginalTopic = "dlq_original_topic"
+	MetaDLQGroup         = "dlq_group"
+	MetaDLQFailedAt      = "dlq_failed_at"
+
+	DefaultRetryMaxAttempts = 3
+	DefaultRetryBackoff     = time.Second
+	DefaultRetryMaxBackoff  = 30 * time.Second
+	DefaultDLQTopicSuffix   = ".dlq"
 )
 
 var (
 	consumeStrategy = map[string]ConsumeStrategyFactory{
 		"always_commit":     NoConfigConsumeStrategyFactory(AlwaysCommitStrategy),
 		"commit_on_success": NoConfigConsumeStrategyFactory(CommitOnSuccessStrategy),
+		"retry_then_dlq":    RetryThenDLQStrategyFactory,
 	}
 )
 
@@ -16,6 +37,27 @@ type (
 	ConsumeStrategy func(ctx context.Context, message ConsumeMessage, handler EventHandler) error
 
 	ConsumeStrategyFactory func(ctx context.Context, config interface{}) (ConsumeStrategy, error)
+
+	RetryThenDLQConfig struct {
+		// MaxAttempts number of handler call before message sent to dead letter topic, default: 3
+		MaxAttempts int `json:"max_attempts" mapstructure:"max_attempts"`
+		// Backoff initial wait between attempts, doubled on every retry, default: 1s
+		Backoff string `json:"backoff" mapstructure:"backoff"`
+		// MaxBackoff maximum wait between attempts, default: 30s
+		MaxBackoff string `json:"max_backoff" mapstructure:"max_backoff"`
+		// Topic dead letter topic, default: original topic with .dlq suffix
+		Topic string `json:"topic" mapstructure:"topic"`
+		// Sender driver used to publish to dead letter topic
+		Sender *DriverConfig `json:"sender" mapstructure:"sender"`
+	}
+
+	retryThenDLQ struct {
+		sender      Sender
+		maxAttempts int
+		backoff     time.Duration
+		maxBackoff  time.Duration
+		topic       string
+	}
 )
 
 // RegisterConsumeStrategy register consumestrategy
@@ -31,13 +73,14 @@ func AlwaysCommitStrategy(ctx context.Context, message ConsumeMessage, handler E
 	}
 
 	if err := message.Commit(ctx); err != nil {
-		return fmt.Errorf("[event/alwaysCommitStrategy] failed to commit: %w", err)
+		return fmt.Errorf("[event/alwaysCommitStrategy] %w: %w", ErrCommitFailed, err)
 	}
 
 	return handler(ctx, em)
 }
 
-// CommitOnSuccessStrategy will only commit the message if handler doesn't return error
+// CommitOnSuccessStrategy will only commit the message if handler doesn't return error,
+// message failed with non retryable error is committed
 func CommitOnSuccessStrategy(ctx context.Context, message ConsumeMessage, handler EventHandler) error {
 	em, err := message.GetEventConsumeMessage(ctx)
 	if err != nil {
@@ -45,11 +88,17 @@ func CommitOnSuccessStrategy(ctx context.Context, message ConsumeMessage, handle
 	}
 
 	if err := handler(ctx, em); err != nil {
+		if IsNonRetryable(err) {
+			// message is never going to succeed, commit it so it doesn't block the consumer
+			if cerr := message.Commit(ctx); cerr != nil {
+				return fmt.Errorf("[event/commitOnSuccessStrategy] %w: %w", ErrCommitFailed, cerr)
+			}
+		}
 		return fmt.Errorf("[event/commitOnSuccessStrategy] handler failed to process message: %w", err)
 	}
 
 	if err := message.Commit(ctx); err != nil {
-		return fmt.Errorf("[event/commitOnSuccessStrategy] failed to commit: %w", err)
+		return fmt.Errorf("[event/commitOnSuccessStrategy] %w: %w", ErrCommitFailed, err)
 	}
 
 	return nil
@@ -62,3 +111,159 @@ func NoConfigConsumeStrategyFactory(strategy ConsumeStrategy) ConsumeStrategyFac
 		return strategy, nil
 	}
 }
+
+// RetryThenDLQStrategyFactory create retry then dead letter strategy from RetryThenDLQConfig
+func RetryThenDLQStrategyFactory(ctx context.Context, config interface{}) (ConsumeStrategy, error) {
+	var conf RetryThenDLQConfig
+	if err := mapstructure.Decode(config, &conf); err != nil {
+		return nil, fmt.Errorf("[event/retryThenDLQStrategy] failed to decode config: %w", err)
+	}
+
+	if conf.Sender == nil {
+		return nil, errors.New("[event/retryThenDLQStrategy] missing sender driver config")
+	}
+
+	sf, ok := senders[conf.Sender.Type]
+	if !ok {
+		return nil, fmt.Errorf("[event/retryThenDLQStrategy] unsupported sender driver: %s",
+			conf.Sender.Type)
+	}
+
+	sender, err := sf(ctx, conf.Sender.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRetryThenDLQStrategy(sender, &conf)
+}
+
+// NewRetryThenDLQStrategy create strategy which retry the handler with backoff
+// and publish the message to dead letter topic through sender when all attempts failed or the handler
+// returned non retryable error, message is committed once it is handled or published to dead letter topic
+func NewRetryThenDLQStrategy(sender Sender, config *RetryThenDLQConfig) (ConsumeStrategy, error) {
+	if sender == nil {
+		return nil, errors.New("[event/retryThenDLQStrategy] missing sender")
+	}
+
+	r := &retryThenDLQ{
+		sender:      sender,
+		maxAttempts: DefaultRetryMaxAttempts,
+		backoff:     DefaultRetryBackoff,
+		maxBackoff:  DefaultRetryMaxBackoff,
+	}
+
+	if config == nil {
+		return r.consume, nil
+	}
+
+	if config.MaxAttempts > 0 {
+		r.maxAttempts = config.MaxAttempts
+	}
+
+	if config.Backoff != "" {
+		backoff, err := time.ParseDuration(config.Backoff)
+		if err != nil {
+			return nil, fmt.Errorf("[event/retryThenDLQStrategy] invalid backoff value: %w", err)
+		}
+
+		r.backoff = backoff
+	}
+
+	if config.MaxBackoff != "" {
+		backoff, err := time.ParseDuration(config.MaxBackoff)
+		if err != nil {
+			return nil, fmt.Errorf("[event/retryThenDLQStrategy] invalid max backoff value: %w", err)
+		}
+
+		r.maxBackoff = backoff
+	}
+
+	r.topic = config.Topic
+
+	return r.consume, nil
+}
+
+func (r *retryThenDLQ) consume(ctx context.Context, message ConsumeMessage, handler EventHandler) error {
+	em, err := message.GetEventConsumeMessage(ctx)
+	if err != nil {
+		return fmt.Errorf("[event/retryThenDLQStrategy] failed to get event message: %w", err)
+	}
+
+	backoff := r.backoff
+	attempt := 0
+
+	for {
+		attempt++
+
+		err = handler(ctx, em)
+		if err == nil {
+			break
+		}
+
+		if attempt >= r.maxAttempts || IsNonRetryable(err) {
+			log.WithContext(ctx).WithError(err).WithField("attempts", attempt).
+				Warnln("[event/retryThenDLQStrategy] sending message to dead letter topic")
+
+			if err := sendDeadLetter(ctx, r.sender, r.topic, em, err, attempt); err != nil {
+				return fmt.Errorf("[event/retryThenDLQStrategy] failed to send dead letter: %w", err)
+			}
+
+			monitor.FeedConsumerDeadLetterMetrics(em.Topic, GetConsumerGroupFromContext(ctx))
+			break
+		}
+
+		monitor.FeedConsumerRetryMetrics(em.Topic, GetConsumerGroupFromContext(ctx))
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("[event/retryThenDLQStrategy] handler failed to process message: %w", err)
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > r.maxBackoff {
+			backoff = r.maxBackoff
+		}
+	}
+
+	if err := message.Commit(ctx); err != nil {
+		return fmt.Errorf("[event/retryThenDLQStrategy] %w: %w", ErrCommitFailed, err)
+	}
+
+	return nil
+}
+
+// sendDeadLetter send the message with the failure metadata to dead letter topic,
+// default to the original topic with .dlq suffix
+func sendDeadLetter(ctx context.Context, sender Sender, topic string, em *EventConsumeMessage,
+	cause error, attempts int) error {
+	metadata := make(map[string]interface{}, len(em.Metadata)+5)
+	for k, v := range em.Metadata {
+		metadata[k] = v
+	}
+
+	metadata[MetaDLQError] = cause.Error()
+	metadata[MetaDLQAttempts] = attempts
+	metadata[MetaDLQOriginalTopic] = em.Topic
+	metadata[MetaDLQGroup] = GetConsumerGroupFromContext(ctx)
+	metadata[MetaDLQFailedAt] = time.Now()
+
+	if topic == "" {
+		topic = em.Topic + DefaultDLQTopicSuffix
+	}
+
+	msg := &EventMessage{
+		Topic:    topic,
+		Key:      em.Key,
+		Metadata: metadata,
+		RawData:  em.Data,
+	}
+
+	if json.Valid(em.Data) {
+		msg.Data = json.RawMessage(em.Data)
+	} else if len(em.Data) > 0 {
+		msg.Data = string(em.Data)
+	}
+
+	return sender.Send(ctx, msg)
+}
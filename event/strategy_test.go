@@ -3,9 +3,34 @@ package event
 import (
 	"context"
 	"errors"
+	"strings"
+	"sync"
 	"testing"
 )
 
+type testSender struct {
+	lock   sync.Mutex
+	sent   []*EventMessage
+	failFn func(message *EventMessage) bool
+}
+
+func (s *testSender) Send(ctx context.Context, message *EventMessage) error {
+	if s.failFn != nil && s.failFn(message) {
+		return errors.New("failed")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.sent = append(s.sent, message)
+	return nil
+}
+
+func (s *testSender) count() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.sent)
+}
+
 func TestAlwaysCommitStrategy(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -75,3 +100,146 @@ func TestCommitOnSuccessStrategy(t *testing.T) {
 		})
 	}
 }
+
+func TestRetryThenDLQStrategy(t *testing.T) {
+	tests := []struct {
+		name              string
+		failures          int
+		expectedCalls     int
+		expectedDLQ       bool
+		expectedCommitted bool
+	}{
+		{
+			name:              "success handle message",
+			failures:          0,
+			expectedCalls:     1,
+			expectedCommitted: true,
+		},
+		{
+			name:              "success after retry",
+			failures:          2,
+			expectedCalls:     3,
+			expectedCommitted: true,
+		},
+		{
+			name:              "failed all attempts",
+			failures:          5,
+			expectedCalls:     3,
+			expectedDLQ:       true,
+			expectedCommitted: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt // NOTE: https://github.com/golang/go/wiki/CommonMistakes#using-goroutines-on-loop-iterator-variables
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &testSender{}
+			strategy, err := NewRetryThenDLQStrategy(sender, &RetryThenDLQConfig{
+				MaxAttempts: 3,
+				Backoff:     "1ms",
+			})
+			if err != nil {
+				t.Fatalf("failed to create strategy: %v", err)
+			}
+
+			calls := 0
+			handler := func(ctx context.Context, message *EventConsumeMessage) error {
+				calls++
+				if calls <= tt.failures {
+					return errors.New("failed")
+				}
+				return nil
+			}
+
+			cm := newTestConsumeMessage(&EventConsumeMessage{
+				Topic:    "test",
+				Key:      "t123",
+				Data:     []byte(`{"foo":"bar"}`),
+				Metadata: map[string]interface{}{MetaEvent: "test"},
+			})
+			ctx := context.WithValue(context.Background(), consumerGroupKey, "group")
+
+			if err := strategy(ctx, cm, handler); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if calls != tt.expectedCalls {
+				t.Fatalf("calls [%v] not equals to expected [%v]", calls, tt.expectedCalls)
+			}
+
+			if cm.committed != tt.expectedCommitted {
+				t.Fatalf("committed [%v] not equals to expected [%v]",
+					cm.committed, tt.expectedCommitted)
+			}
+
+			if (sender.count() == 1) != tt.expectedDLQ {
+				t.Fatalf("dead letter sent [%v] not equals to expected [%v]",
+					sender.count(), tt.expectedDLQ)
+			}
+
+			if tt.expectedDLQ {
+				msg := sender.sent[0]
+				if msg.Topic != "test"+DefaultDLQTopicSuffix || msg.Key != "t123" {
+					t.Fatalf("unexpected dead letter topic [%s] key [%s]", msg.Topic, msg.Key)
+				}
+
+				if msg.Metadata[MetaDLQError] != "failed" || msg.Metadata[MetaDLQAttempts] != 3 ||
+					msg.Metadata[MetaDLQOriginalTopic] != "test" || msg.Metadata[MetaDLQGroup] != "group" ||
+					msg.Metadata[MetaEvent] != "test" {
+					t.Fatalf("unexpected dead letter metadata %v", msg.Metadata)
+				}
+
+				if b, _ := msg.ToBytes(); !strings.Contains(string(b), `"data":{"foo":"bar"}`) {
+					t.Fatalf("unexpected dead letter payload %s", b)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryThenDLQStrategyFailedDLQ(t *testing.T) {
+	sender := &testSender{
+		failFn: func(message *EventMessage) bool {
+			return true
+		},
+	}
+
+	strategy, err := NewRetryThenDLQStrategy(sender, &RetryThenDLQConfig{
+		MaxAttempts: 1,
+	})
+	if err != nil {
+		t.Fatalf("failed to create strategy: %v", err)
+	}
+
+	cm := newTestConsumeMessage(&EventConsumeMessage{})
+	err = strategy(context.Background(), cm, func(ctx context.Context, message *EventConsumeMessage) error {
+		return errors.New("failed")
+	})
+
+	if err == nil {
+		t.Fatalf("expected error when dead letter failed")
+	}
+
+	if cm.committed {
+		t.Fatalf("message should not be committed")
+	}
+}
+
+func TestRetryThenDLQStrategyFactory(t *testing.T) {
+	factory, ok := consumeStrategy["retry_then_dlq"]
+	if !ok {
+		t.Fatalf("retry_then_dlq strategy is not registered")
+	}
+
+	if _, err := factory(context.Background(), map[string]interface{}{}); err == nil {
+		t.Fatalf("expected error on missing sender")
+	}
+
+	_, err := factory(context.Background(), map[string]interface{}{
+		"max_attempts": 5,
+		"topic":        "dlq",
+		"sender":       map[string]interface{}{"type": "logger"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
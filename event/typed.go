@@ -0,0 +1,117 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type (
+	// TypedMessage event message with decoded payload
+	TypedMessage[T any] struct {
+		*EventConsumeMessage
+		Payload T
+	}
+
+	// TypedHandler handle message with decoded payload
+	TypedHandler[T any] func(ctx context.Context, message *TypedMessage[T]) error
+)
+
+// Typed convert typed handler into event handler, payload is decoded with the codec of the content type
+// metadata or the given codec, default to json. Decode failure is returned as DecodeError
+func Typed[T any](codec Codec, handler TypedHandler[T]) EventHandler {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
+	return func(ctx context.Context, message *EventConsumeMessage) error {
+		c := codec
+		if ct, ok := message.Metadata[MetaContentType].(string); ok {
+			if registered, ok := GetCodec(ct); ok {
+				c = registered
+			}
+		}
+
+		tm := &TypedMessage[T]{EventConsumeMessage: message}
+		if err := decodePayload(c, message.Data, &tm.Payload); err != nil {
+			return &DecodeError{ContentType: c.ContentType(), Err: err}
+		}
+
+		return handler(ctx, tm)
+	}
+}
+
+// SubscribeTyped subscribe to a topic with handler receiving decoded payload, see Typed
+func SubscribeTyped[T any](ctx context.Context, consumer *Consumer, topic, group string, codec Codec,
+	handler TypedHandler[T]) error {
+	return consumer.Subscribe(ctx, topic, group, Typed(codec, handler))
+}
+
+// PublishTyped publish payload encoded with the codec, default to json.
+// Content type of the codec is set in the metadata
+func PublishTyped[T any](ctx context.Context, emitter *Emitter, codec Codec, event, key string, payload T,
+	metadata map[string]interface{}) error {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
+	data, err := encodePayload(codec, payload)
+	if err != nil {
+		return err
+	}
+
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata[MetaContentType] = codec.ContentType()
+
+	return emitter.Publish(ctx, event, key, data, metadata)
+}
+
+// Event return event name of the message
+func (m *TypedMessage[T]) Event() string {
+	return GetMetadataEvent(m.Metadata)
+}
+
+// Version return event version of the message
+func (m *TypedMessage[T]) Version() int {
+	return GetMetadataVersion(m.Metadata)
+}
+
+// Hash return payload hash set by the emitter
+func (m *TypedMessage[T]) Hash() string {
+	h, _ := GetMetadata[string](m.EventConsumeMessage, MetaHash)
+	return h
+}
+
+// Time return publish time set by the emitter
+func (m *TypedMessage[T]) Time() time.Time {
+	t, _ := GetMetadata[time.Time](m.EventConsumeMessage, MetaTime)
+	return t
+}
+
+// GetMetadata return metadata value converted into V, metadata decoded from json
+// is converted through json, e.g. number into int or timestamp into time.Time
+func GetMetadata[V any](message *EventConsumeMessage, key string) (V, bool) {
+	var v V
+
+	value, ok := message.Metadata[key]
+	if !ok {
+		return v, false
+	}
+
+	if typed, ok := value.(V); ok {
+		return typed, true
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return v, false
+	}
+
+	if err := json.Unmarshal(b, &v); err != nil {
+		return v, false
+	}
+
+	return v, true
+}
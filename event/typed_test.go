@@ -0,0 +1,102 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testOrder struct {
+	ID     string `json:"id"`
+	Amount int    `json:"amount"`
+}
+
+func TestPublishTyped(t *testing.T) {
+	sender := &testSender{}
+	RegisterSender("typed-test", func(ctx context.Context, config interface{}) (Sender, error) {
+		return sender, nil
+	})
+
+	em, err := New(context.Background(), &EmitterConfig{Sender: &DriverConfig{Type: "typed-test"}})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, PublishTyped(ctx, em, nil, "order_created", "o123", testOrder{ID: "o123", Amount: 10},
+		map[string]interface{}{"source": "test"}))
+	require.Equal(t, 1, sender.count())
+
+	b, err := sender.sent[0].ToBytes()
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"data":{"id":"o123","amount":10}`, "json payload is embedded as is")
+
+	cm, err := NewEventConsumeMessage(b)
+	require.NoError(t, err)
+
+	var received *TypedMessage[testOrder]
+	handler := Typed(nil, func(ctx context.Context, message *TypedMessage[testOrder]) error {
+		received = message
+		return nil
+	})
+	require.NoError(t, handler(ctx, cm))
+
+	assert.Equal(t, testOrder{ID: "o123", Amount: 10}, received.Payload)
+	assert.Equal(t, "order_created", received.Event())
+	assert.Equal(t, 1, received.Version())
+	assert.NotEmpty(t, received.Hash())
+	assert.WithinDuration(t, time.Now(), received.Time(), time.Minute)
+
+	source, ok := GetMetadata[string](cm, "source")
+	assert.True(t, ok)
+	assert.Equal(t, "test", source)
+
+	_, ok = GetMetadata[int](cm, "missing")
+	assert.False(t, ok)
+}
+
+func TestTypedDecodeError(t *testing.T) {
+	called := false
+	handler := Typed(JSONCodec{}, func(ctx context.Context, message *TypedMessage[testOrder]) error {
+		called = true
+		return nil
+	})
+
+	err := handler(context.Background(), &EventConsumeMessage{Data: []byte(`{"id":1}`)})
+	var derr *DecodeError
+	require.ErrorAs(t, err, &derr)
+	assert.Equal(t, ContentTypeJSON, derr.ContentType)
+	assert.True(t, IsNonRetryable(err))
+	assert.False(t, called)
+}
+
+func TestNonRetryableStrategy(t *testing.T) {
+	failed := func(ctx context.Context, message *EventConsumeMessage) error {
+		return NonRetryable(errors.New("invalid"))
+	}
+
+	assert.False(t, IsNonRetryable(errors.New("failed")))
+	assert.Nil(t, NonRetryable(nil))
+
+	cm := newTestConsumeMessage(&EventConsumeMessage{Topic: "test"})
+	err := CommitOnSuccessStrategy(context.Background(), cm, failed)
+	assert.True(t, IsNonRetryable(err))
+	assert.True(t, cm.committed, "non retryable message is committed")
+
+	sender := &testSender{}
+	strategy, err := NewRetryThenDLQStrategy(sender, &RetryThenDLQConfig{MaxAttempts: 3, Backoff: "1ms"})
+	require.NoError(t, err)
+
+	calls := 0
+	cm = newTestConsumeMessage(&EventConsumeMessage{Topic: "test"})
+	require.NoError(t, strategy(context.Background(), cm, func(ctx context.Context, message *EventConsumeMessage) error {
+		calls++
+		return failed(ctx, message)
+	}))
+	assert.Equal(t, 1, calls, "non retryable message is not retried")
+	assert.Equal(t, 1, sender.count())
+	assert.Equal(t, 1, sender.sent[0].Metadata[MetaDLQAttempts])
+	assert.True(t, cm.committed)
+}
@@ -74,3 +74,90 @@ func FeedConsumerMetrics(topic, group, status string, duration time.Duration) {
 		"env":    env.Get(),
 	}).Inc()
 }
+
+// FeedOutboxRelayMetrics to monitor outbox relay latency, status counts
+func FeedOutboxRelayMetrics(topic, status string, duration time.Duration) {
+	outboxRelayLatencyHistogram.With(prometheus.Labels{
+		"topic":  topic,
+		"status": status,
+		"env":    env.Get(),
+	}).Observe(duration.Seconds())
+
+	outboxRelayTotalCounter.With(prometheus.Labels{
+		"topic":  topic,
+		"status": status,
+		"env":    env.Get(),
+	}).Inc()
+}
+
+// FeedConsumerLagMetrics to monitor number of messages behind the latest offset of a partition
+func FeedConsumerLagMetrics(topic, partition, group string, lag int64) {
+	consumerLagGauge.With(prometheus.Labels{
+		"topic":     topic,
+		"partition": partition,
+		"group":     group,
+		"env":       env.Get(),
+	}).Set(float64(lag))
+}
+
+// FeedConsumerInflightMetrics to monitor number of messages being processed, delta can be negative
+func FeedConsumerInflightMetrics(topic, group string, delta int) {
+	consumerInflightGauge.With(prometheus.Labels{
+		"topic": topic,
+		"group": group,
+		"env":   env.Get(),
+	}).Add(float64(delta))
+}
+
+// FeedConsumerRetryMetrics to monitor consumer handler retry counts
+func FeedConsumerRetryMetrics(topic, group string) {
+	consumerRetryCounter.With(prometheus.Labels{
+		"topic": topic,
+		"group": group,
+		"env":   env.Get(),
+	}).Inc()
+}
+
+// FeedConsumerDeadLetterMetrics to monitor messages sent to dead letter topic counts
+func FeedConsumerDeadLetterMetrics(topic, group string) {
+	consumerDeadLetterCounter.With(prometheus.Labels{
+		"topic": topic,
+		"group": group,
+		"env":   env.Get(),
+	}).Inc()
+}
+
+// FeedConsumerCommitFailureMetrics to monitor consumer failed commit counts
+func FeedConsumerCommitFailureMetrics(topic, group string) {
+	consumerCommitFailureCounter.With(prometheus.Labels{
+		"topic": topic,
+		"group": group,
+		"env":   env.Get(),
+	}).Inc()
+}
+
+// FeedConsumerDuplicateMetrics to monitor duplicate messages skipped by idempotent consumer
+func FeedConsumerDuplicateMetrics(topic, group string) {
+	consumerDuplicateCounter.With(prometheus.Labels{
+		"topic": topic,
+		"group": group,
+		"env":   env.Get(),
+	}).Inc()
+}
+
+// FeedSenderSpillMetrics to monitor messages spilled to fallback writer when sender is unavailable
+func FeedSenderSpillMetrics(topic string) {
+	senderSpillCounter.With(prometheus.Labels{
+		"topic": topic,
+		"env":   env.Get(),
+	}).Inc()
+}
+
+// FeedSenderForwardMetrics to monitor spilled messages forwarded to sender, status counts
+func FeedSenderForwardMetrics(topic, status string) {
+	senderForwardCounter.With(prometheus.Labels{
+		"topic":  topic,
+		"status": status,
+		"env":    env.Get(),
+	}).Inc()
+}
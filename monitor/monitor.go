@@ -21,6 +21,24 @@ var (
 	consumerLatencyHistogram      *prometheus.HistogramVec
 	consumerResponsesTotalCounter *prometheus.CounterVec
 	consumerMetricLabels          = []string{"topic", "group", "status", "env"}
+
+	consumerLagGauge             *prometheus.GaugeVec
+	consumerLagMetricLabels      = []string{"topic", "partition", "group", "env"}
+	consumerInflightGauge        *prometheus.GaugeVec
+	consumerRetryCounter         *prometheus.CounterVec
+	consumerDeadLetterCounter    *prometheus.CounterVec
+	consumerCommitFailureCounter *prometheus.CounterVec
+	consumerDuplicateCounter     *prometheus.CounterVec
+	consumerGroupMetricLabels    = []string{"topic", "group", "env"}
+
+	outboxRelayLatencyHistogram *prometheus.HistogramVec
+	outboxRelayTotalCounter     *prometheus.CounterVec
+	outboxRelayMetricLabels     = []string{"topic", "status", "env"}
+
+	senderSpillCounter        *prometheus.CounterVec
+	senderSpillMetricLabels   = []string{"topic", "env"}
+	senderForwardCounter      *prometheus.CounterVec
+	senderForwardMetricLabels = []string{"topic", "status", "env"}
 )
 
 func init() {
@@ -33,6 +51,7 @@ func Init(appName string) {
 
 	registerHistogram(appName)
 	registerCounter(appName)
+	registerGauge(appName)
 }
 
 func registerHistogram(appName string) {
@@ -44,6 +63,9 @@ func registerHistogram(appName string) {
 
 	unregister(consumerLatencyHistogram)
 	consumerLatencyHistogram = createAndRegisterHistogram("consumer", appName, consumerMetricLabels)
+
+	unregister(outboxRelayLatencyHistogram)
+	outboxRelayLatencyHistogram = createAndRegisterHistogram("outbox_relay", appName, outboxRelayMetricLabels)
 }
 
 func registerCounter(appName string) {
@@ -55,6 +77,43 @@ func registerCounter(appName string) {
 
 	unregister(consumerResponsesTotalCounter)
 	consumerResponsesTotalCounter = createAndRegisterCounter("consumer", appName, consumerMetricLabels)
+
+	unregister(outboxRelayTotalCounter)
+	outboxRelayTotalCounter = createAndRegisterCounter("outbox_relay", appName, outboxRelayMetricLabels)
+
+	unregister(consumerRetryCounter)
+	consumerRetryCounter = createAndRegisterTotalCounter("consumer_retries", appName,
+		"The count of consumer handler retries", consumerGroupMetricLabels)
+
+	unregister(consumerDeadLetterCounter)
+	consumerDeadLetterCounter = createAndRegisterTotalCounter("consumer_dead_letters", appName,
+		"The count of consumer messages sent to dead letter topic", consumerGroupMetricLabels)
+
+	unregister(consumerCommitFailureCounter)
+	consumerCommitFailureCounter = createAndRegisterTotalCounter("consumer_commit_failures", appName,
+		"The count of consumer failed commits", consumerGroupMetricLabels)
+
+	unregister(consumerDuplicateCounter)
+	consumerDuplicateCounter = createAndRegisterTotalCounter("consumer_duplicates", appName,
+		"The count of consumer duplicate messages", consumerGroupMetricLabels)
+
+	unregister(senderSpillCounter)
+	senderSpillCounter = createAndRegisterTotalCounter("sender_spilled", appName,
+		"The count of messages spilled to fallback writer", senderSpillMetricLabels)
+
+	unregister(senderForwardCounter)
+	senderForwardCounter = createAndRegisterTotalCounter("sender_forwarded", appName,
+		"The count of spilled messages forwarded to sender", senderForwardMetricLabels)
+}
+
+func registerGauge(appName string) {
+	unregister(consumerLagGauge)
+	consumerLagGauge = createAndRegisterGauge("consumer_lag", appName,
+		"the number of messages behind the latest offset of the partition", consumerLagMetricLabels)
+
+	unregister(consumerInflightGauge)
+	consumerInflightGauge = createAndRegisterGauge("consumer_inflight_messages", appName,
+		"the number of messages being processed by consumer", consumerGroupMetricLabels)
 }
 
 func unregister(c prometheus.Collector) {
@@ -94,3 +153,35 @@ func createAndRegisterCounter(metric, namespace string, labels []string) *promet
 
 	return newCounter
 }
+
+func createAndRegisterTotalCounter(metric, namespace, help string, labels []string) *prometheus.CounterVec {
+	newCounter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      fmt.Sprintf("%s_total", metric),
+		Namespace: namespace,
+		Help:      help,
+	}, labels)
+	if err := prometheus.Register(newCounter); err != nil {
+		log.WithFields(log.Fields{
+			"metric":    metric,
+			"namespace": namespace,
+		}).WithError(err).Warnln("[monitor] unable to register counter")
+	}
+
+	return newCounter
+}
+
+func createAndRegisterGauge(metric, namespace, help string, labels []string) *prometheus.GaugeVec {
+	newGauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      metric,
+		Namespace: namespace,
+		Help:      help,
+	}, labels)
+	if err := prometheus.Register(newGauge); err != nil {
+		log.WithFields(log.Fields{
+			"metric":    metric,
+			"namespace": namespace,
+		}).WithError(err).Warnln("[monitor] unable to register gauge")
+	}
+
+	return newGauge
+}